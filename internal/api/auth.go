@@ -0,0 +1,51 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authRealm is the realm browsers show in their basic auth prompt.
+const authRealm = "SQLiter"
+
+// authEnabled reports whether any credentials are configured.
+func (c Config) authEnabled() bool {
+	return c.AuthUser != "" || c.AuthToken != ""
+}
+
+// authenticate rejects API requests without valid credentials when auth is
+// configured. Either the basic auth user and password or the bearer token is
+// accepted when both are set.
+func (h *Handler) authenticate(c *gin.Context) {
+	if !h.config.authEnabled() {
+		return
+	}
+
+	if h.config.AuthToken != "" {
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && secureEqual(token, h.config.AuthToken) {
+			return
+		}
+	}
+	if h.config.AuthUser != "" {
+		if user, pass, ok := c.Request.BasicAuth(); ok && secureEqual(user, h.config.AuthUser) && secureEqual(pass, h.config.AuthPass) {
+			return
+		}
+	}
+
+	// Ask browsers to prompt for basic auth; token clients only get the scheme
+	if h.config.AuthUser != "" {
+		c.Header("WWW-Authenticate", `Basic realm="`+authRealm+`", charset="UTF-8"`)
+	} else {
+		c.Header("WWW-Authenticate", `Bearer realm="`+authRealm+`"`)
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(http.StatusUnauthorized, errors.New("authentication required")))
+}
+
+// secureEqual compares credentials in constant time.
+func secureEqual(given, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
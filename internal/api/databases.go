@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sqliter/internal/db"
+	"sqliter/internal/models"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys of the database selected for a request.
+const (
+	databaseKey     = "database"
+	databaseNameKey = "database_name"
+)
+
+// AddDatabase serves another database under its filename, which clients
+// pass as the db query parameter to select it.
+func (h *Handler) AddDatabase(database *db.SQLiteDB) error {
+	info, err := database.GetDatabaseInfo()
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.dbs[info.Filename]; ok {
+		return fmt.Errorf("a database named '%s' is already open", info.Filename)
+	}
+	h.dbs[info.Filename] = database
+	h.requests[database] = &sync.WaitGroup{}
+	h.names = append(h.names, info.Filename)
+	return nil
+}
+
+// selectDatabase resolves the db query parameter to one of the served
+// databases, defaulting to the first one, and counts the request as in flight
+// on it until the handlers return.
+func (h *Handler) selectDatabase(c *gin.Context) {
+	h.mu.RLock()
+	if len(h.names) == 0 {
+		h.mu.RUnlock()
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, errorResponse(http.StatusServiceUnavailable, errors.New("no database is open")))
+		return
+	}
+	name := c.Query("db")
+	if name == "" {
+		name = h.names[0]
+	}
+	database, ok := h.dbs[name]
+	requests := h.requests[database]
+	if ok {
+		// Added under the lock, so a switch can't start waiting before it
+		requests.Add(1)
+	}
+	h.mu.RUnlock()
+
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, errorResponse(http.StatusNotFound, &db.NotFoundError{Kind: "database", Name: name}))
+		return
+	}
+	defer requests.Done()
+	c.Set(databaseKey, database)
+	c.Set(databaseNameKey, name)
+	c.Next()
+}
+
+// database returns the database selected for the request.
+func (h *Handler) database(c *gin.Context) *db.SQLiteDB {
+	return c.MustGet(databaseKey).(*db.SQLiteDB)
+}
+
+// databaseNames returns the names of the served databases in order.
+func (h *Handler) databaseNames() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]string(nil), h.names...)
+}
+
+func (h *Handler) ListDatabases(c *gin.Context) {
+	h.mu.RLock()
+	databases := make([]models.DatabaseEntry, len(h.names))
+	for i, name := range h.names {
+		info, err := h.dbs[name].GetDatabaseInfo()
+		if err != nil {
+			h.mu.RUnlock()
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		databases[i] = models.DatabaseEntry{Name: name, Filename: info.Filename, ReadOnly: info.ReadOnly, Default: i == 0}
+	}
+	h.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{"databases": databases})
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"sqliter/internal/db"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorBody is the envelope every error is rendered in, so clients can react
+// to Code rather than parse Message:
+//
+//	{"error": {"code": "UNIQUE_VIOLATION", "message": "...", "column": "email"}}
+//
+// Column names the offending column when there is one, and the remaining
+// fields carry the details of particular errors.
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Column  string `json:"column,omitempty"`
+	// Row is the index of the rejected row in a bulk insert
+	Row *int `json:"row,omitempty"`
+	// Index and Statement locate the failed statement of a script
+	Index       *int     `json:"index,omitempty"`
+	Statement   string   `json:"statement,omitempty"`
+	Table       string   `json:"table,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// newErrorBody describes err, taking the code from its type and falling back
+// to one named after the HTTP status, e.g. BAD_REQUEST.
+func newErrorBody(status int, err error) errorBody {
+	body := errorBody{Code: statusCode(status), Message: err.Error()}
+
+	var failedRow *db.RowError
+	if errors.As(err, &failedRow) {
+		body.Row = &failedRow.Index
+	}
+	var failedStatement *db.ScriptError
+	if errors.As(err, &failedStatement) {
+		body.Index = &failedStatement.Index
+		body.Statement = failedStatement.Statement
+	}
+
+	var (
+		constraint    *db.ConstraintError
+		noSuchTable   *db.NoSuchTableError
+		tooLong       *db.LengthError
+		tooLarge      *db.BlobSizeError
+		wrongType     *db.ColumnTypeError
+		badFilter     *db.FilterError
+		badSort       *db.SortError
+		badCursor     *db.CursorError
+		badParams     *db.ParamError
+		badSaved      *db.SavedQueryError
+		incompleteKey *db.IncompleteKeyError
+		noKey         *db.NoPrimaryKeyError
+		notFound      *db.NotFoundError
+		conflict      *db.ConflictError
+		notFTS        *db.NotFTSTableError
+		ftsFailed     *db.FTSCommandError
+	)
+	switch {
+	case errors.As(err, &constraint):
+		body.Code, body.Column = constraint.Code, constraint.Column
+	case errors.As(err, &noSuchTable):
+		body.Code, body.Table, body.Suggestions = "NO_SUCH_TABLE", noSuchTable.Name, noSuchTable.Suggestions
+	case errors.As(err, &tooLong):
+		body.Code, body.Column = "VALUE_TOO_LONG", tooLong.Column
+	case errors.As(err, &tooLarge):
+		body.Code, body.Column = "BLOB_TOO_LARGE", tooLarge.Column
+	case errors.As(err, &wrongType):
+		body.Code, body.Column = "TYPE_MISMATCH", wrongType.Column
+	case errors.As(err, &badFilter):
+		body.Code, body.Column = "INVALID_FILTER", badFilter.Column
+	case errors.As(err, &badSort):
+		body.Code, body.Column = "INVALID_SORT", badSort.Column
+	case errors.As(err, &badCursor):
+		body.Code = "INVALID_CURSOR"
+	case errors.As(err, &badParams):
+		body.Code = "INVALID_PARAMS"
+	case errors.As(err, &badSaved):
+		body.Code = "INVALID_SAVED_QUERY"
+	case errors.As(err, &incompleteKey):
+		body.Code = "INCOMPLETE_KEY"
+	case errors.As(err, &noKey):
+		body.Code, body.Table = "NO_PRIMARY_KEY", noKey.Table
+	case errors.As(err, &notFound):
+		body.Code = "NOT_FOUND"
+		if notFound.Kind == "column" {
+			body.Column = notFound.Name
+		}
+	case errors.As(err, &conflict):
+		body.Code = "ALREADY_EXISTS"
+	case errors.As(err, &notFTS):
+		body.Code = "NOT_FTS_TABLE"
+	case errors.As(err, &ftsFailed):
+		body.Code = "FTS_COMMAND_FAILED"
+	case errors.Is(err, db.ErrFTS5Unavailable):
+		body.Code = "FTS5_UNAVAILABLE"
+	case errors.Is(err, db.ErrReadOnly):
+		body.Code = "READ_ONLY"
+	case errors.Is(err, db.ErrQueryTimeout):
+		body.Code = "QUERY_TIMEOUT"
+	}
+
+	return body
+}
+
+// statusCode names an HTTP status in the style of the error codes, e.g.
+// NOT_FOUND for 404.
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "ERROR"
+	}
+	return strings.ToUpper(strings.ReplaceAll(text, " ", "_"))
+}
+
+// errorResponse wraps err in the error envelope.
+func errorResponse(status int, err error) gin.H {
+	return gin.H{"error": newErrorBody(status, err)}
+}
+
+// respondError renders err in the error envelope with the given status.
+func respondError(c *gin.Context, status int, err error) {
+	c.JSON(status, errorResponse(status, err))
+}
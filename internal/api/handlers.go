@@ -2,220 +2,2010 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
 	"io/fs"
+	"log"
 	"net/http"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
 	"sqliter/internal/db"
 	"sqliter/internal/models"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Config holds the server options that change how requests are handled.
+type Config struct {
+	// DefaultSort orders table rows by primary key when no sort is requested.
+	DefaultSort bool
+	// MaxColumns caps the columns returned for wide tables; 0 means no limit.
+	MaxColumns int
+	// TrackUsage records which tables are browsed, for the recent tables list.
+	// It writes to the database on every table read, so it's off by default.
+	TrackUsage bool
+	// MaxSQLLength rejects console queries longer than this many bytes; 0 means no limit.
+	MaxSQLLength int
+	// Scopes restricts the listed tables to matching rows, keyed by table name.
+	Scopes map[string][]models.Scope
+	// RowKeyFormat adds each row's key to table data as an object, embedded
+	// columns or a token; empty adds none.
+	RowKeyFormat string
+	// MaxResponseBytes cuts off table data and query results once their rows
+	// reach this many bytes of JSON; 0 means no limit.
+	MaxResponseBytes int
+	// MaxRows stops reading console query results after this many rows,
+	// unless a request asks for another limit; 0 means no limit.
+	MaxRows int
+	// QueryTimeout interrupts console queries that run longer than this; 0
+	// means no limit.
+	QueryTimeout time.Duration
+	// MaxBlobSize rejects BLOB cell uploads over this many bytes; 0 means
+	// SQLite's own 1 GB limit.
+	MaxBlobSize int64
+	// RequestTimeout, JournalMode and BusyTimeout are applied outside the
+	// handler, by WithRequestTimeout and when opening the databases; they are
+	// only reported in the diagnostics.
+	RequestTimeout time.Duration
+	JournalMode    string
+	BusyTimeout    time.Duration
+	// AuthUser and AuthPass require HTTP basic auth for the API when set.
+	AuthUser string
+	AuthPass string
+	// AuthToken requires an "Authorization: Bearer" token for the API when set.
+	AuthToken string
+}
+
+// diagnostics returns the options reported by GetDiagnostics, leaving out
+// the credentials.
+func (c Config) diagnostics() map[string]interface{} {
+	return map[string]interface{}{
+		"default_sort":       c.DefaultSort,
+		"max_columns":        c.MaxColumns,
+		"usage_tracking":     c.TrackUsage,
+		"max_sql_length":     c.MaxSQLLength,
+		"scoped_tables":      len(c.Scopes),
+		"row_key_format":     c.RowKeyFormat,
+		"max_response_bytes": c.MaxResponseBytes,
+		"max_rows":           c.MaxRows,
+		"query_timeout":      c.QueryTimeout.String(),
+		"request_timeout":    c.RequestTimeout.String(),
+		"max_blob_size":      c.MaxBlobSize,
+		"journal_mode":       c.JournalMode,
+		"busy_timeout":       c.BusyTimeout.String(),
+		"auth":               c.AuthUser != "" || c.AuthToken != "",
+	}
+}
+
 type Handler struct {
-	db        *db.SQLiteDB
 	staticFS  fs.FS
+	config    Config
+
+	// mu guards dbs, whose entries are replaced when switching to a saved
+	// copy, and requests
+	mu  sync.RWMutex
+	dbs map[string]*db.SQLiteDB
+	// requests tracks the in-flight requests of each database, so a replaced
+	// one is closed only once they have finished
+	requests map[*db.SQLiteDB]*sync.WaitGroup
+	// names lists the databases in the order they were added; the first is
+	// served when a request doesn't select one
+	names []string
+}
+
+// NewHandler serves database under its filename. More databases can be
+// added with AddDatabase. It panics if the database can't be served, since
+// the handler would have nothing to serve.
+func NewHandler(database *db.SQLiteDB, staticFS fs.FS, config Config) *Handler {
+	h := &Handler{
+		staticFS: staticFS,
+		config:   config,
+		dbs:      make(map[string]*db.SQLiteDB),
+		requests: make(map[*db.SQLiteDB]*sync.WaitGroup),
+	}
+	if err := h.AddDatabase(database); err != nil {
+		panic("Failed to serve the database: " + err.Error())
+	}
+	return h
+}
+
+func (h *Handler) SaveAs(c *gin.Context) {
+	var req models.SaveAsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	current := h.database(c)
+	if err := current.SaveAs(req.Path, req.Overwrite); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, db.ErrTargetExists) {
+			status = http.StatusConflict
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	if req.Switch {
+		saved, err := current.OpenCopy(req.Path)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		// The copy keeps the name clients select it by
+		h.mu.Lock()
+		h.dbs[c.GetString(databaseNameKey)] = saved
+		retired := h.requests[current]
+		delete(h.requests, current)
+		h.requests[saved] = &sync.WaitGroup{}
+		h.mu.Unlock()
+
+		// Requests already using the old handle, this one included, finish
+		// before it's closed
+		go func() {
+			retired.Wait()
+			current.Close()
+		}()
+		c.Set(databaseKey, saved)
+	}
+
+	info, err := h.database(c).GetDatabaseInfo()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"path": req.Path, "switched": req.Switch, "filename": info.Filename})
+}
+
+func (h *Handler) GetDatabaseInfo(c *gin.Context) {
+	info, err := h.database(c).GetDatabaseInfo()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	info.Databases = h.databaseNames()
+
+	c.JSON(http.StatusOK, info)
+}
+
+func (h *Handler) GetWALStatus(c *gin.Context) {
+	status, err := h.database(c).GetWALStatus()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// IntegrityCheck reports whether the database file is intact. "ok" is true
+// only when SQLite's sole message is "ok".
+func (h *Handler) IntegrityCheck(c *gin.Context) {
+	messages, err := h.database(c).IntegrityCheck()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	ok := len(messages) == 1 && messages[0] == "ok"
+	c.JSON(http.StatusOK, gin.H{"ok": ok, "messages": messages})
+}
+
+// ForeignKeyCheck lists the rows that violate a foreign key.
+func (h *Handler) ForeignKeyCheck(c *gin.Context) {
+	violations, err := h.database(c).ForeignKeyCheck()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": len(violations) == 0, "violations": violations})
+}
+
+// GetTableStats returns each table's row count and size, plus the size of
+// the whole database file.
+func (h *Handler) GetTableStats(c *gin.Context) {
+	stats, err := h.database(c).GetTableStats(h.config.Scopes)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	size, err := h.database(c).DatabaseSize()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tables": stats, "database_size": size})
+}
+
+func (h *Handler) Checkpoint(c *gin.Context) {
+	result, err := h.database(c).Checkpoint()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) GetSetting(c *gin.Context) {
+	key := c.Param("key")
+
+	value, err := h.database(c).GetSetting(key)
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"key": key, "value": json.RawMessage(value)})
+}
+
+func (h *Handler) SetSetting(c *gin.Context) {
+	key := c.Param("key")
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, db.MaxSettingSize+1))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if len(body) > db.MaxSettingSize {
+		respondError(c, http.StatusRequestEntityTooLarge, fmt.Errorf("setting value exceeds the maximum size of %d bytes", db.MaxSettingSize))
+		return
+	}
+	if !json.Valid(body) {
+		respondError(c, http.StatusBadRequest, errors.New("setting value must be valid JSON"))
+		return
+	}
+
+	if err := h.database(c).SetSetting(key, string(body)); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"key": key, "value": json.RawMessage(body)})
+}
+
+func (h *Handler) DiffSchema(c *gin.Context) {
+	var req models.SchemaDiffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	diff, err := h.database(c).DiffSchema(req.Path)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// sqliteDriverModule is the module path of the SQLite driver, used to look up its version.
+const sqliteDriverModule = "github.com/mattn/go-sqlite3"
+
+func (h *Handler) GetDiagnostics(c *gin.Context) {
+	sqliteVersion, err := h.database(c).SQLiteVersion()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	walStatus, err := h.database(c).GetWALStatus()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	driverVersion := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == sqliteDriverModule {
+				driverVersion = dep.Version
+				break
+			}
+		}
+	}
+
+	config := h.config.diagnostics()
+	config["init_pragmas"] = h.database(c).InitPragmas()
+	config["read_only"] = h.database(c).ReadOnly()
+
+	c.JSON(http.StatusOK, models.Diagnostics{
+		SQLiteVersion: sqliteVersion,
+		DriverVersion: driverVersion,
+		GoVersion:     runtime.Version(),
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		JournalMode:   walStatus.JournalMode,
+		Config:        config,
+	})
+}
+
+func (h *Handler) GetTables(c *gin.Context) {
+	tables, err := h.database(c).GetTables()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tables": tables})
+}
+
+func (h *Handler) GetRecentTables(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		respondError(c, http.StatusBadRequest, errors.New("invalid limit parameter"))
+		return
+	}
+
+	tables, err := h.database(c).GetRecentTables(limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tables": tables})
+}
+
+func (h *Handler) GetTableSchema(c *gin.Context) {
+	tableName := c.Param("table")
+	if tableName == "" {
+		respondError(c, http.StatusBadRequest, errors.New("table name is required"))
+		return
+	}
+
+	columns, err := h.database(c).GetTableSchema(tableName)
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	uniqueConstraints, err := h.database(c).GetCompositeUniqueConstraints(tableName)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	foreignKeys, err := h.database(c).GetForeignKeys(tableName)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"columns": columns, "unique_constraints": uniqueConstraints, "foreign_keys": foreignKeys})
+}
+
+// GetRowByForeignKey follows a foreign key value to the referenced row.
+func (h *Handler) GetRowByForeignKey(c *gin.Context) {
+	data, err := h.database(c).GetRowByForeignKey(c.Param("table"), c.Param("column"), c.Param("value"), h.config.Scopes)
+	if err != nil {
+		status := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			// Anything else is a column that can't be followed
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+func (h *Handler) GetForeignKeys(c *gin.Context) {
+	foreignKeys, err := h.database(c).GetForeignKeys(c.Param("table"))
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"foreign_keys": foreignKeys})
+}
+
+func (h *Handler) GetFTSCandidates(c *gin.Context) {
+	candidates, err := h.database(c).GetFTSCandidates(c.Param("table"))
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, candidates)
+}
+
+// RunFTSCommand runs the FTS maintenance command in the path, rebuild or
+// integrity-check, on an FTS table.
+func (h *Handler) RunFTSCommand(c *gin.Context) {
+	tableName := c.Param("table")
+	command := c.Param("command")
+	if !db.IsFTSCommand(command) {
+		respondError(c, http.StatusBadRequest, errors.New("invalid FTS command, must be 'rebuild' or 'integrity-check'"))
+		return
+	}
+
+	if err := h.database(c).RunFTSCommand(tableName, command); err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"table": tableName, "command": command, "ok": true})
+}
+
+// CreateFTSIndex creates a full-text index over the requested columns that
+// triggers keep in sync with the table.
+func (h *Handler) CreateFTSIndex(c *gin.Context) {
+	tableName := c.Param("table")
+
+	var req models.CreateFTSRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	ftsName, err := h.database(c).CreateFTSIndex(tableName, req.Columns)
+	if err != nil {
+		status := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			// Anything else is an index SQLite can't create
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"table": tableName, "fts_table": ftsName, "columns": req.Columns})
+}
+
+// SearchFTS runs the full-text query in q against the table's full-text
+// index and returns the matching rows, best first.
+func (h *Handler) SearchFTS(c *gin.Context) {
+	tableName := c.Param("table")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		respondError(c, http.StatusBadRequest, errors.New("invalid limit parameter"))
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		respondError(c, http.StatusBadRequest, errors.New("invalid offset parameter"))
+		return
+	}
+
+	result, err := h.database(c).SearchFTS(tableName, c.Query("q"), limit, offset, h.config.Scopes[tableName]...)
+	if err != nil {
+		status := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			// Anything else is a query the full-text index can't parse
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) GetRowidChunks(c *gin.Context) {
+	tableName := c.Param("table")
+
+	size, err := strconv.Atoi(c.DefaultQuery("size", "1000"))
+	if err != nil || size <= 0 {
+		respondError(c, http.StatusBadRequest, errors.New("invalid size parameter, must be a positive integer"))
+		return
+	}
+
+	chunks, err := h.database(c).GetRowidChunks(tableName, size, h.config.Scopes[tableName]...)
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, chunks)
+}
+
+// GetColumnDistinctValues lists the distinct values of a column for
+// building filters. The limit defaults to 100 and is capped server-side.
+func (h *Handler) GetColumnDistinctValues(c *gin.Context) {
+	tableName := c.Param("table")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		respondError(c, http.StatusBadRequest, errors.New("invalid limit parameter, must be a positive integer"))
+		return
+	}
+
+	values, err := h.database(c).GetColumnDistinctValues(tableName, c.Param("column"), limit, h.config.Scopes[tableName]...)
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"values": values})
+}
+
+// GetColumnStats summarizes a column's values: null and distinct counts for
+// every column, and min, max, average and sum for numeric ones.
+func (h *Handler) GetColumnStats(c *gin.Context) {
+	tableName := c.Param("table")
+
+	stats, err := h.database(c).GetColumnStats(tableName, c.Param("column"), h.config.Scopes[tableName]...)
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
 }
 
-func NewHandler(database *db.SQLiteDB, staticFS fs.FS) *Handler {
-	return &Handler{db: database, staticFS: staticFS}
+func (h *Handler) GetTableData(c *gin.Context) {
+	tableName := c.Param("table")
+	if tableName == "" {
+		respondError(c, http.StatusBadRequest, errors.New("table name is required"))
+		return
+	}
+
+	limitStr := c.DefaultQuery("limit", "100")
+	offsetStr := c.DefaultQuery("offset", "0")
+	sortColumn := c.Query("sort_column")
+	sortDirection := c.Query("sort_direction")
+	collation := c.Query("collation")
+	format := c.Query("format")
+
+	filters, whereClause, err := rowFilters(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	sorting, err := sortParam(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var projection []string
+	if columnsParam := c.Query("columns"); columnsParam != "" {
+		projection = strings.Split(columnsParam, ",")
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errors.New("invalid limit parameter"))
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errors.New("invalid offset parameter"))
+		return
+	}
+
+	if format != "" && format != "rows" && format != "columnar" {
+		respondError(c, http.StatusBadRequest, errors.New("invalid format parameter, must be 'rows' or 'columnar'"))
+		return
+	}
+
+	keyCase := c.Query("key_case")
+	if !models.IsValidKeyCase(keyCase) {
+		respondError(c, http.StatusBadRequest, errors.New("invalid key_case parameter, must be 'camel', 'snake' or 'original'"))
+		return
+	}
+
+	// Validate sort direction if provided
+	if sortDirection != "" && sortDirection != "asc" && sortDirection != "desc" {
+		respondError(c, http.StatusBadRequest, errors.New("invalid sort_direction parameter, must be 'asc' or 'desc'"))
+		return
+	}
+
+	// Validate collation if provided
+	if collation != "" && !db.IsValidCollation(collation) {
+		respondError(c, http.StatusBadRequest, errors.New("invalid collation parameter, must be 'BINARY', 'NOCASE' or 'RTRIM'"))
+		return
+	}
+
+	// Parse foreign key label expansions in the form "col:label_column,col2:label_column2"
+	expansions := make(map[string]string)
+	if expand := c.Query("expand"); expand != "" {
+		for _, part := range strings.Split(expand, ",") {
+			column, labelColumn, ok := strings.Cut(part, ":")
+			if !ok || column == "" || labelColumn == "" {
+				respondError(c, http.StatusBadRequest, errors.New("invalid expand parameter, must be 'column:label_column'"))
+				return
+			}
+			expansions[column] = labelColumn
+		}
+	}
+
+	// Parse JSON extractions in the form "col:$.path,col2:$.other[0]"
+	var jsonPaths []models.JSONPath
+	if jsonPath := c.Query("json_path"); jsonPath != "" {
+		for _, part := range strings.Split(jsonPath, ",") {
+			column, path, ok := strings.Cut(part, ":")
+			if !ok || column == "" || !db.IsValidJSONPath(path) {
+				respondError(c, http.StatusBadRequest, errors.New("invalid json_path parameter, must be 'column:$.path'"))
+				return
+			}
+			jsonPaths = append(jsonPaths, models.JSONPath{Column: column, Path: path})
+		}
+	}
+
+	data, err := h.database(c).GetTableData(tableName, models.TableQuery{
+		Limit:            limit,
+		Offset:           offset,
+		SortColumn:       sortColumn,
+		SortDirection:    sortDirection,
+		Collation:        collation,
+		Sort:             sorting,
+		WhereClause:      whereClause,
+		Filters:          filters,
+		DefaultSort:      h.config.DefaultSort,
+		Columns:          projection,
+		MaxColumns:       h.config.MaxColumns,
+		Snapshot:         c.Query("snapshot"),
+		Search:           c.Query("search"),
+		Fold:             c.Query("fold") == "true",
+		Scopes:           h.config.Scopes[tableName],
+		JSONPaths:        jsonPaths,
+		RowKeyFormat:     h.config.RowKeyFormat,
+		MaxResponseBytes: h.config.MaxResponseBytes,
+		After:            c.Query("after"),
+		Count:            countMode(c),
+	})
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	if err := h.database(c).ExpandForeignKeyLabels(tableName, data.Rows, expansions); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if h.config.TrackUsage && !h.database(c).ReadOnly() {
+		if err := h.database(c).RecordTableAccess(tableName); err != nil {
+			log.Printf("Failed to record table access: %v", err)
+		}
+	}
+
+	if data.Total >= 0 {
+		if link := paginationLinks(c.Request.URL, limit, offset, data.Total); link != "" {
+			c.Header("Link", link)
+		}
+	}
+
+	if format == "columnar" {
+		columnar := data.Columnar()
+		columnar.ApplyKeyCase(keyCase)
+		c.JSON(http.StatusOK, columnar)
+		return
+	}
+
+	data.ApplyKeyCase(keyCase)
+	c.JSON(http.StatusOK, data)
+}
+
+// countMode reads how the total of table data is computed: skip_count=true
+// leaves it unknown (-1) and estimate_count=true reads it from the statistics
+// gathered by ANALYZE.
+func countMode(c *gin.Context) string {
+	switch {
+	case c.Query("skip_count") == "true":
+		return models.CountSkip
+	case c.Query("estimate_count") == "true":
+		return models.CountEstimate
+	}
+	return ""
+}
+
+// rowFilters parses the structured "filters" parameter, a JSON array such as
+// [{"column":"age","op":">=","value":30}], and the raw "where_clause", which
+// is only accepted together with allow_raw=true.
+func rowFilters(c *gin.Context) ([]models.Filter, string, error) {
+	var filters []models.Filter
+	if param := c.Query("filters"); param != "" {
+		if err := json.Unmarshal([]byte(param), &filters); err != nil {
+			return nil, "", fmt.Errorf("invalid filters parameter, must be a JSON array of {column, op, value}: %w", err)
+		}
+	}
+
+	whereClause := c.Query("where_clause")
+	if whereClause != "" && c.Query("allow_raw") != "true" {
+		return nil, "", fmt.Errorf("where_clause requires allow_raw=true; use filters instead")
+	}
+
+	return filters, whereClause, nil
+}
+
+// sortParam parses the "sort" parameter, a comma-separated list such as
+// "status:asc,created_at:desc". The direction defaults to asc. Columns are
+// checked against the schema when the query runs.
+func sortParam(c *gin.Context) ([]models.SortTerm, error) {
+	param := c.Query("sort")
+	if param == "" {
+		return nil, nil
+	}
+
+	var terms []models.SortTerm
+	for _, part := range strings.Split(param, ",") {
+		column, direction, ok := strings.Cut(part, ":")
+		if !ok {
+			direction = "asc"
+		}
+		if column == "" || direction != "asc" && direction != "desc" {
+			return nil, fmt.Errorf("invalid sort parameter, must be 'column:asc' or 'column:desc' separated by commas")
+		}
+		terms = append(terms, models.SortTerm{Column: column, Direction: direction})
+	}
+	return terms, nil
+}
+
+// paginationLinks builds an RFC 5988 Link header with first/prev/next/last
+// page URLs derived from the request URL's limit and offset parameters.
+func paginationLinks(requestURL *url.URL, limit, offset, total int) string {
+	if limit <= 0 {
+		return ""
+	}
+
+	pageURL := func(pageOffset int) string {
+		u := *requestURL
+		query := u.Query()
+		query.Set("limit", strconv.Itoa(limit))
+		query.Set("offset", strconv.Itoa(pageOffset))
+		u.RawQuery = query.Encode()
+		return u.RequestURI()
+	}
+
+	lastOffset := 0
+	if total > 0 {
+		lastOffset = (total - 1) / limit * limit
+	}
+
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, pageURL(0))}
+	if offset > 0 {
+		prevOffset := offset - limit
+		if prevOffset < 0 {
+			prevOffset = 0
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(prevOffset)))
+	}
+	if offset+limit < total {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(offset+limit)))
+	}
+	links = append(links, fmt.Sprintf(`<%s>; rel="last"`, pageURL(lastOffset)))
+
+	return strings.Join(links, ", ")
+}
+
+func (h *Handler) HeadTableData(c *gin.Context) {
+	tableName := c.Param("table")
+	if tableName == "" {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	filters, whereClause, err := rowFilters(c)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	total, err := h.database(c).CountTableRows(tableName, models.TableQuery{
+		WhereClause: whereClause,
+		Filters:     filters,
+		Scopes:      h.config.Scopes[tableName],
+	})
+	if err != nil {
+		c.Status(errorStatus(err))
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.Status(http.StatusOK)
+}
+
+func (h *Handler) BeginSnapshot(c *gin.Context) {
+	snapshot, err := h.database(c).BeginSnapshot()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, snapshot)
+}
+
+func (h *Handler) CloseSnapshot(c *gin.Context) {
+	if err := h.database(c).CloseSnapshot(c.Param("token")); err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "snapshot closed successfully"})
+}
+
+func (h *Handler) InsertRow(c *gin.Context) {
+	tableName := c.Param("table")
+	if tableName == "" {
+		respondError(c, http.StatusBadRequest, errors.New("table name is required"))
+		return
+	}
+
+	var req models.InsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var err error
+	if len(req.Columns) > 0 || len(req.Values) > 0 {
+		if status, msg := h.validatePositionalInsert(c, tableName, req.Columns, req.Values); status != 0 {
+			respondError(c, status, errors.New(msg))
+			return
+		}
+		err = h.database(c).InsertRowValues(tableName, req.Columns, req.Values)
+	} else {
+		err = h.database(c).InsertRow(tableName, req.Data)
+	}
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "row inserted successfully"})
+}
+
+// BulkInsert inserts many rows in one transaction. If any row fails, none are
+// inserted and the response names the failing row's index.
+func (h *Handler) BulkInsert(c *gin.Context) {
+	tableName := c.Param("table")
+
+	var req models.BulkInsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	returning := c.Query("returning") == "true"
+	var inserted int64
+	var returned []models.Row
+	var err error
+	if returning {
+		returned, err = h.database(c).BulkInsertReturning(tableName, req.Rows)
+		inserted = int64(len(returned))
+	} else {
+		inserted, err = h.database(c).BulkInsert(tableName, req.Rows)
+	}
+	if err != nil {
+		// Rejected rows are the client's data, not a server failure
+		status := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	response := gin.H{"message": "rows inserted successfully", "rows_affected": inserted}
+	if returning {
+		response["rows"] = returned
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// validatePositionalInsert checks that a positional insert has one value per
+// column and only names columns that exist. It returns a zero status when the
+// request is valid.
+func (h *Handler) validatePositionalInsert(c *gin.Context, tableName string, columns []string, values []interface{}) (int, string) {
+	if len(columns) == 0 {
+		return http.StatusBadRequest, "columns are required"
+	}
+	if len(columns) != len(values) {
+		return http.StatusBadRequest, fmt.Sprintf("got %d columns but %d values", len(columns), len(values))
+	}
+
+	schema, err := h.database(c).GetTableSchema(tableName)
+	if err != nil {
+		return errorStatus(err), err.Error()
+	}
+
+	existing := make(map[string]bool, len(schema))
+	for _, col := range schema {
+		existing[col.Name] = true
+	}
+	for _, col := range columns {
+		if !existing[col] {
+			return http.StatusBadRequest, fmt.Sprintf("unknown column: %s", col)
+		}
+	}
+
+	return 0, ""
+}
+
+// GetCell sends a single cell's raw value, so large TEXT or BLOB values can be
+// downloaded without going through the table grid. The value is written
+// straight from the row rather than buffered first.
+func (h *Handler) GetCell(c *gin.Context) {
+	tableName := c.Param("table")
+	h.sendCell(c, tableName, func(start func(string, int64)) error {
+		return h.database(c).WriteCell(tableName, c.Param("id"), c.Param("column"), c.Writer, start, h.config.Scopes[tableName]...)
+	})
+}
+
+// GetBlob downloads a single cell's raw bytes like GetCell. The row is
+// identified by a key token in "key" or by its primary key columns as query
+// parameters.
+func (h *Handler) GetBlob(c *gin.Context) {
+	tableName := c.Param("table")
+
+	var where map[string]interface{}
+	if key := c.Query("key"); key != "" {
+		decoded, err := db.DecodeRowKey(key)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+		where = decoded
+	} else {
+		where = make(map[string]interface{})
+		for name, values := range c.Request.URL.Query() {
+			if name != "db" {
+				where[name] = values[0]
+			}
+		}
+	}
+
+	h.sendCell(c, tableName, func(start func(string, int64)) error {
+		return h.database(c).WriteCellByKey(tableName, c.Param("column"), where, c.Writer, start, h.config.Scopes[tableName]...)
+	})
+}
+
+// sendCell responds with the cell that write streams to c.Writer, setting the
+// status and headers when write starts.
+func (h *Handler) sendCell(c *gin.Context, tableName string, write func(start func(storageClass string, size int64)) error) {
+	started := false
+	err := write(func(storageClass string, size int64) {
+		started = true
+		if storageClass == "null" {
+			c.Status(http.StatusNoContent)
+			c.Writer.WriteHeaderNow()
+			return
+		}
+
+		contentType := "text/plain; charset=utf-8"
+		if storageClass == "blob" {
+			contentType = "application/octet-stream"
+		}
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Length", strconv.FormatInt(size, 10))
+		c.Status(http.StatusOK)
+	})
+	if err != nil {
+		if started {
+			// The response is under way, so the client sees a short body
+			log.Printf("Writing cell of %s failed: %v", tableName, err)
+			return
+		}
+		respondError(c, errorStatus(err), err)
+	}
+}
+
+// PutCell writes the request body into a BLOB cell, so files can be uploaded
+// without a full-row insert. A body whose Content-Length is over the limit is
+// rejected before it is read.
+func (h *Handler) PutCell(c *gin.Context) {
+	tableName := c.Param("table")
+	if limit := h.config.MaxBlobSize; limit > 0 && c.Request.ContentLength > limit {
+		respondError(c, http.StatusRequestEntityTooLarge, &db.BlobSizeError{Column: c.Param("column"), Limit: limit})
+		return
+	}
+
+	written, err := h.database(c).WriteBlobCell(tableName, c.Param("id"), c.Param("column"), c.Request.Body, h.config.MaxBlobSize, h.config.Scopes[tableName]...)
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "cell updated successfully", "bytes_written": written})
+}
+
+// GenerateInsertSQL returns ready-to-run INSERT statements for the selected
+// rows, for copying them to another database.
+func (h *Handler) GenerateInsertSQL(c *gin.Context) {
+	tableName := c.Param("table")
+
+	var req models.GenerateSQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.IDs) == 0 && len(req.Filters) == 0 {
+		respondError(c, http.StatusBadRequest, errors.New("ids or filters are required"))
+		return
+	}
+
+	statements, count, err := h.database(c).GenerateInsertSQL(tableName, req.IDs, req.Filters, h.config.Scopes[tableName]...)
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sql": statements, "count": count})
+}
+
+func (h *Handler) UpdateRow(c *gin.Context) {
+	tableName := c.Param("table")
+	if tableName == "" {
+		respondError(c, http.StatusBadRequest, errors.New("table name is required"))
+		return
+	}
+
+	var req models.UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	where, err := rowWhere(req.Where, req.Key)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if c.Query("require_pk") == "true" {
+		if err := h.database(c).RequirePrimaryKey(tableName, where); err != nil {
+			respondError(c, errorStatus(err), err)
+			return
+		}
+	}
+
+	result, err := h.database(c).UpdateRow(tableName, req.Data, where)
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "row updated successfully",
+		"rows_affected": result.RowsAffected,
+		"noop":          result.Noop,
+		"changes":       result.Changes,
+	})
+}
+
+// rowWhere returns the conditions identifying the row to change: the request's
+// where map, or its key as returned in table data.
+func rowWhere(where map[string]interface{}, key interface{}) (map[string]interface{}, error) {
+	if key == nil {
+		return where, nil
+	}
+	if len(where) > 0 {
+		return nil, fmt.Errorf("specify either where or key, not both")
+	}
+	return db.DecodeRowKey(key)
+}
+
+func (h *Handler) DeleteRow(c *gin.Context) {
+	tableName := c.Param("table")
+	if tableName == "" {
+		respondError(c, http.StatusBadRequest, errors.New("table name is required"))
+		return
+	}
+
+	var req models.DeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	where, err := rowWhere(req.Where, req.Key)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if c.Query("require_pk") == "true" {
+		if err := h.database(c).RequirePrimaryKey(tableName, where); err != nil {
+			respondError(c, errorStatus(err), err)
+			return
+		}
+	}
+
+	deleted, err := h.database(c).DeleteRow(tableName, where)
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "row deleted successfully", "rows_affected": deleted})
+}
+
+// resultTableTemplate renders a query result as a minimal HTML table. Cell
+// values are escaped by html/template.
+var resultTableTemplate = template.Must(template.New("result").Parse(`<table>
+<thead><tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr>{{range .}}<td>{{if eq . nil}}NULL{{else}}{{.}}{{end}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+`))
+
+func (h *Handler) ExecuteSQL(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" && strings.Contains(c.GetHeader("Accept"), "text/html") {
+		format = "html"
+	}
+	if format != "" && format != "rows" && format != "columnar" && format != "html" {
+		respondError(c, http.StatusBadRequest, errors.New("invalid format parameter, must be 'rows', 'columnar' or 'html'"))
+		return
+	}
+
+	keyCase := c.Query("key_case")
+	if !models.IsValidKeyCase(keyCase) {
+		respondError(c, http.StatusBadRequest, errors.New("invalid key_case parameter, must be 'camel', 'snake' or 'original'"))
+		return
+	}
+
+	var req models.ExecuteSQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if strings.TrimSpace(req.SQL) == "" {
+		respondError(c, http.StatusBadRequest, errors.New("SQL query cannot be empty"))
+		return
+	}
+
+	if err := h.checkSQLLength(req.SQL); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if h.database(c).ReadOnly() && !db.IsSelectQuery(req.SQL) {
+		readOnlyError(c)
+		return
+	}
+
+	maxRows, err := h.maxRows(req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if h.config.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.config.QueryTimeout)
+		defer cancel()
+	}
+
+	result, err := h.database(c).ExecuteSQLContext(ctx, req.SQL, maxRows, h.config.MaxResponseBytes, req.Params...)
+	if err != nil {
+		if errors.Is(err, db.ErrReadOnly) {
+			readOnlyError(c)
+			return
+		}
+		if errors.Is(err, db.ErrQueryTimeout) {
+			respondError(c, http.StatusServiceUnavailable, fmt.Errorf("query timed out after %s: %w", h.config.QueryTimeout, err))
+			return
+		}
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Serialize numbers as strings so that 64-bit integers keep their precision in JavaScript
+	if c.Query("numbers_as_strings") == "true" {
+		result.StringifyNumbers()
+	}
+	result.ApplyKeyCase(keyCase)
+
+	if format == "columnar" {
+		c.JSON(http.StatusOK, result.Columnar())
+		return
+	}
+
+	if format == "html" {
+		var buf bytes.Buffer
+		if err := resultTableTemplate.Execute(&buf, result); err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetQueryHistory lists the statements recently run in the SQL console,
+// newest first.
+func (h *Handler) GetQueryHistory(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"history": h.database(c).QueryHistory()})
+}
+
+// ClearQueryHistory forgets the recorded SQL console statements.
+func (h *Handler) ClearQueryHistory(c *gin.Context) {
+	if err := h.database(c).ClearQueryHistory(); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "query history cleared"})
+}
+
+// SaveQuery stores a named SQL snippet. An existing name is only replaced
+// with overwrite=true.
+func (h *Handler) SaveQuery(c *gin.Context) {
+	var req models.SaveQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	saved, err := h.database(c).SaveQuery(req.Name, req.SQL, c.Query("overwrite") == "true")
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, saved)
+}
+
+// GetSavedQueries lists the saved SQL snippets ordered by name.
+func (h *Handler) GetSavedQueries(c *gin.Context) {
+	saved, err := h.database(c).SavedQueries()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"queries": saved})
+}
+
+// DeleteSavedQuery removes a saved SQL snippet.
+func (h *Handler) DeleteSavedQuery(c *gin.Context) {
+	if err := h.database(c).DeleteSavedQuery(c.Param("name")); err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "saved query deleted successfully"})
+}
+
+// AddColumn adds a column to an existing table and responds with the
+// refreshed schema.
+func (h *Handler) AddColumn(c *gin.Context) {
+	tableName := c.Param("table")
+
+	var col models.Column
+	if err := c.ShouldBindJSON(&col); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.database(c).AddColumn(tableName, col); err != nil {
+		status := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			// Anything else is a definition SQLite can't add
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	columns, err := h.database(c).GetTableSchema(tableName)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"name": tableName, "columns": columns})
+}
+
+func (h *Handler) GetIndexes(c *gin.Context) {
+	indexes, err := h.database(c).GetIndexes(c.Param("table"))
+	if err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"indexes": indexes})
+}
+
+func (h *Handler) CreateIndex(c *gin.Context) {
+	var req models.CreateIndexRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	index, err := h.database(c).CreateIndex(c.Param("table"), req)
+	if err != nil {
+		status := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			// Anything else is an index SQLite can't create
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, index)
+}
+
+// AttachDatabase attaches a database file to the SQL console under an alias
+// and returns the current attachments.
+func (h *Handler) AttachDatabase(c *gin.Context) {
+	var req models.AttachRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	database := h.database(c)
+	if err := database.AttachDatabase(req.Alias, req.Path); err != nil {
+		status := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			// Anything else is an alias or file SQLite can't attach
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "database attached successfully", "attached": database.AttachedDatabases()})
 }
 
-func (h *Handler) GetDatabaseInfo(c *gin.Context) {
-	info, err := h.db.GetDatabaseInfo()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// DetachDatabase detaches a database attached with AttachDatabase.
+func (h *Handler) DetachDatabase(c *gin.Context) {
+	var req models.AttachRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, info)
+	database := h.database(c)
+	if err := database.DetachDatabase(req.Alias); err != nil {
+		respondError(c, errorStatus(err), err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "database detached successfully", "attached": database.AttachedDatabases()})
 }
 
-func (h *Handler) GetTables(c *gin.Context) {
-	tables, err := h.db.GetTables()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// RenameTable renames a table to the request's new_name.
+func (h *Handler) RenameTable(c *gin.Context) {
+	var req models.RenameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"tables": tables})
+	if err := h.database(c).RenameTable(c.Param("table"), req.NewName); err != nil {
+		status := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "table renamed successfully", "name": req.NewName})
 }
 
-func (h *Handler) GetTableSchema(c *gin.Context) {
+// RenameColumn renames a column and responds with the refreshed schema.
+func (h *Handler) RenameColumn(c *gin.Context) {
 	tableName := c.Param("table")
-	if tableName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "table name is required"})
+
+	var req models.RenameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.database(c).RenameColumn(tableName, c.Param("column"), req.NewName); err != nil {
+		status := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
 		return
 	}
 
-	columns, err := h.db.GetTableSchema(tableName)
+	columns, err := h.database(c).GetTableSchema(tableName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"columns": columns})
+	c.JSON(http.StatusOK, gin.H{"name": tableName, "columns": columns})
 }
 
-func (h *Handler) GetTableData(c *gin.Context) {
-	tableName := c.Param("table")
-	if tableName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "table name is required"})
+// CreateTable creates a table from a structured definition and responds with
+// its schema as SQLite reports it.
+func (h *Handler) CreateTable(c *gin.Context) {
+	var req models.CreateTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	limitStr := c.DefaultQuery("limit", "100")
-	offsetStr := c.DefaultQuery("offset", "0")
-	sortColumn := c.Query("sort_column")
-	sortDirection := c.Query("sort_direction")
-	whereClause := c.Query("where_clause")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit parameter"})
+	if err := h.database(c).CreateTable(req.Name, req.Columns); err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
+	columns, err := h.database(c).GetTableSchema(req.Name)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset parameter"})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	// Validate sort direction if provided
-	if sortDirection != "" && sortDirection != "asc" && sortDirection != "desc" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort_direction parameter, must be 'asc' or 'desc'"})
+	c.JSON(http.StatusCreated, gin.H{"name": req.Name, "columns": columns})
+}
+
+func (h *Handler) GetViews(c *gin.Context) {
+	views, err := h.database(c).GetViews()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	data, err := h.db.GetTableData(tableName, limit, offset, sortColumn, sortDirection, whereClause)
+	c.JSON(http.StatusOK, gin.H{"views": views})
+}
+
+// GetTriggers lists the triggers of the table in the path, or of the whole
+// database on the /triggers route.
+func (h *Handler) GetTriggers(c *gin.Context) {
+	triggers, err := h.database(c).GetTriggers(c.Param("table"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, errorStatus(err), err)
 		return
 	}
 
-	c.JSON(http.StatusOK, data)
+	c.JSON(http.StatusOK, gin.H{"triggers": triggers})
 }
 
-func (h *Handler) InsertRow(c *gin.Context) {
-	tableName := c.Param("table")
-	if tableName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "table name is required"})
+func (h *Handler) CreateView(c *gin.Context) {
+	var req models.CreateViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	var req models.InsertRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.database(c).CreateView(req.Name, req.Select); err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	if err := h.db.InsertRow(tableName, req.Data); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.JSON(http.StatusCreated, gin.H{"message": "view created successfully"})
+}
+
+func (h *Handler) DropView(c *gin.Context) {
+	if err := h.database(c).DropView(c.Param("name")); err != nil {
+		respondError(c, errorStatus(err), err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "row inserted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "view dropped successfully"})
 }
 
-func (h *Handler) UpdateRow(c *gin.Context) {
+// DropTable drops a table. As a safeguard against accidental deletes, the
+// confirm query parameter must repeat the table name.
+func (h *Handler) DropTable(c *gin.Context) {
 	tableName := c.Param("table")
-	if tableName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "table name is required"})
+	if c.Query("confirm") != tableName {
+		respondError(c, http.StatusBadRequest, errors.New("confirm parameter must match the table name"))
 		return
 	}
 
-	var req models.UpdateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.database(c).DropTable(tableName); err != nil {
+		respondError(c, errorStatus(err), err)
 		return
 	}
 
-	if err := h.db.UpdateRow(tableName, req.Data, req.Where); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, gin.H{"message": "table dropped successfully"})
+}
+
+func (h *Handler) DropTrigger(c *gin.Context) {
+	if err := h.database(c).DropTrigger(c.Param("name")); err != nil {
+		respondError(c, errorStatus(err), err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "row updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "trigger dropped successfully"})
 }
 
-func (h *Handler) DeleteRow(c *gin.Context) {
-	tableName := c.Param("table")
-	if tableName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "table name is required"})
+// errorStatus maps a database error to an HTTP status code.
+func errorStatus(err error) int {
+	var notFound *db.NotFoundError
+	if errors.As(err, &notFound) {
+		return http.StatusNotFound
+	}
+	var tooLong *db.LengthError
+	if errors.As(err, &tooLong) {
+		return http.StatusUnprocessableEntity
+	}
+	var tooLarge *db.BlobSizeError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	var wrongType *db.ColumnTypeError
+	if errors.As(err, &wrongType) {
+		return http.StatusBadRequest
+	}
+	var badFilter *db.FilterError
+	if errors.As(err, &badFilter) {
+		return http.StatusBadRequest
+	}
+	var constraint *db.ConstraintError
+	if errors.As(err, &constraint) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, db.ErrFTS5Unavailable) {
+		return http.StatusNotImplemented
+	}
+	var incompleteKey *db.IncompleteKeyError
+	if errors.As(err, &incompleteKey) {
+		return http.StatusBadRequest
+	}
+	var noKey *db.NoPrimaryKeyError
+	if errors.As(err, &noKey) {
+		return http.StatusBadRequest
+	}
+	var conflict *db.ConflictError
+	if errors.As(err, &conflict) {
+		return http.StatusConflict
+	}
+	var notFTS *db.NotFTSTableError
+	if errors.As(err, &notFTS) {
+		return http.StatusBadRequest
+	}
+	var badSort *db.SortError
+	if errors.As(err, &badSort) {
+		return http.StatusBadRequest
+	}
+	var badCursor *db.CursorError
+	if errors.As(err, &badCursor) {
+		return http.StatusBadRequest
+	}
+	var badParams *db.ParamError
+	if errors.As(err, &badParams) {
+		return http.StatusBadRequest
+	}
+	var badSavedQuery *db.SavedQueryError
+	if errors.As(err, &badSavedQuery) {
+		return http.StatusBadRequest
+	}
+	var badPreview *db.PreviewError
+	if errors.As(err, &badPreview) {
+		return http.StatusBadRequest
+	}
+	var ftsFailed *db.FTSCommandError
+	if errors.As(err, &ftsFailed) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *Handler) ValidateSQL(c *gin.Context) {
+	var req models.ExecuteSQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	var req models.DeleteRequest
+	if strings.TrimSpace(req.SQL) == "" {
+		respondError(c, http.StatusBadRequest, errors.New("SQL query cannot be empty"))
+		return
+	}
+
+	if err := h.checkSQLLength(req.SQL); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.database(c).ValidateSQL(req.SQL))
+}
+
+// ExplainSQL returns the query plan of a statement without running it.
+func (h *Handler) ExplainSQL(c *gin.Context) {
+	var req models.ExecuteSQLRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if strings.TrimSpace(req.SQL) == "" {
+		respondError(c, http.StatusBadRequest, errors.New("SQL query cannot be empty"))
+		return
+	}
+
+	if err := h.checkSQLLength(req.SQL); err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	if err := h.db.DeleteRow(tableName, req.Where); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	result, err := h.database(c).ExplainQuery(req.SQL)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "row deleted successfully"})
+	c.JSON(http.StatusOK, result)
 }
 
-func (h *Handler) ExecuteSQL(c *gin.Context) {
+// PreviewSQL reports how many rows a DELETE or UPDATE would affect without
+// committing it.
+func (h *Handler) PreviewSQL(c *gin.Context) {
 	var req models.ExecuteSQLRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if strings.TrimSpace(req.SQL) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "SQL query cannot be empty"})
+		respondError(c, http.StatusBadRequest, errors.New("SQL query cannot be empty"))
+		return
+	}
+
+	if err := h.checkSQLLength(req.SQL); err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	result, err := h.db.ExecuteSQL(req.SQL)
+	result, err := h.database(c).PreviewSQL(req.SQL)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, errorStatus(err), err)
 		return
 	}
 
 	c.JSON(http.StatusOK, result)
 }
 
-func (h *Handler) ExportTableCSV(c *gin.Context) {
-	tableName := c.Param("table")
-	if tableName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "table name is required"})
+// requireWritable stops requests that would change a database opened
+// read-only before they reach their handler.
+func (h *Handler) requireWritable(c *gin.Context) {
+	if h.database(c).ReadOnly() {
+		readOnlyError(c)
+		c.Abort()
+	}
+}
+
+func readOnlyError(c *gin.Context) {
+	respondError(c, http.StatusForbidden, db.ErrReadOnly)
+}
+
+// checkSQLLength rejects query text over the configured maximum before it
+// reaches SQLite's parser.
+func (h *Handler) checkSQLLength(query string) error {
+	if h.config.MaxSQLLength > 0 && len(query) > h.config.MaxSQLLength {
+		return fmt.Errorf("SQL query is %d bytes, exceeding the maximum of %d bytes", len(query), h.config.MaxSQLLength)
+	}
+	return nil
+}
+
+// maxRows returns the row limit for a console query: --max-rows, or the
+// request's own maxRows when it is lower. Without --max-rows a request may
+// set any limit, or 0 for none.
+func (h *Handler) maxRows(req models.ExecuteSQLRequest) (int, error) {
+	if req.MaxRows == nil {
+		return h.config.MaxRows, nil
+	}
+	if *req.MaxRows < 0 {
+		return 0, errors.New("maxRows must not be negative")
+	}
+	if h.config.MaxRows > 0 && (*req.MaxRows == 0 || *req.MaxRows > h.config.MaxRows) {
+		return h.config.MaxRows, nil
+	}
+	return *req.MaxRows, nil
+}
+
+// ExecuteSQLScript runs several semicolon-separated statements in one
+// transaction, rolling all of them back if any fails.
+func (h *Handler) ExecuteSQLScript(c *gin.Context) {
+	var req models.ExecuteSQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	sortColumn := c.Query("sort_column")
-	sortDirection := c.Query("sort_direction")
-	whereClause := c.Query("where_clause")
+	if err := h.checkSQLLength(req.SQL); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
 
-	// Validate sort direction if provided
-	if sortDirection != "" && sortDirection != "asc" && sortDirection != "desc" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort_direction parameter, must be 'asc' or 'desc'"})
+	maxRows, err := h.maxRows(req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.database(c).ExecuteSQLScriptWithLimits(req.SQL, maxRows, h.config.MaxResponseBytes)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// csvOptions reads the CSV rendering options: "null", the text written for
+// NULL cells, "binary", either base64 (default) or placeholder, "delimiter",
+// a single character such as ; or a tab, and "crlf" for \r\n line endings.
+func csvOptions(c *gin.Context) (models.CSVOptions, error) {
+	opts := models.CSVOptions{
+		Null:   c.Query("null"),
+		Binary: c.DefaultQuery("binary", models.CSVBinaryBase64),
+		CRLF:   c.Query("crlf") == "true",
+	}
+	if opts.Binary != models.CSVBinaryBase64 && opts.Binary != models.CSVBinaryPlaceholder {
+		return opts, fmt.Errorf("invalid binary parameter, must be 'base64' or 'placeholder'")
+	}
+
+	if delimiter := c.Query("delimiter"); delimiter != "" {
+		r, size := utf8.DecodeRuneInString(delimiter)
+		if size != len(delimiter) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+			return opts, fmt.Errorf("invalid delimiter parameter, must be a single character other than a quote or newline")
+		}
+		opts.Delimiter = r
+	}
+	return opts, nil
+}
+
+// newCSVWriter returns a CSV writer using the delimiter and line endings of
+// the export options.
+func newCSVWriter(w io.Writer, opts models.CSVOptions) *csv.Writer {
+	writer := csv.NewWriter(w)
+	if opts.Delimiter != 0 {
+		writer.Comma = opts.Delimiter
+	}
+	writer.UseCRLF = opts.CRLF
+	return writer
+}
+
+func (h *Handler) ExportSQLCSV(c *gin.Context) {
+	var req models.ExecuteSQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.checkSQLLength(req.SQL); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	opts, err := csvOptions(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	// Create a buffer to write CSV data
 	var buf bytes.Buffer
-	writer := csv.NewWriter(&buf)
+	writer := newCSVWriter(&buf, opts)
+
+	if err := h.database(c).ExportQueryCSV(req.SQL, opts, writer); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=query_export.csv")
+	c.Data(http.StatusOK, "text/csv", buf.Bytes())
+}
+
+// exportQuery reads the sorting and filtering parameters of a table export,
+// which are the same as those of the data endpoint.
+func (h *Handler) exportQuery(c *gin.Context, tableName string) (models.TableQuery, error) {
+	q := models.TableQuery{
+		SortColumn:    c.Query("sort_column"),
+		SortDirection: c.Query("sort_direction"),
+		Collation:     c.Query("collation"),
+		DefaultSort:   h.config.DefaultSort,
+		Scopes:        h.config.Scopes[tableName],
+	}
+
+	var err error
+	q.Filters, q.WhereClause, err = rowFilters(c)
+	if err != nil {
+		return q, err
+	}
+	q.Sort, err = sortParam(c)
+	if err != nil {
+		return q, err
+	}
+
+	// Validate sort direction if provided
+	if q.SortDirection != "" && q.SortDirection != "asc" && q.SortDirection != "desc" {
+		return q, fmt.Errorf("invalid sort_direction parameter, must be 'asc' or 'desc'")
+	}
+
+	// Validate collation if provided
+	if q.Collation != "" && !db.IsValidCollation(q.Collation) {
+		return q, fmt.Errorf("invalid collation parameter, must be 'BINARY', 'NOCASE' or 'RTRIM'")
+	}
+
+	return q, nil
+}
+
+func (h *Handler) ExportTableCSV(c *gin.Context) {
+	tableName := c.Param("table")
+	if tableName == "" {
+		respondError(c, http.StatusBadRequest, errors.New("table name is required"))
+		return
+	}
+
+	q, err := h.exportQuery(c, tableName)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
 
-	// Export data to CSV
-	if err := h.db.ExportTableCSV(tableName, sortColumn, sortDirection, whereClause, writer); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	opts, err := csvOptions(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	// Set appropriate headers for file download
+	// Stream the CSV straight to the response. Errors found before the first
+	// flush still get a JSON error response; later ones cut the download short.
 	filename := tableName + "_export.csv"
 	c.Header("Content-Disposition", "attachment; filename="+filename)
 	c.Header("Content-Type", "text/csv")
 
-	// Return the CSV data
-	c.Data(http.StatusOK, "text/csv", buf.Bytes())
+	writer := newCSVWriter(c.Writer, opts)
+	if err := h.database(c).ExportTableCSV(tableName, q, opts, writer); err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			respondError(c, errorStatus(err), err)
+			return
+		}
+		log.Printf("CSV export of %s failed: %v", tableName, err)
+		c.Abort()
+	}
+}
+
+// ExportTableJSON streams the table as a JSON array of row objects. Errors
+// found before the first byte is written get a JSON error response; later
+// ones can only cut the download short.
+func (h *Handler) ExportTableJSON(c *gin.Context) {
+	tableName := c.Param("table")
+
+	q, err := h.exportQuery(c, tableName)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+tableName+"_export.json")
+	c.Header("Content-Type", "application/json")
+	if err := h.database(c).ExportTableJSON(tableName, q, c.Writer); err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			respondError(c, errorStatus(err), err)
+			return
+		}
+		log.Printf("JSON export of %s failed: %v", tableName, err)
+		c.Abort()
+	}
+}
+
+// DumpSQL streams the whole database as a SQL script that recreates it.
+// Scoped tables only contribute their scoped rows.
+func (h *Handler) DumpSQL(c *gin.Context) {
+	database := h.database(c)
+	info, err := database.GetDatabaseInfo()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	filename := strings.TrimSuffix(info.Filename, filepath.Ext(info.Filename)) + ".sql"
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", "application/sql")
+	if err := database.DumpSQL(c.Writer, h.config.Scopes); err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		log.Printf("SQL dump failed: %v", err)
+		c.Abort()
+	}
+}
+
+// Backup downloads a consistent copy of the database file, named after it
+// with the time of the backup. The copy holds every row, so it's refused when
+// tables are scoped.
+func (h *Handler) Backup(c *gin.Context) {
+	if len(h.config.Scopes) > 0 {
+		respondError(c, http.StatusForbidden, errors.New("backups are disabled while tables are scoped, since the file holds every row; use the SQL dump instead"))
+		return
+	}
+
+	database := h.database(c)
+	info, err := database.GetDatabaseInfo()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	ext := filepath.Ext(info.Filename)
+	if ext == "" {
+		ext = ".db"
+	}
+	filename := strings.TrimSuffix(info.Filename, filepath.Ext(info.Filename)) + "-backup-" + time.Now().Format("20060102-150405") + ext
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", "application/x-sqlite3")
+	if err := database.Backup(c.Writer); err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		log.Printf("Backup failed: %v", err)
+		c.Abort()
+	}
+}
+
+// ImportTableCSV inserts the rows of an uploaded CSV file ("file" form field)
+// into the table. Fields are matched to columns by the header row, or by
+// position with header=false. Rows that fail are skipped and listed in errors.
+func (h *Handler) ImportTableCSV(c *gin.Context) {
+	tableName := c.Param("table")
+
+	upload, err := c.FormFile("file")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errors.New("a CSV file upload in the 'file' field is required"))
+		return
+	}
+	file, err := upload.Open()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	defer file.Close()
+
+	imported, err := h.database(c).ImportTableCSV(tableName, file, c.DefaultQuery("header", "true") != "false")
+	rowErrors := []gin.H{}
+	var failed *db.CSVImportError
+	if errors.As(err, &failed) {
+		for _, row := range failed.Rows {
+			rowErrors = append(rowErrors, gin.H{"row": row.Index, "error": newErrorBody(http.StatusBadRequest, row.Err)})
+		}
+	} else if err != nil {
+		// A malformed file is the client's data, not a server failure
+		status := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	if imported > 0 && c.Query("analyze") == "true" {
+		if err := h.database(c).AnalyzeTable(tableName); err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imported": imported, "errors": rowErrors})
 }
 
 func (h *Handler) SetupRoutes() *gin.Engine {
@@ -227,7 +2017,8 @@ func (h *Handler) SetupRoutes() *gin.Engine {
 	// CORS middleware
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Expose-Headers", "X-Total-Count, Link")
 		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
 
 		if c.Request.Method == "OPTIONS" {
@@ -273,24 +2064,82 @@ func (h *Handler) SetupRoutes() *gin.Engine {
 		c.Data(http.StatusOK, "text/html", data)
 	})
 
-	api := r.Group("/api")
+	api := r.Group("/api", h.authenticate, h.selectDatabase)
 	{
+		api.GET("/databases", h.ListDatabases)
 		api.GET("/info", h.GetDatabaseInfo)
+		api.GET("/diagnostics", h.GetDiagnostics)
+		api.GET("/stats", h.GetTableStats)
+		api.GET("/wal-status", h.GetWALStatus)
+		api.POST("/maintenance/checkpoint", h.requireWritable, h.Checkpoint)
+		api.GET("/maintenance/integrity-check", h.IntegrityCheck)
+		api.GET("/maintenance/foreign-key-check", h.ForeignKeyCheck)
+		api.POST("/save-as", h.requireWritable, h.SaveAs)
+		api.POST("/schema-diff", h.DiffSchema)
+		api.GET("/settings/:key", h.GetSetting)
+		api.PUT("/settings/:key", h.requireWritable, h.SetSetting)
 		api.GET("/tables", h.GetTables)
+		api.POST("/tables", h.requireWritable, h.CreateTable)
+		api.DELETE("/tables/:table", h.requireWritable, h.DropTable)
+		api.PATCH("/tables/:table", h.requireWritable, h.RenameTable)
+		api.GET("/tables/recent", h.GetRecentTables)
 		api.GET("/tables/:table/schema", h.GetTableSchema)
+		api.POST("/tables/:table/columns", h.requireWritable, h.AddColumn)
+		api.PATCH("/tables/:table/columns/:column", h.requireWritable, h.RenameColumn)
+		api.GET("/tables/:table/foreign-keys", h.GetForeignKeys)
+		api.GET("/tables/:table/fk/:column/:value", h.GetRowByForeignKey)
+		api.GET("/tables/:table/indexes", h.GetIndexes)
+		api.GET("/tables/:table/triggers", h.GetTriggers)
+		api.POST("/tables/:table/indexes", h.requireWritable, h.CreateIndex)
+		api.GET("/tables/:table/fts-candidates", h.GetFTSCandidates)
+		api.POST("/tables/:table/fts", h.requireWritable, h.CreateFTSIndex)
+		api.POST("/tables/:table/fts/:command", h.requireWritable, h.RunFTSCommand)
+		api.GET("/tables/:table/search", h.SearchFTS)
+		api.GET("/tables/:table/chunks", h.GetRowidChunks)
+		api.GET("/tables/:table/columns/:column/distinct", h.GetColumnDistinctValues)
+		api.GET("/tables/:table/columns/:column/stats", h.GetColumnStats)
 		api.GET("/tables/:table/data", h.GetTableData)
+		api.HEAD("/tables/:table/data", h.HeadTableData)
 		api.GET("/tables/:table/export/csv", h.ExportTableCSV)
-		api.POST("/tables/:table/rows", h.InsertRow)
-		api.PUT("/tables/:table/rows", h.UpdateRow)
-		api.DELETE("/tables/:table/rows", h.DeleteRow)
+		api.GET("/tables/:table/export/json", h.ExportTableJSON)
+		api.POST("/tables/:table/import/csv", h.requireWritable, h.ImportTableCSV)
+		api.POST("/tables/:table/rows", h.requireWritable, h.InsertRow)
+		api.PUT("/tables/:table/rows", h.requireWritable, h.UpdateRow)
+		api.DELETE("/tables/:table/rows", h.requireWritable, h.DeleteRow)
+		api.POST("/tables/:table/rows/bulk", h.requireWritable, h.BulkInsert)
+		api.POST("/tables/:table/rows/generate-sql", h.GenerateInsertSQL)
+		api.GET("/tables/:table/rows/:id/cell/:column", h.GetCell)
+		api.GET("/tables/:table/blob/:column", h.GetBlob)
+		api.PUT("/tables/:table/rows/:id/cell/:column", h.requireWritable, h.PutCell)
+		api.POST("/snapshots", h.BeginSnapshot)
+		api.DELETE("/snapshots/:token", h.CloseSnapshot)
 		api.POST("/sql/execute", h.ExecuteSQL)
+		api.POST("/sql/execute-script", h.requireWritable, h.ExecuteSQLScript)
+		api.POST("/sql/export", h.ExportSQLCSV)
+		api.GET("/sql/history", h.GetQueryHistory)
+		api.DELETE("/sql/history", h.ClearQueryHistory)
+		api.GET("/sql/saved", h.GetSavedQueries)
+		api.POST("/sql/saved", h.requireWritable, h.SaveQuery)
+		api.DELETE("/sql/saved/:name", h.requireWritable, h.DeleteSavedQuery)
+		api.GET("/export/sql", h.DumpSQL)
+		api.GET("/backup", h.Backup)
+		api.POST("/attach", h.AttachDatabase)
+		api.POST("/detach", h.DetachDatabase)
+		api.POST("/sql/validate", h.ValidateSQL)
+		api.POST("/sql/explain", h.ExplainSQL)
+		api.POST("/sql/preview", h.requireWritable, h.PreviewSQL)
+		api.GET("/views", h.GetViews)
+		api.POST("/views", h.requireWritable, h.CreateView)
+		api.DELETE("/views/:name", h.requireWritable, h.DropView)
+		api.GET("/triggers", h.GetTriggers)
+		api.DELETE("/triggers/:name", h.requireWritable, h.DropTrigger)
 	}
 
 	// Serve React app for all non-API routes (client-side routing)
 	r.NoRoute(func(c *gin.Context) {
 		// Don't serve index.html for API routes
 		if strings.HasPrefix(c.Request.URL.Path, "/api") {
-			c.AbortWithStatus(http.StatusNotFound)
+			c.AbortWithStatusJSON(http.StatusNotFound, errorResponse(http.StatusNotFound, errors.New("no such API endpoint")))
 			return
 		}
 
@@ -3,14 +3,29 @@ package api
 import (
 	"bytes"
 	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
 	"sqliter/internal/db"
 	"sqliter/internal/models"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -66,7 +81,7 @@ func TestGetTables(t *testing.T) {
 	defer database.Close()
 	defer os.Remove(dbPath)
 
-	handler := NewHandler(database)
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
 	router := handler.SetupRoutes()
 
 	w := httptest.NewRecorder()
@@ -106,7 +121,7 @@ func TestGetTableSchema(t *testing.T) {
 	defer database.Close()
 	defer os.Remove(dbPath)
 
-	handler := NewHandler(database)
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
 	router := handler.SetupRoutes()
 
 	w := httptest.NewRecorder()
@@ -151,7 +166,7 @@ func TestGetTableData(t *testing.T) {
 	defer database.Close()
 	defer os.Remove(dbPath)
 
-	handler := NewHandler(database)
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
 	router := handler.SetupRoutes()
 
 	w := httptest.NewRecorder()
@@ -188,7 +203,7 @@ func TestInsertRow(t *testing.T) {
 	defer database.Close()
 	defer os.Remove(dbPath)
 
-	handler := NewHandler(database)
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
 	router := handler.SetupRoutes()
 
 	insertData := models.InsertRequest{
@@ -222,12 +237,84 @@ func TestInsertRow(t *testing.T) {
 	}
 }
 
+func TestInsertRowPositional(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/tables/users/rows", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"columns": ["email", "name", "age"], "values": ["carol@example.com", "Carol White", 41]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	result, err := database.ExecuteSQL("SELECT name, age FROM users WHERE email = 'carol@example.com'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0][0] != "Carol White" || result.Rows[0][1] != int64(41) {
+		t.Errorf("Expected Carol White aged 41, got %v", result.Rows)
+	}
+
+	if w := post(`{"columns": ["name", "email"], "values": ["Dan"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a length mismatch, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := post(`{"columns": ["name", "nickname"], "values": ["Dan", "D"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown column, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestInsertRowDefaultSentinel(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT, created_at TEXT DEFAULT CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	for _, body := range []string{
+		`{"data": {"name": "signup", "created_at": {"__default__": true}}}`,
+		`{"columns": ["name", "created_at"], "values": ["login", {"__default__": true}]}`,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/tables/events/rows", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+
+	result, err := database.ExecuteSQL("SELECT name FROM events WHERE created_at >= datetime('now', '-1 minute') ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rows) != 2 || result.Rows[0][0] != "signup" || result.Rows[1][0] != "login" {
+		t.Errorf("Expected both rows to get the CURRENT_TIMESTAMP default, got %v", result.Rows)
+	}
+}
+
 func TestUpdateRow(t *testing.T) {
 	database, dbPath := setupTestDB(t)
 	defer database.Close()
 	defer os.Remove(dbPath)
 
-	handler := NewHandler(database)
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
 	router := handler.SetupRoutes()
 
 	updateData := models.UpdateRequest{
@@ -268,12 +355,113 @@ func TestUpdateRow(t *testing.T) {
 	}
 }
 
+func TestUpdateRowDiff(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	// Record every write to users so skipped updates can be detected
+	setup := []string{
+		`CREATE TABLE user_writes (user_id INTEGER)`,
+		`CREATE TRIGGER users_written AFTER UPDATE ON users BEGIN INSERT INTO user_writes VALUES (NEW.id); END`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	update := func(body string) models.UpdateResult {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/api/tables/users/rows", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var result models.UpdateResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result
+	}
+
+	result := update(`{"data": {"name": "John Doe", "age": 30}, "where": {"id": 1}}`)
+	if !result.Noop || result.RowsAffected != 0 || len(result.Changes) != 0 {
+		t.Errorf("Expected a no-op for identical values, got %+v", result)
+	}
+	writes, err := database.CountRows("user_writes", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writes != 0 {
+		t.Errorf("Expected no write for a no-op update, got %d", writes)
+	}
+
+	result = update(`{"data": {"name": "John Doe", "age": 31}, "where": {"id": 1}}`)
+	if result.Noop || result.RowsAffected != 1 {
+		t.Errorf("Expected one affected row, got %+v", result)
+	}
+	expected := map[string]models.FieldChange{"age": {Before: float64(30), After: float64(31)}}
+	if !reflect.DeepEqual(result.Changes, expected) {
+		t.Errorf("Expected only age to change from 30 to 31, got %+v", result.Changes)
+	}
+
+	// Columns are matched case-insensitively, so a NULL isn't mistaken for a
+	// missing value
+	result = update(`{"data": {"AGE": null}, "where": {"id": 1}}`)
+	if result.Noop || result.RowsAffected != 1 {
+		t.Errorf("Expected clearing AGE to update the row, got %+v", result)
+	}
+
+	// BLOBs sent back as rendered compare by their bytes
+	if _, err := database.ExecuteSQL(`ALTER TABLE users ADD COLUMN avatar BLOB`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.ExecuteSQL(`UPDATE users SET avatar = x'89504e47' WHERE id = 1`); err != nil {
+		t.Fatal(err)
+	}
+	result = update(`{"data": {"avatar": {"__blob__": "iVBORw=="}}, "where": {"id": 1}}`)
+	if !result.Noop {
+		t.Errorf("Expected a no-op for an unchanged BLOB, got %+v", result)
+	}
+	result = update(`{"data": {"avatar": {"__blob__": "AAE="}}, "where": {"id": 1}}`)
+	expected = map[string]models.FieldChange{"avatar": {
+		Before: map[string]interface{}{"__blob__": "iVBORw=="},
+		After:  map[string]interface{}{"__blob__": "AAE="},
+	}}
+	if result.Noop || !reflect.DeepEqual(result.Changes, expected) {
+		t.Errorf("Expected the BLOB to change, got %+v", result)
+	}
+	count, err := database.CountRows("users", "id = 1 AND avatar = x'0001'")
+	if err != nil || count != 1 {
+		t.Errorf("Expected the BLOB to be written as bytes, got %d rows (%v)", count, err)
+	}
+
+	// WITHOUT ROWID tables have no rowid to set
+	if _, err := database.ExecuteSQL(`CREATE TABLE tags (name TEXT PRIMARY KEY) WITHOUT ROWID`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.ExecuteSQL(`INSERT INTO tags VALUES ('go')`); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/tables/tags/rows", strings.NewReader(`{"data": {"rowid": null}, "where": {"name": "go"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown column, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+}
+
 func TestDeleteRow(t *testing.T) {
 	database, dbPath := setupTestDB(t)
 	defer database.Close()
 	defer os.Remove(dbPath)
 
-	handler := NewHandler(database)
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
 	router := handler.SetupRoutes()
 
 	deleteData := models.DeleteRequest{
@@ -310,4 +498,5288 @@ func TestDeleteRow(t *testing.T) {
 			t.Error("Row with id=2 should have been deleted")
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestRequirePrimaryKey(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE memberships (org_id INTEGER, user_id INTEGER, role TEXT, PRIMARY KEY (org_id, user_id))`,
+		`INSERT INTO memberships VALUES (1, 1, 'owner'), (1, 2, 'member'), (2, 1, 'member')`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := database.PrimaryKeyColumns("memberships")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"org_id", "user_id"}) {
+		t.Errorf("Expected the composite key in order, got %v", keys)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	send := func(method, query, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/api/tables/memberships/rows"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A partial key is rejected before anything is written
+	w := send("PUT", "?require_pk=true", `{"data": {"role": "admin"}, "where": {"org_id": 1}}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "user_id") {
+		t.Errorf("Expected 400 naming user_id, got %d: %s", w.Code, w.Body.String())
+	}
+	w = send("DELETE", "?require_pk=true", `{"where": {"org_id": 1}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a partial key, got %d: %s", w.Code, w.Body.String())
+	}
+	if count, _ := database.CountRows("memberships", ""); count != 3 {
+		t.Errorf("Expected no rows deleted, got %d left", count)
+	}
+
+	w = send("PUT", "?require_pk=true", `{"data": {"role": "admin"}, "where": {"ORG_ID": 1, "user_id": 2}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = send("DELETE", "?require_pk=true", `{"where": {"org_id": 1, "user_id": 2}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var deleted struct {
+		RowsAffected int64 `json:"rows_affected"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &deleted)
+	if deleted.RowsAffected != 1 {
+		t.Errorf("Expected 1 row deleted, got %d", deleted.RowsAffected)
+	}
+
+	// Without require_pk a partial key still matches every row it covers
+	w = send("DELETE", "", `{"where": {"user_id": 1}}`)
+	json.Unmarshal(w.Body.Bytes(), &deleted)
+	if w.Code != http.StatusOK || deleted.RowsAffected != 2 {
+		t.Errorf("Expected 2 rows deleted, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Views have no key to require, which is the client's mistake
+	if _, err := database.ExecuteSQL(`CREATE VIEW owners AS SELECT * FROM memberships WHERE role = 'owner'`); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/tables/owners/rows?require_pk=true", strings.NewReader(`{"where": {"org_id": 1}}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	var failure struct {
+		Error errorBody `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &failure)
+	if w.Code != http.StatusBadRequest || failure.Error.Code != "NO_PRIMARY_KEY" {
+		t.Errorf("Expected 400 NO_PRIMARY_KEY for a view, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRowsAffectedWhenNothingMatches(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	for _, tc := range []struct{ method, body string }{
+		{"PUT", `{"data": {"age": 40}, "where": {"id": 99}}`},
+		{"DELETE", `{"where": {"id": 99}}`},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(tc.method, "/api/tables/users/rows", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.method, http.StatusOK, w.Code, w.Body.String())
+		}
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if affected, ok := response["rows_affected"]; !ok || affected != float64(0) {
+			t.Errorf("%s: expected rows_affected 0, got %s", tc.method, w.Body.String())
+		}
+	}
+}
+
+func TestNullAndEmptyStringAreDistinct(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT, rating INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	send := func(method, body string) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/api/tables/notes/rows", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated && w.Code != http.StatusOK {
+			t.Fatalf("%s %s: got %d: %s", method, body, w.Code, w.Body.String())
+		}
+	}
+	send("POST", `{"data": {"id": 1, "body": null, "rating": null}}`)
+	send("POST", `{"data": {"id": 2, "body": "", "rating": 5}}`)
+	send("PUT", `{"data": {"rating": null}, "where": {"id": 2}}`)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/notes/data", nil)
+	router.ServeHTTP(w, req)
+
+	// Decode the raw JSON so a null can't be confused with a missing key
+	var response struct {
+		Rows []map[string]json.RawMessage `json:"rows"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %s", w.Body.String())
+	}
+	expected := []map[string]string{
+		{"body": `null`, "rating": `null`},
+		{"body": `""`, "rating": `null`},
+	}
+	for i, row := range response.Rows {
+		for col, want := range expected[i] {
+			if got := string(row[col]); got != want {
+				t.Errorf("Row %d: expected %s to be %s, got %s", i+1, col, want, got)
+			}
+		}
+	}
+
+	// The stored values are real NULLs and empty strings, not text
+	result, err := database.ExecuteSQL(`SELECT COUNT(*) FROM notes WHERE body IS NULL AND rating IS NULL`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Rows[0][0] != int64(1) {
+		t.Errorf("Expected one row with NULL body and rating, got %v", result.Rows[0][0])
+	}
+	result, err = database.ExecuteSQL(`SELECT typeof(body), typeof(rating) FROM notes WHERE id = 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Rows[0][0] != "text" || result.Rows[0][1] != "null" {
+		t.Errorf("Expected a text body and NULL rating, got %v", result.Rows[0])
+	}
+}
+
+func TestBlobValues(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE attachments (owner INTEGER, name TEXT, data BLOB, note TEXT, PRIMARY KEY (owner, name))`,
+		`INSERT INTO attachments VALUES (1, 'logo.png', X'89504E47FF00', 'plain text')`,
+		`INSERT INTO attachments VALUES (1, 'empty', NULL, NULL)`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	// Binary data comes back base64-encoded and tagged; text stays a string
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/attachments/data", nil)
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Rows []map[string]interface{} `json:"rows"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %s", w.Body.String())
+	}
+	logo, empty := response.Rows[0], response.Rows[1]
+	if logo["name"] != "logo.png" {
+		logo, empty = empty, logo
+	}
+	expected := map[string]interface{}{"__blob__": base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G', 0xff, 0x00})}
+	if !reflect.DeepEqual(logo["data"], expected) {
+		t.Errorf("Expected %v, got %v", expected, logo["data"])
+	}
+	if logo["note"] != "plain text" {
+		t.Errorf("Expected the text column as a string, got %v", logo["note"])
+	}
+	if empty["data"] != nil {
+		t.Errorf("Expected a NULL BLOB to stay null, got %v", empty["data"])
+	}
+
+	// Query results tag BLOBs the same way
+	body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: "SELECT data, note FROM attachments WHERE name = 'logo.png'"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/sql/execute", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	var result models.SQLQueryResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if len(result.Rows) != 1 || !reflect.DeepEqual(result.Rows[0][0], expected) || result.Rows[0][1] != "plain text" {
+		t.Errorf("Expected a tagged BLOB and a string in the query result, got %s", w.Body.String())
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/attachments/blob/data?"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w = get("owner=1&name=logo.png")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !bytes.Equal(w.Body.Bytes(), []byte{0x89, 'P', 'N', 'G', 0xff, 0x00}) {
+		t.Errorf("Expected the raw bytes, got %x", w.Body.Bytes())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/octet-stream" {
+		t.Errorf("Expected application/octet-stream, got %s", ct)
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "6" {
+		t.Errorf("Expected Content-Length 6, got %q", cl)
+	}
+
+	if w = get("owner=1&name=empty"); w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for a NULL cell, got %d", w.Code)
+	}
+	if w = get("owner=1"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a partial primary key, got %d: %s", w.Code, w.Body.String())
+	}
+	if w = get("owner=2&name=logo.png"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing row, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGetTableStats(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`CREATE TABLE empty (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+
+	scopes := map[string][]models.Scope{"users": {{Column: "name", Value: "John Doe"}}}
+	handler := NewHandler(database, fstest.MapFS{}, Config{Scopes: scopes})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/stats", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Tables       []models.TableStat `json:"tables"`
+		DatabaseSize int64              `json:"database_size"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	rows := map[string]int{}
+	for _, stat := range response.Tables {
+		rows[stat.Name] = stat.Rows
+		// Scoped tables never report a size
+		if stat.Name == "users" && stat.SizeBytes != nil {
+			t.Errorf("Expected no size for a scoped table, got %d", *stat.SizeBytes)
+		}
+	}
+	if !reflect.DeepEqual(rows, map[string]int{"empty": 0, "users": 1}) {
+		t.Errorf("Expected row counts within scopes, got %v", rows)
+	}
+	if response.DatabaseSize <= 0 {
+		t.Errorf("Expected the database size, got %d", response.DatabaseSize)
+	}
+}
+
+func TestMaintenanceChecks(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	get := func(path string, response interface{}) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", path, http.StatusOK, w.Code, w.Body.String())
+		}
+		json.Unmarshal(w.Body.Bytes(), response)
+	}
+
+	var integrity struct {
+		OK       bool     `json:"ok"`
+		Messages []string `json:"messages"`
+	}
+	get("/api/maintenance/integrity-check", &integrity)
+	if !integrity.OK || !reflect.DeepEqual(integrity.Messages, []string{"ok"}) {
+		t.Errorf("Expected an intact database, got %+v", integrity)
+	}
+
+	var fkCheck struct {
+		OK         bool                         `json:"ok"`
+		Violations []models.ForeignKeyViolation `json:"violations"`
+	}
+	get("/api/maintenance/foreign-key-check", &fkCheck)
+	if !fkCheck.OK || len(fkCheck.Violations) != 0 {
+		t.Errorf("Expected no violations, got %+v", fkCheck)
+	}
+
+	// Foreign keys aren't enforced on this connection, so orphans can be added
+	setup := []string{
+		`CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id))`,
+		`INSERT INTO posts VALUES (1, 1), (2, 99)`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get("/api/maintenance/foreign-key-check", &fkCheck)
+	if fkCheck.OK || len(fkCheck.Violations) != 1 {
+		t.Fatalf("Expected one violation, got %+v", fkCheck)
+	}
+	violation := fkCheck.Violations[0]
+	if violation.Table != "posts" || violation.Parent != "users" || violation.RowID == nil || *violation.RowID != 2 {
+		t.Errorf("Expected posts row 2 to reference a missing user, got %+v", violation)
+	}
+}
+
+func TestBackup(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/backup", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-sqlite3" {
+		t.Errorf("Expected application/x-sqlite3, got %s", ct)
+	}
+	base := strings.TrimSuffix(filepath.Base(dbPath), ".db")
+	if disposition := w.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, "attachment; filename="+base+"-backup-") || !strings.HasSuffix(disposition, ".db") {
+		t.Errorf("Expected a backup filename based on %s, got %s", base, disposition)
+	}
+
+	// The download is a working database with the same rows
+	backupPath := filepath.Join(t.TempDir(), "backup.db")
+	if err := os.WriteFile(backupPath, w.Body.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := db.NewReadOnlySQLiteDB(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	count, err := backup.CountRows("users", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 users in the backup, got %d", count)
+	}
+
+	// A copy of the file would bypass the scopes
+	router = NewHandler(database, fstest.MapFS{}, Config{
+		Scopes: map[string][]models.Scope{"users": {{Column: "id", Value: "1"}}},
+	}).SetupRoutes()
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/backup", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden || w.Header().Get("Content-Disposition") != "" {
+		t.Errorf("Expected status %d without a download, got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+}
+
+func TestAttachDatabase(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	otherPath := filepath.Join(t.TempDir(), "archive.db")
+	other, err := db.NewSQLiteDB(otherPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup := []string{
+		`CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER, total REAL)`,
+		`INSERT INTO orders VALUES (1, 1, 9.5), (2, 1, 20), (3, 2, 5)`,
+	}
+	for _, stmt := range setup {
+		if _, err := other.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other.Close()
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	attachBody, _ := json.Marshal(models.AttachRequest{Alias: "archive", Path: otherPath})
+
+	w := post("/api/attach", string(attachBody))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w = post("/api/attach", string(attachBody)); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a taken alias, got %d: %s", w.Code, w.Body.String())
+	}
+	if w = post("/api/attach", `{"alias": "x; DROP TABLE users", "path": "x.db"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unsafe alias, got %d: %s", w.Code, w.Body.String())
+	}
+	if w = post("/api/attach", `{"alias": "missing", "path": "/nonexistent/x.db"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing file, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Scripts, exports and query plans see the attachment
+	for _, r := range []struct{ path, sql string }{
+		{"/api/sql/execute-script", "UPDATE archive.orders SET total = total WHERE id = 1; SELECT total FROM archive.orders WHERE id = 1;"},
+		{"/api/sql/export", "SELECT * FROM archive.orders"},
+		{"/api/sql/explain", "SELECT * FROM archive.orders WHERE user_id = 1"},
+	} {
+		body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: r.sql})
+		if w = post(r.path, string(body)); w.Code != http.StatusOK {
+			t.Errorf("Expected status %d for %s, got %d: %s", http.StatusOK, r.path, w.Code, w.Body.String())
+		}
+	}
+
+	// Every pooled connection sees the attachment, so run the join a few times
+	for i := 0; i < 5; i++ {
+		result, err := database.ExecuteSQL(`SELECT u.name, SUM(o.total) FROM users u JOIN archive.orders o ON o.user_id = u.id GROUP BY u.id ORDER BY u.id`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.RowCount != 2 || result.Rows[0][0] != "John Doe" || result.Rows[0][1] != 29.5 {
+			t.Fatalf("Expected totals per user, got %v", result.Rows)
+		}
+	}
+	if _, err := database.ExecuteSQL(`UPDATE archive.orders SET total = 0 WHERE id = 3`); err != nil {
+		t.Errorf("Expected writes to the attached database to work, got %v", err)
+	}
+
+	if w = post("/api/detach", `{"alias": "archive"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if _, err := database.ExecuteSQL(`SELECT * FROM archive.orders`); err == nil {
+		t.Error("Expected the detached database to be gone")
+	}
+	if w = post("/api/detach", `{"alias": "archive"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown alias, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGetColumnDistinctValues(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`INSERT INTO users (name, email, age) VALUES ('Jim', 'jim@example.com', 30), ('Kim', 'kim@example.com', NULL)`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{
+		Scopes: map[string][]models.Scope{"scoped": {{Column: "tenant", Value: "1"}}},
+	})
+	router := handler.SetupRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	values := func(path string) []interface{} {
+		w := get(path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response struct {
+			Values []interface{} `json:"values"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response.Values
+	}
+
+	if got, want := values("/api/tables/users/columns/age/distinct"), []interface{}{nil, float64(25), float64(30)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected distinct ages %v, got %v", want, got)
+	}
+	if got := values("/api/tables/users/columns/name/distinct?limit=2"); !reflect.DeepEqual(got, []interface{}{"Jane Smith", "Jim"}) {
+		t.Errorf("Expected the first 2 names, got %v", got)
+	}
+
+	// Scopes restrict the rows the values come from
+	if _, err := database.ExecuteSQL(`CREATE TABLE scoped (tenant TEXT, status TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.ExecuteSQL(`INSERT INTO scoped VALUES ('1', 'open'), ('1', 'open'), ('2', 'closed')`); err != nil {
+		t.Fatal(err)
+	}
+	if got := values("/api/tables/scoped/columns/status/distinct"); !reflect.DeepEqual(got, []interface{}{"open"}) {
+		t.Errorf("Expected only the scoped status, got %v", got)
+	}
+
+	for path, status := range map[string]int{
+		"/api/tables/users/columns/nope/distinct":          http.StatusNotFound,
+		"/api/tables/missing/columns/age/distinct":         http.StatusNotFound,
+		"/api/tables/users/columns/age/distinct?limit=0":   http.StatusBadRequest,
+		"/api/tables/users/columns/age/distinct?limit=abc": http.StatusBadRequest,
+	} {
+		if w := get(path); w.Code != status {
+			t.Errorf("Expected status %d for %s, got %d: %s", status, path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestGetColumnStats(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`INSERT INTO users (name, email, age) VALUES ('Jim', 'jim@example.com', 30), ('Kim', 'kim@example.com', NULL)`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	stats := func(column string) models.ColumnStats {
+		w := get("/api/tables/users/columns/" + column + "/stats")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response models.ColumnStats
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	age := stats("age")
+	if !age.Numeric || age.Count != 4 || age.NullCount != 1 || age.DistinctCount != 2 {
+		t.Errorf("Unexpected age counts: %+v", age)
+	}
+	if age.Min != float64(25) || age.Max != float64(30) || age.Sum != float64(85) || age.Avg == nil || *age.Avg < 28.33 || *age.Avg > 28.34 {
+		t.Errorf("Unexpected age aggregates: %+v", age)
+	}
+
+	// Text columns only get counts
+	name := stats("name")
+	if name.Numeric || name.Count != 4 || name.NullCount != 0 || name.DistinctCount != 4 || name.Min != nil || name.Avg != nil {
+		t.Errorf("Unexpected name stats: %+v", name)
+	}
+
+	// Columns are matched like the distinct values endpoint, rowid included
+	if rowid := stats("rowid"); !rowid.Numeric || rowid.Count != 4 || rowid.Max != float64(4) {
+		t.Errorf("Unexpected rowid stats: %+v", rowid)
+	}
+
+	// A sum that overflows 64-bit integers is totalled as a float
+	if _, err := database.ExecuteSQL(`UPDATE users SET age = 9223372036854775807 WHERE age IS NOT NULL`); err != nil {
+		t.Fatal(err)
+	}
+	if age := stats("age"); age.Sum != 3*9223372036854775807.0 || age.Max != "9223372036854775807" {
+		t.Errorf("Unexpected overflowing age aggregates: %+v", age)
+	}
+
+	for _, path := range []string{"/api/tables/users/columns/nope/stats", "/api/tables/users/columns/age%20FROM%20users--/stats", "/api/tables/missing/columns/age/stats"} {
+		if w := get(path); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d for %s, got %d: %s", http.StatusNotFound, path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestTableIdentifiersAreValidated(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	// A crafted table name is reported as missing rather than executed
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/"+url.PathEscape("users; DROP TABLE users")+"/data", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a crafted table name, got %d", http.StatusNotFound, w.Code)
+	}
+
+	// An unknown WHERE column is rejected instead of matching as a string literal
+	jsonData, _ := json.Marshal(models.DeleteRequest{Where: map[string]interface{}{"1=1 OR id": 1}})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/tables/users/rows", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown column, got %d", http.StatusNotFound, w.Code)
+	}
+
+	jsonData, _ = json.Marshal(models.UpdateRequest{
+		Data:  map[string]interface{}{"name": "Mallory"},
+		Where: map[string]interface{}{"id = id OR id": 1},
+	})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/tables/users/rows", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown column, got %d", http.StatusNotFound, w.Code)
+	}
+
+	count, err := database.CountRows("users", "")
+	if err != nil {
+		t.Fatalf("CountRows failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected users to keep its 2 rows, got %d", count)
+	}
+}
+
+func TestKeyCase(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE events (id INTEGER PRIMARY KEY, created_at TEXT, "userID" INTEGER)`,
+		`INSERT INTO events (created_at, "userID") VALUES ('2024-01-01', 7)`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/events/data?key_case=camel&sort_column=created_at&sort_direction=asc", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var data models.TableData
+	json.Unmarshal(w.Body.Bytes(), &data)
+	if len(data.Rows) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(data.Rows))
+	}
+	row := data.Rows[0]
+	if row["createdAt"] != "2024-01-01" || row["userId"] != float64(7) {
+		t.Errorf("Expected camelCase keys createdAt and userId, got %v", row)
+	}
+	if _, ok := row["created_at"]; ok {
+		t.Errorf("Expected created_at to be renamed, got %v", row)
+	}
+	if data.Columns[1].Name != "created_at" {
+		t.Errorf("Expected the schema to keep the real column name, got %s", data.Columns[1].Name)
+	}
+
+	body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: `SELECT created_at, "userID" FROM events`})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/sql/execute?key_case=snake", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	var result models.SQLQueryResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if !reflect.DeepEqual(result.Columns, []string{"created_at", "user_id"}) {
+		t.Errorf("Expected snake_case columns, got %v", result.Columns)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/events/data?key_case=kebab", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown key case, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGenerateInsertSQL(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	// Give the rows values that need escaping: quotes, NULL and a BLOB
+	if _, err := database.ExecuteSQLScript(`
+		ALTER TABLE users ADD COLUMN avatar BLOB;
+		UPDATE users SET name = 'John ''Johnny'' O''Brien', avatar = X'00FF27' WHERE id = 1;
+		UPDATE users SET age = NULL WHERE id = 2;
+		INSERT INTO users (name, email, age) VALUES ('Left Out', 'left@example.com', 40);`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	body, _ := json.Marshal(models.GenerateSQLRequest{IDs: []interface{}{2, 1}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/tables/users/rows/generate-sql", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var generated struct {
+		SQL   string `json:"sql"`
+		Count int    `json:"count"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &generated)
+	if generated.Count != 2 || strings.Count(generated.SQL, "INSERT INTO") != 2 {
+		t.Fatalf("Expected 2 INSERT statements, got %d: %s", generated.Count, generated.SQL)
+	}
+
+	// Replay the statements into a fresh database with the same table
+	createSQL, err := database.ExecuteSQL(`SELECT sql FROM sqlite_master WHERE name = 'users'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	freshPath := filepath.Join(t.TempDir(), "fresh.db")
+	fresh, err := db.NewSQLiteDB(freshPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fresh.Close()
+	if _, err := fresh.ExecuteSQLScript(createSQL.Rows[0][0].(string) + ";\n" + generated.SQL); err != nil {
+		t.Fatalf("Failed to replay the generated SQL: %v\n%s", err, generated.SQL)
+	}
+
+	const query = `SELECT id, name, email, age, hex(avatar), typeof(age) FROM users WHERE id IN (1, 2) ORDER BY id`
+	want, err := database.ExecuteSQL(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := fresh.ExecuteSQL(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Rows, want.Rows) {
+		t.Errorf("Replayed rows differ:\n got %v\nwant %v", got.Rows, want.Rows)
+	}
+	if total, _ := fresh.CountRows("users", ""); total != 2 {
+		t.Errorf("Expected only the 2 selected rows to be generated, got %d", total)
+	}
+}
+
+func TestHeadTableData(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("HEAD", "/api/tables/users/data", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := w.Header().Get("X-Total-Count"); got != "2" {
+		t.Errorf("Expected X-Total-Count to be 2, got %q", got)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected empty body, got %q", w.Body.String())
+	}
+
+	// Filters are applied to the count
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("HEAD", "/api/tables/users/data?allow_raw=true&where_clause=age+%3E+28", nil)
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("X-Total-Count"); got != "1" {
+		t.Errorf("Expected filtered X-Total-Count to be 1, got %q", got)
+	}
+}
+
+func TestGetTableDataFilters(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	request := func(method, filters string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/api/tables/users/data?filters="+url.QueryEscape(filters), nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for filters, want := range map[string]int{
+		`[{"column":"age","op":">=","value":30}]`:                                        1,
+		`[{"column":"name","op":"LIKE","value":"J%"}]`:                                   2,
+		`[{"column":"age","op":"IN","value":[25,30,40]}]`:                                2,
+		`[{"column":"email","op":"IS NULL"}]`:                                            0,
+		`[{"column":"age","op":"<","value":30},{"column":"name","op":"!=","value":"x"}]`: 1,
+		// A value that looks like SQL is only ever compared as a string
+		`[{"column":"name","op":"=","value":"x' OR '1'='1"}]`: 0,
+	} {
+		w := request("GET", filters)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d: %s", filters, http.StatusOK, w.Code, w.Body.String())
+			continue
+		}
+		var data models.TableData
+		json.Unmarshal(w.Body.Bytes(), &data)
+		if data.Total != want || len(data.Rows) != want {
+			t.Errorf("%s: expected %d rows, got total %d and %d rows", filters, want, data.Total, len(data.Rows))
+		}
+
+		// The count-only request applies the same filters
+		if got := request("HEAD", filters).Header().Get("X-Total-Count"); got != fmt.Sprint(want) {
+			t.Errorf("%s: expected X-Total-Count %d, got %q", filters, want, got)
+		}
+	}
+
+	for filters, want := range map[string]int{
+		`[{"column":"age","op":"; DROP TABLE users","value":1}]`: http.StatusBadRequest,
+		`[{"column":"age","op":"IN","value":30}]`:                http.StatusBadRequest,
+		`[{"column":"age","op":">"}]`:                            http.StatusBadRequest,
+		`[{"column":"missing","op":"=","value":1}]`:              http.StatusNotFound,
+		`not json`: http.StatusBadRequest,
+	} {
+		if w := request("GET", filters); w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", filters, want, w.Code)
+		}
+	}
+
+	// The raw where clause needs an explicit opt-in
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/users/data?where_clause=age+%3E+28", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for where_clause without allow_raw, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestCreateTable(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/tables", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := create(`{"name": "order items", "columns": [
+		{"name": "id", "type": "int", "primary_key": true},
+		{"name": "sku", "type": "VARCHAR(32)", "not_null": true, "unique": true},
+		{"name": "qty", "type": "int", "default_value": "1"}
+	]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var created struct {
+		Name    string          `json:"name"`
+		Columns []models.Column `json:"columns"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Name != "order items" || len(created.Columns) != 3 {
+		t.Fatalf("Expected the created schema, got %+v", created)
+	}
+	sku := created.Columns[1]
+	if sku.Type != "VARCHAR(32)" || !sku.NotNull || !sku.Unique || !created.Columns[0].PrimaryKey {
+		t.Errorf("Unexpected column definitions: %+v", created.Columns)
+	}
+	if qty := created.Columns[2]; qty.DefaultValue == nil || *qty.DefaultValue != "1" {
+		t.Errorf("Expected qty to default to 1, got %+v", qty.DefaultValue)
+	}
+
+	for _, body := range []string{
+		`{"name": "dupes", "columns": [{"name": "a", "type": "int"}, {"name": "A", "type": "string"}]}`,
+		`{"name": "order items", "columns": [{"name": "id", "type": "int"}]}`,
+		`{"name": "empty", "columns": []}`,
+		`{"name": "", "columns": [{"name": "id"}]}`,
+	} {
+		if w := create(body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, body, w.Code)
+		}
+	}
+	if exists, _ := database.GetTableSchema("dupes"); exists != nil {
+		t.Error("Expected no table to be created for duplicate column names")
+	}
+}
+
+func TestAddColumn(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	add := func(table, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/tables/"+table+"/columns", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := add("users", `{"name": "status", "type": "string", "not_null": true, "default_value": "active"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var refreshed struct {
+		Columns []models.Column `json:"columns"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &refreshed)
+	if n := len(refreshed.Columns); n != 5 || refreshed.Columns[n-1].Name != "status" || !refreshed.Columns[n-1].NotNull {
+		t.Errorf("Expected the refreshed schema to end with status, got %+v", refreshed.Columns)
+	}
+
+	w = add("users", `{"name": "nickname", "type": "string", "not_null": true}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "default value") {
+		t.Errorf("Expected a 400 explaining the missing default, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := add("users", `{"name": "email", "type": "string"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a duplicate column, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := add("missing", `{"name": "x", "type": "int"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing table, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestRenameTableAndColumn(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`CREATE TABLE accounts (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	patch := func(path, newName string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.RenameRequest{NewName: newName})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PATCH", path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, tt := range []struct {
+		path, newName string
+		want          int
+	}{
+		{"/api/tables/users", "Accounts", http.StatusConflict},
+		{"/api/tables/missing", "other", http.StatusNotFound},
+		{"/api/tables/users", "", http.StatusBadRequest},
+		{"/api/tables/users/columns/name", "EMAIL", http.StatusConflict},
+		{"/api/tables/users/columns/missing", "other", http.StatusNotFound},
+	} {
+		if w := patch(tt.path, tt.newName); w.Code != tt.want {
+			t.Errorf("PATCH %s to %q: expected status %d, got %d: %s", tt.path, tt.newName, tt.want, w.Code, w.Body.String())
+		}
+	}
+
+	if w := patch("/api/tables/users", "members \"all\""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if _, err := database.GetTableSchema(`members "all"`); err != nil {
+		t.Fatalf("Expected the renamed table to exist, got %v", err)
+	}
+
+	w := patch("/api/tables/"+url.PathEscape(`members "all"`)+"/columns/name", "full name")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var refreshed struct {
+		Columns []models.Column `json:"columns"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &refreshed)
+	if len(refreshed.Columns) != 4 || refreshed.Columns[1].Name != "full name" {
+		t.Errorf("Expected the column to be renamed to 'full name', got %+v", refreshed.Columns)
+	}
+}
+
+func TestDropTable(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	drop := func(path string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", path, nil)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for path, want := range map[string]int{
+		"/api/tables/users":                                 http.StatusBadRequest,
+		"/api/tables/users?confirm=Users":                   http.StatusBadRequest,
+		"/api/tables/missing?confirm=missing":               http.StatusNotFound,
+		"/api/tables/_sqliter_usage?confirm=_sqliter_usage": http.StatusNotFound,
+	} {
+		if got := drop(path); got != want {
+			t.Errorf("DELETE %s: expected status %d, got %d", path, want, got)
+		}
+	}
+	if _, err := database.GetTableSchema("users"); err != nil {
+		t.Fatalf("Expected users to survive unconfirmed drops, got %v", err)
+	}
+
+	if got := drop("/api/tables/users?confirm=users"); got != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, got)
+	}
+	if _, err := database.GetTableSchema("users"); err == nil {
+		t.Error("Expected users to be dropped")
+	}
+}
+
+func TestCreateAndDropView(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	body, _ := json.Marshal(models.CreateViewRequest{Name: "older_users", Select: "SELECT * FROM users WHERE age > 28"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/views", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/views/older_users", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Dropping it again reports that it no longer exists
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/views/older_users", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestIndexEndpoints(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	create := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/tables/users/indexes", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := create(`{"name": "users_age", "columns": ["age"], "where": "age IS NOT NULL"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if w := create(`{"name": "users_age", "columns": ["name"]}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for a taken name, got %d", http.StatusConflict, w.Code)
+	}
+	if w := create(`{"columns": ["nope"]}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown column, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := create(`{"columns": ["name"], "where": "bogus("}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid predicate, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/users/indexes", nil)
+	router.ServeHTTP(w, req)
+	var listed struct {
+		Indexes []models.Index `json:"indexes"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &listed)
+	found := false
+	for _, index := range listed.Indexes {
+		if index.Name == "users_age" {
+			found = index.Partial && index.Where == "age IS NOT NULL" && reflect.DeepEqual(index.Columns, []string{"age"})
+		}
+	}
+	if !found {
+		t.Errorf("Expected the partial users_age index to be listed, got %s", w.Body.String())
+	}
+}
+
+func TestGetTriggers(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	const auditTrigger = "CREATE TRIGGER users_audit AFTER UPDATE ON users BEGIN SELECT 1; END"
+	if _, err := database.ExecuteSQLScript(`
+		CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT);
+		` + auditTrigger + `;
+		CREATE TRIGGER notes_guard BEFORE DELETE ON notes BEGIN SELECT RAISE(ABORT, 'no'); END;
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	get := func(path string) (int, []models.Trigger) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		var response struct {
+			Triggers []models.Trigger `json:"triggers"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Triggers
+	}
+
+	_, triggers := get("/api/tables/users/triggers")
+	want := []models.Trigger{{Name: "users_audit", Table: "users", SQL: auditTrigger}}
+	if !reflect.DeepEqual(triggers, want) {
+		t.Errorf("Expected %+v, got %+v", want, triggers)
+	}
+
+	if _, triggers := get("/api/triggers"); len(triggers) != 2 || triggers[0].Name != "notes_guard" || triggers[1].Name != "users_audit" {
+		t.Errorf("Expected both triggers ordered by name, got %+v", triggers)
+	}
+	if code, _ := get("/api/tables/missing/triggers"); code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown table, got %d", http.StatusNotFound, code)
+	}
+}
+
+func TestViews(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	const definition = "CREATE VIEW adults AS SELECT name, age FROM users WHERE age > 26"
+	if _, err := database.ExecuteSQL(definition); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{DefaultSort: true})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/views", nil)
+	router.ServeHTTP(w, req)
+	var listed struct {
+		Views []models.View `json:"views"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &listed)
+	if len(listed.Views) != 1 || listed.Views[0].Name != "adults" || listed.Views[0].SQL != definition {
+		t.Errorf("Expected the adults view with its definition, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/adults/data", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var data models.TableData
+	json.Unmarshal(w.Body.Bytes(), &data)
+	if data.Total != 1 || data.Rows[0]["name"] != "John Doe" || data.ViewSQL != definition {
+		t.Errorf("Expected the view's rows and definition, got %s", w.Body.String())
+	}
+
+	// Tables don't carry a definition
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/users/data", nil)
+	router.ServeHTTP(w, req)
+	data = models.TableData{}
+	json.Unmarshal(w.Body.Bytes(), &data)
+	if data.ViewSQL != "" {
+		t.Errorf("Expected no view definition for a table, got %q", data.ViewSQL)
+	}
+}
+
+func TestCreateViewRejectsMultipleStatements(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	body, _ := json.Marshal(models.CreateViewRequest{Name: "v", Select: "SELECT 1; DROP TABLE users"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/views", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	// A semicolon inside a string literal doesn't end the statement
+	body, _ = json.Marshal(models.CreateViewRequest{Name: "v", Select: "SELECT 'a;b' AS pair;"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/views", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+}
+
+func TestDropTrigger(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`CREATE TRIGGER users_touch AFTER UPDATE ON users BEGIN SELECT 1; END`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/triggers/users_touch", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/triggers/users_touch", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestExecuteSQLNumbersAsStrings(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: "SELECT 9007199254740993 AS big, 1.5 AS ratio, 'x' AS label"})
+
+	var response struct {
+		Rows [][]interface{} `json:"rows"`
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/sql/execute?numbers_as_strings=true", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Rows[0][0] != "9007199254740993" {
+		t.Errorf("Expected big integer as string, got %#v", response.Rows[0][0])
+	}
+	if response.Rows[0][1] != "1.5" {
+		t.Errorf("Expected float as string, got %#v", response.Rows[0][1])
+	}
+	if response.Rows[0][2] != "x" {
+		t.Errorf("Expected text to be unchanged, got %#v", response.Rows[0][2])
+	}
+
+	// Without the option numbers stay native, except integers a double
+	// can't hold exactly
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/sql/execute", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Rows[0][0] != "9007199254740993" {
+		t.Errorf("Expected a big integer as string by default, got %#v", response.Rows[0][0])
+	}
+	if _, ok := response.Rows[0][1].(float64); !ok {
+		t.Errorf("Expected a JSON number by default, got %#v", response.Rows[0][1])
+	}
+}
+
+func TestBigIntegersKeepPrecision(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE ledger (id INTEGER PRIMARY KEY, amount INTEGER)`,
+		`INSERT INTO ledger VALUES (1, 9007199254740993), (2, -9223372036854775808), (3, 9007199254740992)`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/ledger/data", nil)
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Rows []map[string]json.RawMessage `json:"rows"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{`"9007199254740993"`, `"-9223372036854775808"`, `9007199254740992`}
+	if len(response.Rows) != len(expected) {
+		t.Fatalf("Expected %d rows, got %s", len(expected), w.Body.String())
+	}
+	for i, want := range expected {
+		if got := string(response.Rows[i]["amount"]); got != want {
+			t.Errorf("Row %d: expected amount %s, got %s", i+1, want, got)
+		}
+		if got := string(response.Rows[i]["id"]); got != fmt.Sprint(i+1) {
+			t.Errorf("Row %d: expected a numeric id, got %s", i+1, got)
+		}
+	}
+
+	// Values are only rendered in the response, so updates compare them exactly
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/tables/ledger/rows", strings.NewReader(`{"data": {"amount": 9007199254740992}, "where": {"id": 1}}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	var result models.UpdateResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if w.Code != http.StatusOK || result.Noop || result.RowsAffected != 1 {
+		t.Errorf("Expected the amount to change, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// and cursors keep big keys as numbers
+	if _, err := database.ExecuteSQL(`INSERT INTO ledger VALUES (9007199254740993, 1), (9007199254740995, 2)`); err != nil {
+		t.Fatal(err)
+	}
+	router = NewHandler(database, fstest.MapFS{}, Config{DefaultSort: true}).SetupRoutes()
+	cursor := ""
+	var ids []interface{}
+	for {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/api/tables/ledger/data?limit=2&after="+cursor, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var page models.TableData
+		json.Unmarshal(w.Body.Bytes(), &page)
+		for _, row := range page.Rows {
+			ids = append(ids, row["id"])
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	want := []interface{}{float64(1), float64(2), float64(3), "9007199254740993", "9007199254740995"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("Expected ids %v, got %v", want, ids)
+	}
+}
+
+func TestSelfJoinDuplicateColumnNames(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: "SELECT a.id, b.id FROM users a JOIN users b ON a.id <> b.id ORDER BY a.id"})
+
+	// SQL console results
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/sql/execute", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	var result models.SQLQueryResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Columns) != 2 || result.Columns[0] != "id" || result.Columns[1] != "id_1" {
+		t.Errorf("Expected columns [id id_1], got %v", result.Columns)
+	}
+
+	// CSV export of the same query
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/sql/export", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %d records", len(records))
+	}
+	if records[0][0] != "id" || records[0][1] != "id_1" {
+		t.Errorf("Expected distinct CSV headers [id id_1], got %v", records[0])
+	}
+	if records[1][0] != "1" || records[1][1] != "2" {
+		t.Errorf("Expected first row [1 2], got %v", records[1])
+	}
+}
+
+func TestExportTableCSVDelimiter(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/users/export/csv?"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("delimiter=" + url.QueryEscape(";") + "&crlf=true")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	want := "id;name;email;age\r\n" +
+		"1;John Doe;john@example.com;30\r\n" +
+		"2;Jane Smith;jane@example.com;25\r\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("Unexpected CSV:\n%q\nwant:\n%q", got, want)
+	}
+
+	for _, delimiter := range []string{";;", `"`, "\n"} {
+		if w := get("delimiter=" + url.QueryEscape(delimiter)); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for delimiter %q, got %d", http.StatusBadRequest, delimiter, w.Code)
+		}
+	}
+}
+
+func TestExportTableJSON(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/tables/users/export/json?sort_column=age&sort_direction=asc")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != "attachment; filename=users_export.json" {
+		t.Errorf("Unexpected Content-Disposition %q", got)
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("Expected a JSON array, got %v: %s", err, w.Body.String())
+	}
+	if len(rows) != 2 || rows[0]["name"] != "Jane Smith" || rows[1]["age"] != float64(30) {
+		t.Errorf("Expected both users sorted by age, got %v", rows)
+	}
+	// Keys follow the column order rather than being sorted
+	if !strings.Contains(w.Body.String(), `{"id":2,"name":"Jane Smith","email":"jane@example.com","age":25}`) {
+		t.Errorf("Expected row keys in column order, got %s", w.Body.String())
+	}
+
+	w = get("/api/tables/users/export/json?filters=" + url.QueryEscape(`[{"column":"age","op":">","value":26}]`))
+	rows = nil
+	json.Unmarshal(w.Body.Bytes(), &rows)
+	if len(rows) != 1 || rows[0]["name"] != "John Doe" {
+		t.Errorf("Expected only John Doe to match the filter, got %v", rows)
+	}
+
+	for _, tt := range []struct {
+		path string
+		want int
+	}{
+		{"/api/tables/users/export/json?sort_column=age&sort_direction=up", http.StatusBadRequest},
+		{"/api/tables/missing/export/json", http.StatusNotFound},
+	} {
+		w := get(tt.path)
+		if w.Code != tt.want {
+			t.Errorf("GET %s: expected status %d, got %d", tt.path, tt.want, w.Code)
+		}
+		if w.Header().Get("Content-Disposition") != "" {
+			t.Errorf("GET %s: expected no attachment for an error response", tt.path)
+		}
+	}
+}
+
+func TestDumpSQL(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQLScript(`
+		CREATE TABLE items (id INTEGER PRIMARY KEY, label TEXT, price REAL, data BLOB);
+		CREATE TABLE audit (item_id INTEGER);
+		CREATE TRIGGER items_audit AFTER INSERT ON items BEGIN INSERT INTO audit VALUES (NEW.id); END;
+		INSERT INTO items VALUES (1, 'it''s "quoted"', 0.1, X'00FF'), (2, NULL, -3, NULL);
+		CREATE INDEX items_label ON items (label);
+		CREATE VIEW priced AS SELECT label FROM items WHERE price > 0;
+		CREATE VIRTUAL TABLE notes USING fts4(body);
+		INSERT INTO notes VALUES ('full text');
+		DELETE FROM users WHERE id = 2;
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.SaveQuery("adults", "SELECT * FROM users WHERE age >= 18", false); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/export/sql", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	wantDisposition := "attachment; filename=" + strings.TrimSuffix(filepath.Base(dbPath), ".db") + ".sql"
+	if got := w.Header().Get("Content-Disposition"); got != wantDisposition {
+		t.Errorf("Expected Content-Disposition %q, got %q", wantDisposition, got)
+	}
+	if strings.Contains(w.Body.String(), "_sqliter_") {
+		t.Errorf("Expected SQLiter's own tables to be left out, got %s", w.Body.String())
+	}
+
+	// Load the dump into an empty database and compare every object and row
+	restoredPath := filepath.Join(t.TempDir(), "restored.db")
+	restoredDB, err := sql.Open("sqlite3", restoredPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restoredDB.Exec(w.Body.String()); err != nil {
+		t.Fatalf("Failed to load dump: %v\n%s", err, w.Body.String())
+	}
+	restoredDB.Close()
+	restored, err := db.NewSQLiteDB(restoredPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+
+	for _, query := range []string{
+		`SELECT type, name, sql FROM sqlite_master WHERE tbl_name NOT LIKE '\_sqliter\_%' ESCAPE '\' ORDER BY name`,
+		`SELECT id, name, email, age FROM users ORDER BY id`,
+		`SELECT id, quote(label), quote(price), quote(data) FROM items ORDER BY id`,
+		`SELECT * FROM audit ORDER BY item_id`,
+		`SELECT * FROM sqlite_sequence`,
+		`SELECT body FROM notes WHERE notes MATCH 'text'`,
+		`SELECT * FROM priced`,
+	} {
+		want, err := database.ExecuteSQL(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := restored.ExecuteSQL(query)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if !reflect.DeepEqual(got.Rows, want.Rows) {
+			t.Errorf("%s: expected %v after restoring, got %v", query, want.Rows, got.Rows)
+		}
+	}
+
+	// Scoped tables only dump their scoped rows
+	router = NewHandler(database, fstest.MapFS{}, Config{
+		Scopes: map[string][]models.Scope{"items": {{Column: "id", Value: "2"}}},
+	}).SetupRoutes()
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/export/sql", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if dump := w.Body.String(); strings.Contains(dump, `quoted`) || !strings.Contains(dump, `VALUES (2, NULL, -3.0, NULL);`) {
+		t.Errorf("Expected only the scoped item in the dump, got %s", dump)
+	}
+}
+
+func TestImportTableCSV(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`CREATE TABLE products (id INTEGER PRIMARY KEY, name TEXT NOT NULL, price REAL, qty INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	upload := func(query, content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", "products.csv")
+		part.Write([]byte(content))
+		form.Close()
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/tables/products/import/csv"+query, &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := upload("", "Name,price,qty\nWidget,2.50,3\nGadget,abc,1\nGizmo,1\nDoohickey,,7\n")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var report struct {
+		Imported int `json:"imported"`
+		Errors   []struct {
+			Row   int       `json:"row"`
+			Error errorBody `json:"error"`
+		} `json:"errors"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if report.Imported != 2 || len(report.Errors) != 2 || report.Errors[0].Row != 1 || report.Errors[1].Row != 2 {
+		t.Fatalf("Expected 2 rows imported and rows 1 and 2 reported, got %+v", report)
+	}
+	if rowError := report.Errors[0].Error; rowError.Code != "BAD_REQUEST" || !strings.Contains(rowError.Message, "'price'") {
+		t.Errorf("Expected an error envelope naming the column, got %+v", rowError)
+	}
+
+	result, err := database.ExecuteSQL(`SELECT name, typeof(price), typeof(qty) FROM products ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]interface{}{{"Widget", "real", "integer"}, {"Doohickey", "null", "integer"}}
+	if !reflect.DeepEqual(result.Rows, want) {
+		t.Errorf("Expected values converted to the column types, got %v", result.Rows)
+	}
+
+	// Without a header, fields map to the columns in order
+	if w := upload("?header=false", "10,Thing,1.5,2\n"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"imported":1`) {
+		t.Errorf("Expected a positional import of 1 row, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := upload("", "name,colour\nWidget,red\n"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown header column, got %d", http.StatusNotFound, w.Code)
+	}
+
+	// analyze=true refreshes the planner statistics after the load
+	stats := func() interface{} {
+		result, err := database.ExecuteSQL(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'sqlite_stat1'`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(result.Rows[0][0]) == "0" {
+			return nil
+		}
+		result, err = database.ExecuteSQL(`SELECT stat FROM sqlite_stat1 WHERE tbl = 'products'`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Rows) == 0 {
+			return nil
+		}
+		return result.Rows[0][0]
+	}
+	if got := stats(); got != nil {
+		t.Fatalf("Expected no statistics before analyzing, got %v", got)
+	}
+	if w := upload("?analyze=true", "name,price,qty\nSprocket,3,4\n"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got := stats(); got != "4" {
+		t.Errorf("Expected sqlite_stat1 to count the table's 4 rows, got %v", got)
+	}
+}
+
+func TestExportTableCSVStreaming(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	// Enough rows to span several flushes
+	if _, err := database.ExecuteSQL(`
+		WITH RECURSIVE n(i) AS (SELECT 3 UNION ALL SELECT i + 1 FROM n WHERE i < 2500)
+		INSERT INTO users (name, email, age) SELECT 'user ' || i, 'user' || i || '@example.com', i FROM n`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/users/export/csv?sort_column=age&sort_direction=desc", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != "attachment; filename=users_export.csv" {
+		t.Errorf("Unexpected Content-Disposition %q", got)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2501 {
+		t.Fatalf("Expected a header and 2500 rows, got %d records", len(records))
+	}
+	if records[1][3] != "2500" || records[2500][3] != "3" {
+		t.Errorf("Expected rows sorted by age descending, got first %v and last %v", records[1], records[2500])
+	}
+
+	// Errors found before streaming starts still get a JSON response
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/missing/export/csv", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Disposition") != "" {
+		t.Errorf("Expected a plain %d error, got %d with Content-Disposition %q", http.StatusNotFound, w.Code, w.Header().Get("Content-Disposition"))
+	}
+}
+
+func TestGetTableDataCursorPaging(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQLScript(`
+		CREATE TABLE enrollments (student INTEGER, course TEXT, PRIMARY KEY (course, student));
+		INSERT INTO enrollments VALUES (2, 'math'), (1, 'art'), (1, 'math'), (3, 'art'), (2, 'art');
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{DefaultSort: true})
+	router := handler.SetupRoutes()
+
+	get := func(query string) (*httptest.ResponseRecorder, models.TableData) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/enrollments/data?"+query, nil)
+		router.ServeHTTP(w, req)
+		var data models.TableData
+		json.Unmarshal(w.Body.Bytes(), &data)
+		return w, data
+	}
+
+	// Follow the cursors from the first offset page to the end
+	var seen []string
+	query := "limit=2"
+	for pages := 0; query != ""; pages++ {
+		if pages > 3 {
+			t.Fatalf("Expected paging to end after 3 pages, seen %v", seen)
+		}
+		w, data := get(query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		for _, row := range data.Rows {
+			seen = append(seen, fmt.Sprintf("%v/%v", row["course"], row["student"]))
+		}
+		if pages > 0 && data.Total != -1 {
+			t.Errorf("Expected cursor pages to skip the count, got total %d", data.Total)
+		}
+		query = ""
+		if data.NextCursor != "" {
+			query = "limit=2&after=" + url.QueryEscape(data.NextCursor)
+		}
+	}
+
+	want := []string{"art/1", "art/2", "art/3", "math/1", "math/2"}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("Expected rows %v in key order, got %v", want, seen)
+	}
+
+	for _, query := range []string{"after=not-a-cursor", "after=e30&sort_column=course&sort_direction=asc", "after=e30"} {
+		if w, _ := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestGetTableDataCountModes(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	get := func(query string) (*httptest.ResponseRecorder, models.TableData) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/users/data?limit=1&"+query, nil)
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", query, http.StatusOK, w.Code, w.Body.String())
+		}
+		var data models.TableData
+		json.Unmarshal(w.Body.Bytes(), &data)
+		return w, data
+	}
+
+	w, data := get("skip_count=true")
+	if data.Total != -1 || len(data.Rows) != 1 || data.TotalPages != 0 || w.Header().Get("Link") != "" {
+		t.Errorf("Expected an unknown total without page links, got total %d, %d rows, %d pages, Link %q",
+			data.Total, len(data.Rows), data.TotalPages, w.Header().Get("Link"))
+	}
+
+	// Without statistics the estimate falls back to an exact count
+	if _, data := get("estimate_count=true"); data.Total != 2 || data.TotalEstimated {
+		t.Errorf("Expected an exact total of 2, got %d (estimated=%v)", data.Total, data.TotalEstimated)
+	}
+
+	if _, err := database.ExecuteSQLScript(`
+		CREATE INDEX users_age ON users (age);
+		ANALYZE;
+		INSERT INTO users (name, email, age) VALUES ('Late Comer', 'late@example.com', 40);
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	// The estimate is as of the last ANALYZE
+	if _, data := get("estimate_count=true"); data.Total != 2 || !data.TotalEstimated || data.TotalPages != 2 {
+		t.Errorf("Expected an estimated total of 2 over 2 pages, got %d (estimated=%v) over %d pages", data.Total, data.TotalEstimated, data.TotalPages)
+	}
+	filters := url.QueryEscape(`[{"column":"age","op":">","value":0}]`)
+	if _, data := get("estimate_count=true&filters=" + filters); data.Total != 3 || data.TotalEstimated {
+		t.Errorf("Expected filtered rows to be counted exactly, got %d (estimated=%v)", data.Total, data.TotalEstimated)
+	}
+
+	// A partial index's statistics only count the rows it covers
+	if _, err := database.ExecuteSQLScript(`
+		CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT);
+		INSERT INTO events (kind) VALUES ('a'), (NULL), (NULL), (NULL);
+		CREATE INDEX events_kind ON events (kind) WHERE kind IS NOT NULL;
+		ANALYZE;
+	`); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/events/data?limit=1&estimate_count=true", nil)
+	router.ServeHTTP(w, req)
+	var events models.TableData
+	json.Unmarshal(w.Body.Bytes(), &events)
+	if events.Total != 4 || !events.TotalEstimated {
+		t.Errorf("Expected an estimated total of 4 for 'events', got %d (estimated=%v)", events.Total, events.TotalEstimated)
+	}
+}
+
+func TestGetForeignKeys(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQLScript(`
+		CREATE TABLE regions (country TEXT, code TEXT, name TEXT, PRIMARY KEY (country, code));
+		CREATE TABLE posts (
+			id INTEGER PRIMARY KEY,
+			user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+			country TEXT,
+			region TEXT,
+			FOREIGN KEY (country, region) REFERENCES regions ON UPDATE SET NULL
+		);
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/posts/foreign-keys", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		ForeignKeys []models.ForeignKey `json:"foreign_keys"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	// The composite key has no explicit target, so it maps onto the primary key in order
+	want := []models.ForeignKey{
+		{ID: 0, Seq: 0, Column: "country", ReferencedTable: "regions", ReferencedColumn: "country", OnUpdate: "SET NULL", OnDelete: "NO ACTION"},
+		{ID: 0, Seq: 1, Column: "region", ReferencedTable: "regions", ReferencedColumn: "code", OnUpdate: "SET NULL", OnDelete: "NO ACTION"},
+		{ID: 1, Seq: 0, Column: "user_id", ReferencedTable: "users", ReferencedColumn: "id", OnUpdate: "NO ACTION", OnDelete: "CASCADE"},
+	}
+	if !reflect.DeepEqual(response.ForeignKeys, want) {
+		t.Errorf("Expected %+v, got %+v", want, response.ForeignKeys)
+	}
+
+	// The schema response includes them too
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/posts/schema", nil)
+	router.ServeHTTP(w, req)
+	response.ForeignKeys = nil
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.ForeignKeys) != 3 {
+		t.Errorf("Expected 3 foreign key columns in the schema, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/missing/foreign-keys", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown table, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestGetRowByForeignKey(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQLScript(`
+		CREATE TABLE regions (country TEXT, code TEXT, PRIMARY KEY (country, code));
+		CREATE TABLE posts (
+			id INTEGER PRIMARY KEY,
+			user_id INTEGER REFERENCES users,
+			country TEXT,
+			region TEXT,
+			FOREIGN KEY (country, region) REFERENCES regions
+		);
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/tables/posts/fk/user_id/2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var data models.TableData
+	json.Unmarshal(w.Body.Bytes(), &data)
+	if len(data.Rows) != 1 || data.Rows[0]["name"] != "Jane Smith" {
+		t.Errorf("Expected the referenced user, got %s", w.Body.String())
+	}
+
+	failures := map[string]int{
+		"/api/tables/posts/fk/user_id/99":  http.StatusNotFound,
+		"/api/tables/posts/fk/title/1":     http.StatusBadRequest,
+		"/api/tables/posts/fk/country/de":  http.StatusBadRequest,
+		"/api/tables/missing/fk/user_id/1": http.StatusNotFound,
+	}
+	for path, status := range failures {
+		if w := get(path); w.Code != status {
+			t.Errorf("%s: expected status %d, got %d: %s", path, status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestGetTableDataExpandForeignKeyLabel(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT, user_id INTEGER REFERENCES users(id))`,
+		`INSERT INTO posts (title, user_id) VALUES ('Hello', 2), ('World', 1), ('Orphan', NULL)`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/posts/data?expand=user_id:name", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response models.TableData
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{"Hello": "Jane Smith", "World": "John Doe", "Orphan": nil}
+	for _, row := range response.Rows {
+		title := row["title"].(string)
+		if row["user_id__label"] != expected[title] {
+			t.Errorf("Expected label %v for post '%s', got %v", expected[title], title, row["user_id__label"])
+		}
+	}
+
+	// Non foreign key columns and unknown label columns are rejected
+	for _, expand := range []string{"title:name", "user_id:missing"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/api/tables/posts/data?expand="+expand, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for expand=%s, got %d", http.StatusBadRequest, expand, w.Code)
+		}
+	}
+}
+
+func TestTableNameWithSpaces(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE "order details" ("order id" INTEGER PRIMARY KEY, "sku code" TEXT UNIQUE, qty INTEGER)`,
+		`INSERT INTO "order details" ("sku code", qty) VALUES ('B-2', 5), ('A-1', 3)`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/order%20details/schema", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var schema struct {
+		Columns []models.Column `json:"columns"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if len(schema.Columns) != 3 {
+		t.Fatalf("Expected 3 columns, got %d", len(schema.Columns))
+	}
+	if !schema.Columns[1].Unique {
+		t.Error("Expected 'sku code' to be marked unique")
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/order%20details/data?sort_column=sku%20code&sort_direction=asc", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var data models.TableData
+	if err := json.Unmarshal(w.Body.Bytes(), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Total != 2 || len(data.Rows) != 2 {
+		t.Fatalf("Expected 2 rows, got total=%d rows=%d", data.Total, len(data.Rows))
+	}
+	if data.Rows[0]["sku code"] != "A-1" {
+		t.Errorf("Expected first sorted row to be 'A-1', got %v", data.Rows[0]["sku code"])
+	}
+}
+
+func TestGetTableDataSortCollation(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`INSERT INTO users (name, email, age) VALUES ('alice', 'alice@example.com', 20), ('Bob', 'bob@example.com', 40)`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	names := func(query string) []string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/users/data?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var response models.TableData
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		var result []string
+		for _, row := range response.Rows {
+			result = append(result, row["name"].(string))
+		}
+		return result
+	}
+
+	binary := names("sort_column=name&sort_direction=asc")
+	if binary[len(binary)-1] != "alice" {
+		t.Errorf("Expected BINARY order to put 'alice' last, got %v", binary)
+	}
+
+	nocase := names("sort_column=name&sort_direction=asc&collation=nocase")
+	expected := []string{"alice", "Bob", "Jane Smith", "John Doe"}
+	for i := range expected {
+		if nocase[i] != expected[i] {
+			t.Errorf("Expected NOCASE order %v, got %v", expected, nocase)
+			break
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/users/data?sort_column=name&sort_direction=asc&collation=evil", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown collation, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetTableDataMultiColumnSort(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`INSERT INTO users (name, email, age) VALUES ('Adam', 'adam@example.com', 30), ('Zoe', 'zoe@example.com', 25)`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/users/data?"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	names := func(query string) []string {
+		w := get(query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response models.TableData
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		var result []string
+		for _, row := range response.Rows {
+			result = append(result, row["name"].(string))
+		}
+		return result
+	}
+
+	expected := []string{"Zoe", "Jane Smith", "John Doe", "Adam"}
+	if got := names("sort=" + url.QueryEscape("age:asc,name:desc")); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected order %v, got %v", expected, got)
+	}
+
+	// The direction defaults to ascending and sort wins over sort_column
+	expected = []string{"Jane Smith", "Zoe", "Adam", "John Doe"}
+	if got := names("sort=age,name&sort_column=name&sort_direction=desc"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected order %v, got %v", expected, got)
+	}
+
+	// The single-column parameters still work
+	expected = []string{"Zoe", "John Doe", "Jane Smith", "Adam"}
+	if got := names("sort_column=name&sort_direction=desc"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected order %v, got %v", expected, got)
+	}
+
+	for _, query := range []string{"sort=age:up", "sort=nope:asc", "sort=age:asc,", "sort_column=nope&sort_direction=asc"} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %q, got %d: %s", http.StatusBadRequest, query, w.Code, w.Body.String())
+		}
+	}
+
+	// Exports accept the same parameter
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/users/export/json?sort=age:desc,name:asc", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var exported []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &exported); err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != 4 || exported[0]["name"] != "Adam" || exported[3]["name"] != "Zoe" {
+		t.Errorf("Expected export sorted by age desc then name, got %v", exported)
+	}
+}
+
+func TestWALStatusAndCheckpoint(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+	defer os.Remove(dbPath + "-wal")
+	defer os.Remove(dbPath + "-shm")
+
+	if _, err := database.ExecuteSQL("PRAGMA journal_mode=WAL"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.ExecuteSQL("INSERT INTO users (name, email, age) VALUES ('Wal', 'wal@example.com', 1)"); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/wal-status", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var status models.WALStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.JournalMode != "wal" {
+		t.Errorf("Expected journal mode 'wal', got %q", status.JournalMode)
+	}
+	if !status.WALFileExists || status.WALFileSize == 0 {
+		t.Errorf("Expected a non-empty WAL file, got exists=%v size=%d", status.WALFileExists, status.WALFileSize)
+	}
+	if status.LastCheckpoint != nil {
+		t.Errorf("Expected no checkpoint yet, got %+v", status.LastCheckpoint)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/maintenance/checkpoint", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var checkpoint models.CheckpointResult
+	if err := json.Unmarshal(w.Body.Bytes(), &checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Busy != 0 {
+		t.Errorf("Expected checkpoint not to be busy, got %+v", checkpoint)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/wal-status", nil)
+	router.ServeHTTP(w, req)
+
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.LastCheckpoint == nil {
+		t.Error("Expected the last checkpoint to be reported")
+	}
+	if status.WALFileSize != 0 {
+		t.Errorf("Expected the WAL file to be truncated, got size %d", status.WALFileSize)
+	}
+}
+
+func TestGetTableDataLinkHeader(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`INSERT INTO users (name, email, age) VALUES ('Third', 'third@example.com', 40)`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/users/data?limit=2&offset=0", nil)
+	router.ServeHTTP(w, req)
+
+	link := w.Header().Get("Link")
+	if !strings.Contains(link, `</api/tables/users/data?limit=2&offset=2>; rel="next"`) {
+		t.Errorf("Expected a next link to offset 2, got %q", link)
+	}
+	if !strings.Contains(link, `</api/tables/users/data?limit=2&offset=2>; rel="last"`) {
+		t.Errorf("Expected a last link to offset 2, got %q", link)
+	}
+	if strings.Contains(link, `rel="prev"`) {
+		t.Errorf("Expected no prev link on the first page, got %q", link)
+	}
+
+	// The last page has a prev link but no next link
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/users/data?limit=2&offset=2", nil)
+	router.ServeHTTP(w, req)
+
+	link = w.Header().Get("Link")
+	if strings.Contains(link, `rel="next"`) {
+		t.Errorf("Expected no next link on the last page, got %q", link)
+	}
+	if !strings.Contains(link, `</api/tables/users/data?limit=2&offset=0>; rel="prev"`) {
+		t.Errorf("Expected a prev link to offset 0, got %q", link)
+	}
+}
+
+func TestGetTableDataDefaultSort(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE tags (code TEXT PRIMARY KEY, label TEXT)`,
+		`INSERT INTO tags (code, label) VALUES ('c', 'Gamma'), ('a', 'Alpha'), ('b', 'Beta')`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fetch := func(router http.Handler, table, column string) []interface{} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/"+table+"/data", nil)
+		router.ServeHTTP(w, req)
+
+		var response models.TableData
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		var values []interface{}
+		for _, row := range response.Rows {
+			values = append(values, row[column])
+		}
+		return values
+	}
+
+	sorted := NewHandler(database, fstest.MapFS{}, Config{DefaultSort: true}).SetupRoutes()
+
+	ids := fetch(sorted, "users", "id")
+	if len(ids) != 2 || ids[0] != float64(1) || ids[1] != float64(2) {
+		t.Errorf("Expected users ordered by id, got %v", ids)
+	}
+
+	codes := fetch(sorted, "tags", "code")
+	if len(codes) != 3 || codes[0] != "a" || codes[1] != "b" || codes[2] != "c" {
+		t.Errorf("Expected tags ordered by primary key, got %v", codes)
+	}
+
+	// With the default sort disabled rows come back in storage order
+	unsorted := NewHandler(database, fstest.MapFS{}, Config{}).SetupRoutes()
+
+	codes = fetch(unsorted, "tags", "code")
+	if len(codes) != 3 || codes[0] != "c" {
+		t.Errorf("Expected tags in insertion order, got %v", codes)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/settings/ui", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing setting, got %d", http.StatusNotFound, w.Code)
+	}
+
+	blob := `{"columnWidths":{"users":{"name":240}},"savedFilters":[{"column":"age","op":">","value":21}]}`
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/settings/ui", strings.NewReader(blob))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/settings/ui", nil)
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Key   string          `json:"key"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	var got, want interface{}
+	if err := json.Unmarshal(response.Value, &got); err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal([]byte(blob), &want)
+	if response.Key != "ui" || !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected stored blob back, got key=%q value=%s", response.Key, response.Value)
+	}
+
+	// Invalid JSON and oversized values are rejected
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/settings/ui", strings.NewReader("{not json"))
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for invalid JSON, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/settings/ui", strings.NewReader(`"`+strings.Repeat("x", 70*1024)+`"`))
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d for an oversized value, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+
+	// The settings table is hidden from the table list
+	tables, err := database.GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		if table.Name == "_sqliter_settings" {
+			t.Error("Expected the settings table to be excluded from GetTables")
+		}
+	}
+}
+
+func TestColumnarFormat(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var rowMajor models.TableData
+	if err := json.Unmarshal(get("/api/tables/users/data").Body.Bytes(), &rowMajor); err != nil {
+		t.Fatal(err)
+	}
+
+	w := get("/api/tables/users/data?format=columnar")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var columnar models.ColumnarData
+	if err := json.Unmarshal(w.Body.Bytes(), &columnar); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rebuild rows from the columnar form and compare
+	rebuilt := make([]models.Row, columnar.RowCount)
+	for j := range rebuilt {
+		rebuilt[j] = models.Row{}
+		for i, name := range columnar.Columns {
+			rebuilt[j][name] = columnar.Values[i][j]
+		}
+	}
+	if !reflect.DeepEqual(rebuilt, rowMajor.Rows) {
+		t.Errorf("Expected columnar data to round-trip to %v, got %v", rowMajor.Rows, rebuilt)
+	}
+	if columnar.Total != rowMajor.Total {
+		t.Errorf("Expected total %d, got %d", rowMajor.Total, columnar.Total)
+	}
+
+	// SQL console results
+	body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: "SELECT id, name FROM users ORDER BY id"})
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/sql/execute?format=columnar", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if err := json.Unmarshal(w.Body.Bytes(), &columnar); err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]interface{}{{float64(1), float64(2)}, {"John Doe", "Jane Smith"}}
+	if !reflect.DeepEqual(columnar.Values, expected) {
+		t.Errorf("Expected columnar values %v, got %v", expected, columnar.Values)
+	}
+}
+
+func TestGetTableDataMaxColumns(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	var columns []string
+	for i := 0; i < 20; i++ {
+		columns = append(columns, fmt.Sprintf("c%d INTEGER DEFAULT %d", i, i))
+	}
+	if _, err := database.ExecuteSQL("CREATE TABLE wide (" + strings.Join(columns, ", ") + ")"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.ExecuteSQL("INSERT INTO wide DEFAULT VALUES"); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{MaxColumns: 5})
+	router := handler.SetupRoutes()
+
+	fetch := func(path string) models.TableData {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response models.TableData
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	data := fetch("/api/tables/wide/data")
+	if !data.ColumnsTruncated {
+		t.Error("Expected columns_truncated to be set")
+	}
+	if len(data.Columns) != 5 || len(data.Rows[0]) != 5 {
+		t.Errorf("Expected 5 columns, got %d schema columns and %d row fields", len(data.Columns), len(data.Rows[0]))
+	}
+	if data.Columns[4].Name != "c4" {
+		t.Errorf("Expected the first 5 columns, got last column %s", data.Columns[4].Name)
+	}
+
+	// An explicit projection is honoured and not truncated
+	data = fetch("/api/tables/wide/data?columns=c19,c3")
+	if data.ColumnsTruncated {
+		t.Error("Expected columns_truncated to be unset for a projection")
+	}
+	if len(data.Rows[0]) != 2 || data.Rows[0]["c19"] != float64(19) || data.Rows[0]["c3"] != float64(3) {
+		t.Errorf("Expected projected columns c19 and c3, got %v", data.Rows[0])
+	}
+
+	// Narrow tables are untouched
+	data = fetch("/api/tables/users/data")
+	if data.ColumnsTruncated || len(data.Columns) != 4 {
+		t.Errorf("Expected all 4 users columns, got %d (truncated=%v)", len(data.Columns), data.ColumnsTruncated)
+	}
+}
+
+func TestExecuteSQLHTMLFormat(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: "SELECT '<script>alert(1)</script>' AS payload, NULL AS empty"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/sql/execute?format=html", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Expected an HTML content type, got %q", w.Header().Get("Content-Type"))
+	}
+
+	out := w.Body.String()
+	if strings.Contains(out, "<script>") {
+		t.Errorf("Expected cell values to be escaped, got %s", out)
+	}
+	if !strings.Contains(out, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Errorf("Expected escaped script text in output, got %s", out)
+	}
+	if !strings.Contains(out, "<th>payload</th>") || !strings.Contains(out, "<td>NULL</td>") {
+		t.Errorf("Expected header and NULL cell in output, got %s", out)
+	}
+
+	// The Accept header selects HTML as well
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/sql/execute", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/html")
+	router.ServeHTTP(w, req)
+
+	if !strings.HasPrefix(w.Body.String(), "<table>") {
+		t.Errorf("Expected an HTML table for Accept: text/html, got %s", w.Body.String())
+	}
+}
+
+func TestSchemaDiff(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	other, otherPath := setupTestDB(t)
+	defer os.Remove(otherPath)
+	setup := []string{
+		`ALTER TABLE users ADD COLUMN nickname TEXT`,
+		`CREATE TABLE audit (id INTEGER PRIMARY KEY)`,
+		`CREATE UNIQUE INDEX idx_users_name ON users(name)`,
+	}
+	for _, stmt := range setup {
+		if _, err := other.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// SQLiter's own tables aren't part of the schema
+	if _, err := other.SaveQuery("adults", "SELECT * FROM users WHERE age >= 18", false); err != nil {
+		t.Fatal(err)
+	}
+	other.Close()
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	body, _ := json.Marshal(models.SchemaDiffRequest{Path: otherPath})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/schema-diff", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var diff models.SchemaDiff
+	if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.TablesOnlyInOther) != 1 || diff.TablesOnlyInOther[0] != "audit" {
+		t.Errorf("Expected 'audit' to be only in the other database, got %v", diff.TablesOnlyInOther)
+	}
+	if len(diff.Tables) != 1 || diff.Tables[0].Table != "users" {
+		t.Fatalf("Expected a column diff for 'users', got %+v", diff.Tables)
+	}
+	if cols := diff.Tables[0].ColumnsOnlyInOther; len(cols) != 1 || cols[0] != "nickname" {
+		t.Errorf("Expected 'nickname' to be only in the other database, got %v", cols)
+	}
+	// A column that only became unique is a changed column
+	if changed := diff.Tables[0].ChangedColumns; len(changed) != 1 || changed[0].Column != "name" || !changed[0].Other.Unique {
+		t.Errorf("Expected 'name' to be changed to unique, got %+v", changed)
+	}
+
+	// The other database is detached afterwards
+	if _, err := database.ExecuteSQL("SELECT * FROM sqliter_diff.users"); err == nil {
+		t.Error("Expected the compared database to be detached")
+	}
+
+	// A missing file is rejected instead of being created
+	body, _ = json.Marshal(models.SchemaDiffRequest{Path: otherPath + ".missing"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/schema-diff", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a missing file, got %d", http.StatusBadRequest, w.Code)
+	}
+	if _, err := os.Stat(otherPath + ".missing"); err == nil {
+		os.Remove(otherPath + ".missing")
+		t.Error("Expected the missing database file not to be created")
+	}
+}
+
+func TestSnapshotPagination(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+	defer os.Remove(dbPath + "-wal")
+	defer os.Remove(dbPath + "-shm")
+
+	// Readers only avoid blocking writers in WAL mode
+	if _, err := database.ExecuteSQL("PRAGMA journal_mode=WAL"); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{DefaultSort: true})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/snapshots", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var snapshot models.Snapshot
+	if err := json.Unmarshal(w.Body.Bytes(), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	page := func(query string) models.TableData {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/users/data?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response models.TableData
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	first := page("limit=1&offset=0&snapshot=" + snapshot.Token)
+	if first.Total != 2 {
+		t.Fatalf("Expected 2 rows in the snapshot, got %d", first.Total)
+	}
+
+	if _, err := database.ExecuteSQL("INSERT INTO users (name, email, age) VALUES ('Late', 'late@example.com', 50)"); err != nil {
+		t.Fatal(err)
+	}
+
+	for offset := 1; offset < 3; offset++ {
+		data := page(fmt.Sprintf("limit=1&offset=%d&snapshot=%s", offset, snapshot.Token))
+		if data.Total != 2 {
+			t.Errorf("Expected the snapshot total to stay 2, got %d", data.Total)
+		}
+		for _, row := range data.Rows {
+			if row["name"] == "Late" {
+				t.Error("Expected the row inserted mid-paging to be invisible in the snapshot")
+			}
+		}
+	}
+
+	// Outside the snapshot the new row is visible
+	if data := page("limit=1&offset=0"); data.Total != 3 {
+		t.Errorf("Expected 3 rows outside the snapshot, got %d", data.Total)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/api/snapshots/"+snapshot.Token, nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// A closed snapshot can no longer be used
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/users/data?snapshot="+snapshot.Token, nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a closed snapshot, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestRecentTables(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{TrackUsage: true})
+	router := handler.SetupRoutes()
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/users/data", nil)
+		router.ServeHTTP(w, req)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/recent", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Tables []models.TableUsage `json:"tables"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Tables) != 1 || response.Tables[0].Name != "users" || response.Tables[0].AccessCount != 2 {
+		t.Errorf("Expected 'users' accessed twice, got %+v", response.Tables)
+	}
+
+	// The usage table is not listed as a user table
+	tables, err := database.GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		if table.Name == "_sqliter_usage" {
+			t.Error("Expected the usage table to be excluded from GetTables")
+		}
+	}
+}
+
+func TestRecentTablesTrackingDisabled(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	// Tracking is off unless enabled
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/users/data", nil)
+	router.ServeHTTP(w, req)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/recent", nil)
+	router.ServeHTTP(w, req)
+
+	if strings.TrimSpace(w.Body.String()) != `{"tables":[]}` {
+		t.Errorf("Expected no recent tables when tracking is disabled, got %s", w.Body.String())
+	}
+}
+
+func TestGetTableDataSearch(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`INSERT INTO users (name, email, age) VALUES ('Bob 100% Real', 'bob@example.com', 30)`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	search := func(query string) models.TableData {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/users/data?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response models.TableData
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	// Matches any text column, ignoring ASCII case
+	if data := search("search=JOHN"); data.Total != 1 || data.Rows[0]["name"] != "John Doe" {
+		t.Errorf("Expected 'JOHN' to match John Doe, got %+v", data.Rows)
+	}
+	if data := search("search=example.com"); data.Total != 3 {
+		t.Errorf("Expected the email column to be searched, got %d rows", data.Total)
+	}
+
+	// Numeric columns aren't scanned
+	if data := search("search=25"); data.Total != 0 {
+		t.Errorf("Expected search not to match the integer age column, got %d rows", data.Total)
+	}
+
+	// Combined with filters using AND
+	filters := url.QueryEscape(`[{"column":"age","op":"=","value":30}]`)
+	if data := search("search=j&filters=" + filters); data.Total != 1 || data.Rows[0]["name"] != "John Doe" {
+		t.Errorf("Expected search and filters to be combined, got %+v", data.Rows)
+	}
+
+	// LIKE wildcards in the term match literally
+	if data := search("search=" + url.QueryEscape("0%")); data.Total != 1 || data.Rows[0]["name"] != "Bob 100% Real" {
+		t.Errorf("Expected '%%' to match literally, got %+v", data.Rows)
+	}
+	if data := search("search=J_hn"); data.Total != 0 {
+		t.Errorf("Expected '_' to match literally, got %d rows", data.Total)
+	}
+}
+
+func TestGetTableDataAccentInsensitiveSearch(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`INSERT INTO users (name, email, age) VALUES ('José Núñez', 'jose@example.com', 33)`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	search := func(query string) models.TableData {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/users/data?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response models.TableData
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	data := search("search=NUNEZ&fold=true")
+	if data.Total != 1 || len(data.Rows) != 1 || data.Rows[0]["name"] != "José Núñez" {
+		t.Errorf("Expected folded search to match 'José Núñez', got %+v", data.Rows)
+	}
+
+	data = search("search=jose+n&fold=true")
+	if data.Total != 1 {
+		t.Errorf("Expected folded search for 'jose n' to match 1 row, got %d", data.Total)
+	}
+
+	// Without folding the accented row doesn't match
+	data = search("search=nunez")
+	if data.Total != 0 {
+		t.Errorf("Expected unfolded search not to match, got %d rows", data.Total)
+	}
+}
+
+func TestSaveAs(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	target := filepath.Join(t.TempDir(), "copy.db")
+
+	saveAs := func(req models.SaveAsRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", "/api/save-as", bytes.NewBuffer(body))
+		r.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := saveAs(models.SaveAsRequest{Path: target}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	saved, err := db.NewSQLiteDB(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total, err := saved.CountRows("users", "")
+	saved.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Errorf("Expected the copy to have 2 users, got %d", total)
+	}
+
+	// Existing files require confirmation
+	if w := saveAs(models.SaveAsRequest{Path: target}); w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for an existing target, got %d", http.StatusConflict, w.Code)
+	}
+
+	// Switching serves the copy from then on, keeping the query history
+	if err := database.EnableQueryHistory(10, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.ExecuteSQL("SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if w := saveAs(models.SaveAsRequest{Path: target, Overwrite: true, Switch: true}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(target)); len(entries) != 1 {
+		t.Errorf("Expected only the saved file in the target directory, got %v", entries)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/info", nil)
+	router.ServeHTTP(w, req)
+
+	var info models.DatabaseInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Filename != "copy.db" {
+		t.Errorf("Expected filename 'copy.db' after switching, got %q", info.Filename)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/sql/history", nil)
+	router.ServeHTTP(w, req)
+	var history struct {
+		History []models.QueryHistoryEntry `json:"history"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &history)
+	if len(history.History) != 1 || history.History[0].SQL != "SELECT 1" {
+		t.Errorf("Expected the query history to carry over to the copy, got %s", w.Body.String())
+	}
+}
+
+func TestGetTableSchemaUniqueConstraints(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE memberships (id INTEGER PRIMARY KEY, org_id INTEGER, user_id INTEGER, handle TEXT, UNIQUE (org_id, user_id))`,
+		`CREATE UNIQUE INDEX memberships_active_handle ON memberships (handle) WHERE handle IS NOT NULL`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/memberships/schema", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Columns           []models.Column           `json:"columns"`
+		UniqueConstraints []models.UniqueConstraint `json:"unique_constraints"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, col := range response.Columns {
+		if col.Unique {
+			t.Errorf("Expected column '%s' not to be flagged unique", col.Name)
+		}
+	}
+
+	if len(response.UniqueConstraints) != 1 {
+		t.Fatalf("Expected 1 composite unique constraint, got %+v", response.UniqueConstraints)
+	}
+	if cols := response.UniqueConstraints[0].Columns; len(cols) != 2 || cols[0] != "org_id" || cols[1] != "user_id" {
+		t.Errorf("Expected composite unique constraint on (org_id, user_id), got %v", cols)
+	}
+}
+
+func TestGetDiagnostics(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{
+		MaxColumns:     7,
+		MaxRows:        500,
+		RequestTimeout: time.Minute,
+		JournalMode:    "wal",
+		AuthToken:      "secret",
+	})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/diagnostics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var diagnostics models.Diagnostics
+	if err := json.Unmarshal(w.Body.Bytes(), &diagnostics); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(diagnostics.SQLiteVersion, "3.") {
+		t.Errorf("Expected a SQLite 3.x version, got %q", diagnostics.SQLiteVersion)
+	}
+	if diagnostics.GoVersion != runtime.Version() {
+		t.Errorf("Expected Go version %q, got %q", runtime.Version(), diagnostics.GoVersion)
+	}
+	if diagnostics.JournalMode == "" {
+		t.Error("Expected the journal mode to be reported")
+	}
+	if diagnostics.Config["max_columns"] != float64(7) {
+		t.Errorf("Expected max_columns 7 in config, got %v", diagnostics.Config["max_columns"])
+	}
+	want := map[string]interface{}{
+		"max_rows":        float64(500),
+		"request_timeout": "1m0s",
+		"journal_mode":    "wal",
+		"auth":            true,
+	}
+	for key, value := range want {
+		if diagnostics.Config[key] != value {
+			t.Errorf("Expected %s %v in config, got %v", key, value, diagnostics.Config[key])
+		}
+	}
+	if strings.Contains(w.Body.String(), "secret") {
+		t.Error("Expected the credentials to be left out of the diagnostics")
+	}
+}
+
+func TestExecuteSQLQueryTimeout(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{QueryTimeout: 50 * time.Millisecond})
+	router := handler.SetupRoutes()
+
+	execute := func(query string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: query})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/sql/execute", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	start := time.Now()
+	w := execute("WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n) SELECT COUNT(*) FROM n")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected the query to be interrupted, it ran for %s", elapsed)
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusServiceUnavailable, w.Code, w.Body.String())
+	}
+	var response struct {
+		Error errorBody `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Error.Code != "QUERY_TIMEOUT" || !strings.Contains(response.Error.Message, "query timed out") {
+		t.Errorf("Expected a query timeout error, got %+v", response.Error)
+	}
+
+	w = execute("UPDATE users SET age = (WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n) SELECT COUNT(*) FROM n)")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d for a runaway update, got %d: %s", http.StatusServiceUnavailable, w.Code, w.Body.String())
+	}
+
+	// Quick queries are unaffected
+	if w := execute("SELECT COUNT(*) FROM users"); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestMultipleDatabases(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	other, err := db.NewSQLiteDB(filepath.Join(t.TempDir(), "other.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if _, err := other.ExecuteSQL("CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	if err := handler.AddDatabase(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.AddDatabase(other); err == nil {
+		t.Error("Expected adding a database with a taken name to fail")
+	}
+	router := handler.SetupRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/databases")
+	var listed struct {
+		Databases []models.DatabaseEntry `json:"databases"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &listed)
+	defaultName := filepath.Base(dbPath)
+	want := []models.DatabaseEntry{
+		{Name: defaultName, Filename: defaultName, Default: true},
+		{Name: "other.db", Filename: "other.db"},
+	}
+	if !reflect.DeepEqual(listed.Databases, want) {
+		t.Errorf("Expected databases %+v, got %s", want, w.Body.String())
+	}
+
+	var info models.DatabaseInfo
+	json.Unmarshal(get("/api/info?db=other.db").Body.Bytes(), &info)
+	if info.Filename != "other.db" || !reflect.DeepEqual(info.Databases, []string{defaultName, "other.db"}) {
+		t.Errorf("Expected info for other.db listing both databases, got %+v", info)
+	}
+
+	var tables struct {
+		Tables []models.Table `json:"tables"`
+	}
+	json.Unmarshal(get("/api/tables?db=other.db").Body.Bytes(), &tables)
+	if len(tables.Tables) != 1 || tables.Tables[0].Name != "notes" {
+		t.Errorf("Expected the notes table of other.db, got %+v", tables.Tables)
+	}
+	if w := get("/api/tables/users/data"); w.Code != http.StatusOK {
+		t.Errorf("Expected the default database to be used without db, got status %d: %s", w.Code, w.Body.String())
+	}
+	if w := get("/api/tables?db=missing.db"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown database, got %d", http.StatusNotFound, w.Code)
+	}
+
+	// A handler left without databases answers instead of panicking
+	empty := &Handler{staticFS: fstest.MapFS{}, dbs: make(map[string]*db.SQLiteDB)}
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables", nil)
+	empty.SetupRoutes().ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d without databases, got %d: %s", http.StatusServiceUnavailable, w.Code, w.Body.String())
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	static := fstest.MapFS{"index.html": {Data: []byte("<html></html>")}}
+
+	request := func(router http.Handler, path string, setAuth func(*http.Request)) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		if setAuth != nil {
+			setAuth(req)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	basic := func(user, pass string) func(*http.Request) {
+		return func(req *http.Request) { req.SetBasicAuth(user, pass) }
+	}
+	bearer := func(token string) func(*http.Request) {
+		return func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+	}
+
+	t.Run("basic", func(t *testing.T) {
+		router := NewHandler(database, static, Config{AuthUser: "admin", AuthPass: "secret"}).SetupRoutes()
+
+		w := request(router, "/api/tables", nil)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+		if challenge := w.Header().Get("WWW-Authenticate"); !strings.HasPrefix(challenge, "Basic ") {
+			t.Errorf("Expected a basic auth challenge, got %q", challenge)
+		}
+		if w := request(router, "/api/tables", basic("admin", "wrong")); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected a wrong password to get status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+		if w := request(router, "/api/tables", basic("admin", "secret")); w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if w := request(router, "/", nil); w.Code != http.StatusOK {
+			t.Errorf("Expected index.html to be served without credentials, got status %d", w.Code)
+		}
+	})
+
+	t.Run("token", func(t *testing.T) {
+		router := NewHandler(database, static, Config{AuthToken: "t0ken"}).SetupRoutes()
+
+		w := request(router, "/api/tables", bearer("other"))
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+		if challenge := w.Header().Get("WWW-Authenticate"); !strings.HasPrefix(challenge, "Bearer ") {
+			t.Errorf("Expected a bearer challenge, got %q", challenge)
+		}
+		if w := request(router, "/api/tables", bearer("t0ken")); w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		router := NewHandler(database, static, Config{}).SetupRoutes()
+		if w := request(router, "/api/tables", nil); w.Code != http.StatusOK {
+			t.Errorf("Expected status %d without auth configured, got %d", http.StatusOK, w.Code)
+		}
+	})
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	writable, dbPath := setupTestDB(t)
+	writable.Close()
+	defer os.Remove(dbPath)
+
+	database, err := db.NewReadOnlySQLiteDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("GET", "/api/info", "")
+	var info models.DatabaseInfo
+	json.Unmarshal(w.Body.Bytes(), &info)
+	if !info.ReadOnly {
+		t.Errorf("Expected the database info to report read-only mode, got %s", w.Body.String())
+	}
+
+	allowed := []struct{ method, path, body string }{
+		{"GET", "/api/tables/users/data", ""},
+		{"POST", "/api/sql/execute", `{"sql": "SELECT COUNT(*) FROM users"}`},
+		{"POST", "/api/sql/execute", `{"sql": "PRAGMA table_info(users)"}`},
+		{"POST", "/api/sql/explain", `{"sql": "DELETE FROM users"}`},
+	}
+	for _, r := range allowed {
+		if w := request(r.method, r.path, r.body); w.Code != http.StatusOK {
+			t.Errorf("%s %s: expected status %d, got %d: %s", r.method, r.path, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+
+	rejected := []struct{ method, path, body string }{
+		{"POST", "/api/tables/users/rows", `{"data": {"name": "New", "email": "new@example.com"}}`},
+		{"PUT", "/api/tables/users/rows", `{"data": {"age": 1}, "where": {"id": 1}}`},
+		{"DELETE", "/api/tables/users/rows", `{"where": {"id": 1}}`},
+		{"POST", "/api/tables/users/rows/bulk", `{"rows": [{"name": "New"}]}`},
+		{"POST", "/api/tables", `{"name": "t", "columns": [{"name": "id", "type": "INTEGER"}]}`},
+		{"DELETE", "/api/tables/users", ""},
+		{"POST", "/api/sql/execute", `{"sql": "DELETE FROM users"}`},
+		{"POST", "/api/sql/execute", `{"sql": "WITH doomed AS (SELECT 1) DELETE FROM users"}`},
+		{"POST", "/api/sql/execute", `{"sql": "PRAGMA user_version = 3"}`},
+		{"POST", "/api/sql/execute-script", `{"sql": "DELETE FROM users;"}`},
+	}
+	for _, r := range rejected {
+		w := request(r.method, r.path, r.body)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s %s: expected status %d, got %d: %s", r.method, r.path, r.body, http.StatusForbidden, w.Code, w.Body.String())
+			continue
+		}
+		var response struct {
+			Error errorBody `json:"error"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Error.Code != "READ_ONLY" {
+			t.Errorf("%s %s: expected a READ_ONLY error, got %+v", r.method, r.path, response.Error)
+		}
+	}
+
+	count, err := database.CountRows("users", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 users in the read-only database, got %d", count)
+	}
+}
+
+func TestExecuteSQLMaxLength(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{MaxSQLLength: 64})
+	router := handler.SetupRoutes()
+
+	query := "SELECT * FROM users WHERE name = '" + strings.Repeat("x", 100) + "'"
+	body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: query})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/sql/execute", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Error errorBody `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	expected := fmt.Sprintf("SQL query is %d bytes, exceeding the maximum of 64 bytes", len(query))
+	if response.Error.Message != expected {
+		t.Errorf("Expected error %q, got %q", expected, response.Error.Message)
+	}
+}
+
+func TestGetCell(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE documents (id INTEGER PRIMARY KEY, body TEXT, attachment BLOB)`,
+		`INSERT INTO documents (id, body, attachment) VALUES (1, NULL, X'00FF10')`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	body := strings.Repeat("lorem ipsum dolor sit amet ", 40000)
+	if _, err := database.UpdateRow("documents", map[string]interface{}{"body": body}, map[string]interface{}{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/tables/documents/rows/1/cell/body")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected a text/plain content type, got %q", w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("Content-Length") != fmt.Sprint(len(body)) {
+		t.Errorf("Expected Content-Length %d, got %q", len(body), w.Header().Get("Content-Length"))
+	}
+	if w.Body.String() != body {
+		t.Errorf("Streamed cell content does not match the stored value (%d vs %d bytes)", w.Body.Len(), len(body))
+	}
+
+	w = get("/api/tables/documents/rows/1/cell/attachment")
+	if w.Header().Get("Content-Type") != "application/octet-stream" || !bytes.Equal(w.Body.Bytes(), []byte{0x00, 0xFF, 0x10}) {
+		t.Errorf("Expected the BLOB as octet-stream, got %q %v", w.Header().Get("Content-Type"), w.Body.Bytes())
+	}
+
+	if w := get("/api/tables/documents/rows/1/cell/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown column, got %d", http.StatusNotFound, w.Code)
+	}
+	if _, err := database.ExecuteSQL(`INSERT INTO documents (id) VALUES (3)`); err != nil {
+		t.Fatal(err)
+	}
+	if w := get("/api/tables/documents/rows/3/cell/body"); w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("Expected status %d and no body for a NULL cell, got %d: %q", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	if w := get("/api/tables/documents/rows/2/cell/body"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown row, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestPutCell(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE documents (id INTEGER PRIMARY KEY, body TEXT, attachment BLOB)`,
+		`INSERT INTO documents (id, body, attachment) VALUES (1, 'hello', X'FFFF')`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	put := func(path string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/octet-stream")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A few MiB of bytes, including NULs and invalid UTF-8
+	upload := make([]byte, 3<<20+12345)
+	for i := range upload {
+		upload[i] = byte(i * 7)
+	}
+
+	w := put("/api/tables/documents/rows/1/cell/attachment", upload)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	value, storageClass, err := database.GetCell("documents", "1", "attachment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storageClass != "blob" {
+		t.Errorf("Expected the cell to be stored as a blob, got %s", storageClass)
+	}
+	if !bytes.Equal(value, upload) {
+		t.Errorf("Stored BLOB does not match the upload (%d vs %d bytes)", len(value), len(upload))
+	}
+
+	if w := put("/api/tables/documents/rows/1/cell/body", []byte("text")); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a non-BLOB column, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := put("/api/tables/documents/rows/2/cell/attachment", []byte{1}); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown row, got %d", http.StatusNotFound, w.Code)
+	}
+
+	router = NewHandler(database, fstest.MapFS{}, Config{MaxBlobSize: 1024}).SetupRoutes()
+	if w := put("/api/tables/documents/rows/1/cell/attachment", make([]byte, 1025)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d for an upload over the limit, got %d: %s", http.StatusRequestEntityTooLarge, w.Code, w.Body.String())
+	}
+
+	// Without a Content-Length the body is read up to the limit
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/tables/documents/rows/1/cell/attachment", io.MultiReader(bytes.NewReader(make([]byte, 1025))))
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d for a chunked upload over the limit, got %d: %s", http.StatusRequestEntityTooLarge, w.Code, w.Body.String())
+	}
+
+	// A declared length over the limit is rejected before the body is read
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/api/tables/documents/rows/1/cell/attachment", iotest.ErrReader(errors.New("body read")))
+	req.ContentLength = 1 << 40
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d for a declared length over the limit, got %d: %s", http.StatusRequestEntityTooLarge, w.Code, w.Body.String())
+	}
+
+	if value, _, err := database.GetCell("documents", "1", "attachment"); err != nil || !bytes.Equal(value, upload) {
+		t.Errorf("Expected a rejected upload to leave the cell unchanged")
+	}
+}
+
+func TestValidateSQL(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	validate := func(query string) models.SQLValidation {
+		body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: query})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/sql/validate", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var result models.SQLValidation
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if result := validate("DELETE FROM users WHERE id = 1"); !result.Valid {
+		t.Errorf("Expected a valid statement, got error %q", result.Error)
+	}
+
+	// Validating a write statement must not run it
+	count, err := database.CountRows("users", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 users after validation, got %d", count)
+	}
+
+	result := validate("SELECT * FROM users WHERE name = 'x' ORDR BY id")
+	if result.Valid {
+		t.Fatal("Expected a syntax error to be reported")
+	}
+	if result.Near != "ORDR" || result.Offset == nil || *result.Offset != 37 {
+		t.Errorf("Expected error near 'ORDR' at offset 37, got %+v", result)
+	}
+
+	// Every statement of a script is checked, with the offset into the script
+	result = validate("SELECT 1; SELECT 2; SELEC 3")
+	if result.Valid {
+		t.Fatal("Expected the syntax error in the last statement to be reported")
+	}
+	if result.Index == nil || *result.Index != 2 || result.Near != "SELEC" || result.Offset == nil || *result.Offset != 20 {
+		t.Errorf("Expected error in statement 2 near 'SELEC' at offset 20, got %+v", result)
+	}
+
+	// Later statements may use tables created by earlier ones, which are
+	// rolled back afterwards
+	if result := validate("CREATE TABLE scratch (x); INSERT INTO scratch VALUES (1)"); !result.Valid {
+		t.Errorf("Expected a valid script, got error %q", result.Error)
+	}
+	if _, err := database.GetTableSchema("scratch"); err == nil {
+		t.Error("Expected validation to leave no scratch table behind")
+	}
+	result = validate("CREATE TABLE scratch (x); CREATE INDEX idx_scratch_x ON scratch (x); INSERT INTO scratch (y) SELECT id FROM users")
+	if result.Valid || result.Index == nil || *result.Index != 2 || !strings.Contains(result.Error, "no column named y") {
+		t.Errorf("Expected statement 2 to fail against the created table, got %+v", result)
+	}
+
+	// Validation only reads, so it works on a read-only database too
+	readOnly, err := db.NewReadOnlySQLiteDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer readOnly.Close()
+	if result := readOnly.ValidateSQL("CREATE TABLE scratch (x); INSERT INTO scratch SELECT age FROM users"); !result.Valid {
+		t.Errorf("Expected a valid script on a read-only database, got error %q", result.Error)
+	}
+}
+
+func TestExplainSQL(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	explain := func(query string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: query})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/sql/explain", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	queries := map[string]string{
+		"SELECT * FROM users WHERE id = 1":                            "SEARCH users USING INTEGER PRIMARY KEY (rowid=?)",
+		"SELECT * FROM users WHERE age > 20":                          "SCAN users",
+		"UPDATE users SET age = 31 WHERE id = 1":                      "SEARCH users USING INTEGER PRIMARY KEY (rowid=?)",
+		"DELETE FROM users WHERE name = 'John Doe'":                   "SCAN users",
+		"INSERT INTO users (name, email) SELECT name, 'x' FROM users": "SCAN users",
+	}
+	for query, step := range queries {
+		w := explain(query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", query, http.StatusOK, w.Code, w.Body.String())
+		}
+		var result models.SQLQueryResult
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Columns) != 4 || result.Columns[3] != "detail" || result.RowCount == 0 {
+			t.Fatalf("%s: expected plan rows, got %+v", query, result)
+		}
+		if detail := result.Rows[0][3]; detail != step {
+			t.Errorf("%s: expected plan step %q, got %q", query, step, detail)
+		}
+	}
+
+	// Explaining writes must not run them
+	count, err := database.CountRows("users", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 users after explaining, got %d", count)
+	}
+
+	for _, query := range []string{"   ", "SELECT 1; DELETE FROM users", "SELECT * FROM missing"} {
+		if w := explain(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status %d, got %d: %s", query, http.StatusBadRequest, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestScopedTable(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE orders (id INTEGER PRIMARY KEY, tenant_id INTEGER, item TEXT)`,
+		`INSERT INTO orders (tenant_id, item) VALUES (1, 'apple'), (2, 'banana'), (1, 'cherry'), (3, 'date')`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{
+		Scopes: map[string][]models.Scope{"orders": {{Column: "tenant_id", Value: "1"}}},
+	})
+	router := handler.SetupRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{
+		"/api/tables/orders/data",
+		// A where clause trying to escape the scope only sees scoped rows
+		"/api/tables/orders/data?allow_raw=true&where_clause=" + url.QueryEscape("1) OR (1"),
+	} {
+		w := get(path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d for %s, got %d: %s", http.StatusOK, path, w.Code, w.Body.String())
+		}
+
+		var response models.TableData
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Total != 2 || len(response.Rows) != 2 {
+			t.Fatalf("Expected 2 scoped rows for %s, got total %d with %d rows", path, response.Total, len(response.Rows))
+		}
+		for _, row := range response.Rows {
+			if row["tenant_id"] != float64(1) {
+				t.Errorf("Expected only tenant 1 rows for %s, got %v", path, row)
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("HEAD", "/api/tables/orders/data", nil)
+	router.ServeHTTP(w, req)
+	if w.Header().Get("X-Total-Count") != "2" {
+		t.Errorf("Expected X-Total-Count 2, got %q", w.Header().Get("X-Total-Count"))
+	}
+
+	w = get("/api/tables/orders/export/csv")
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Errorf("Expected a header and 2 scoped rows in the export, got %d records", len(records))
+	}
+
+	if w := get("/api/tables/orders/rows/2/cell/item"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a cell outside the scope, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestGetFTSCandidates(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	candidates := func() models.FTSCandidates {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/users/fts-candidates", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var result models.FTSCandidates
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	result := candidates()
+	if !reflect.DeepEqual(result.Columns, []string{"name", "email"}) {
+		t.Errorf("Expected name and email as candidates, got %v", result.Columns)
+	}
+
+	if _, err := database.ExecuteSQL(`CREATE VIRTUAL TABLE users_fts USING fts4(content="users", name)`); err != nil {
+		t.Fatal(err)
+	}
+
+	result = candidates()
+	if !reflect.DeepEqual(result.Columns, []string{"email"}) || !reflect.DeepEqual(result.Indexed, []string{"name"}) {
+		t.Errorf("Expected email as the only candidate with name indexed, got %+v", result)
+	}
+}
+
+func TestFTSIndexAndSearch(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE notes (id INTEGER PRIMARY KEY, title TEXT, body TEXT, owner TEXT)`,
+		`INSERT INTO notes (title, body, owner) VALUES
+			('Groceries', 'milk, eggs and bread', 'ann'),
+			('SQLite tips', 'full text search with fts5, search ranking', 'ann'),
+			('Trip', 'search for cheap flights', 'bob')`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scopes := map[string][]models.Scope{"notes": {{Column: "owner", Value: "ann"}}}
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+	scoped := NewHandler(database, fstest.MapFS{}, Config{Scopes: scopes}).SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/tables/notes/fts", strings.NewReader(`{"columns": ["title", "body"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	// go-sqlite3 only includes FTS5 when built with -tags sqlite_fts5
+	if w.Code == http.StatusNotImplemented {
+		if !strings.Contains(w.Body.String(), "FTS5") {
+			t.Errorf("Expected the error to name FTS5, got %s", w.Body.String())
+		}
+		t.Skip("FTS5 is not available in this build")
+	}
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	search := func(r http.Handler, query string) models.FTSSearchResult {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/notes/search?"+query, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", query, http.StatusOK, w.Code, w.Body.String())
+		}
+		var result models.FTSSearchResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result
+	}
+	titles := func(result models.FTSSearchResult) []interface{} {
+		var titles []interface{}
+		for _, row := range result.Rows {
+			titles = append(titles, row["title"])
+		}
+		return titles
+	}
+
+	// The note mentioning search twice ranks first
+	result := search(router, "q=search")
+	if result.FTSTable != "notes_fts" || result.Total != 2 || !reflect.DeepEqual(titles(result), []interface{}{"SQLite tips", "Trip"}) {
+		t.Errorf("Expected both search notes, best first, got %+v", result)
+	}
+
+	// Triggers keep the index in sync
+	if _, err := database.ExecuteSQL(`UPDATE notes SET body = 'search engines' WHERE title = 'Groceries'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.ExecuteSQL(`DELETE FROM notes WHERE title = 'Trip'`); err != nil {
+		t.Fatal(err)
+	}
+	if result = search(router, "q=search&limit=1"); result.Total != 2 || len(result.Rows) != 1 {
+		t.Errorf("Expected 2 matches with 1 row returned, got %+v", result)
+	}
+	if result = search(router, "q=milk"); result.Total != 0 {
+		t.Errorf("Expected the old body to be gone from the index, got %+v", result)
+	}
+
+	// Scoped tables only match rows in scope
+	if _, err := database.ExecuteSQL(`INSERT INTO notes (title, body, owner) VALUES ('Search party', 'bob only', 'bob')`); err != nil {
+		t.Fatal(err)
+	}
+	if result = search(scoped, "q=search"); result.Total != 2 {
+		t.Errorf("Expected only ann's notes to match, got %+v", result)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/users/search?q=john", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a table without a full-text index, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRunFTSCommand(t *testing.T) {
+	for _, module := range []string{"fts4", "fts5"} {
+		t.Run(module, func(t *testing.T) {
+			database, dbPath := setupTestDB(t)
+			defer database.Close()
+			defer os.Remove(dbPath)
+
+			// go-sqlite3 only includes FTS5 when built with -tags sqlite_fts5
+			if _, err := database.ExecuteSQL(`CREATE VIRTUAL TABLE docs USING ` + module + `(title, body)`); err != nil {
+				if strings.Contains(err.Error(), "no such module") {
+					t.Skipf("%s is not available in this build", module)
+				}
+				t.Fatal(err)
+			}
+			if _, err := database.ExecuteSQL(`INSERT INTO docs (title, body) VALUES ('SQLite', 'full text search'), ('Go', 'static typing')`); err != nil {
+				t.Fatal(err)
+			}
+
+			handler := NewHandler(database, fstest.MapFS{}, Config{})
+			router := handler.SetupRoutes()
+
+			post := func(path string) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest("POST", path, nil)
+				router.ServeHTTP(w, req)
+				return w
+			}
+
+			for _, command := range []string{"rebuild", "integrity-check"} {
+				if w := post("/api/tables/docs/fts/" + command); w.Code != http.StatusOK {
+					t.Errorf("%s: expected status %d, got %d: %s", command, http.StatusOK, w.Code, w.Body.String())
+				}
+			}
+
+			result, err := database.ExecuteSQL(`SELECT title FROM docs WHERE docs MATCH 'search'`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.RowCount != 1 || result.Rows[0][0] != "SQLite" {
+				t.Errorf("Expected MATCH to find the SQLite row after the rebuild, got %v", result.Rows)
+			}
+
+			for _, tt := range []struct {
+				path string
+				want int
+			}{
+				{"/api/tables/users/fts/rebuild", http.StatusBadRequest},
+				{"/api/tables/docs/fts/optimize-everything", http.StatusBadRequest},
+				{"/api/tables/missing/fts/rebuild", http.StatusNotFound},
+			} {
+				if w := post(tt.path); w.Code != tt.want {
+					t.Errorf("POST %s: expected status %d, got %d: %s", tt.path, tt.want, w.Code, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteSQLNoSuchTable(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: "SELECT * FROM usres"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/sql/execute", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Error errorBody `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Error.Code != "NO_SUCH_TABLE" || response.Error.Table != "usres" {
+		t.Errorf("Expected NO_SUCH_TABLE for 'usres', got %+v", response.Error)
+	}
+	if !reflect.DeepEqual(response.Error.Suggestions, []string{"users"}) {
+		t.Errorf("Expected 'users' to be suggested, got %v", response.Error.Suggestions)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	canceled := make(chan bool, 1)
+	router.GET("/api/slow", func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+			canceled <- true
+		case <-time.After(5 * time.Second):
+			canceled <- false
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "too late"})
+	})
+	// Routes are registered up front, as the stuck handler outlives its request
+	router.GET("/api/stuck", func(c *gin.Context) {
+		time.Sleep(time.Second)
+		c.JSON(http.StatusOK, gin.H{"message": "too late"})
+	})
+	router.GET("/api/stream", func(c *gin.Context) {
+		c.Writer.WriteString("first chunk")
+		c.Writer.Flush()
+	})
+
+	server := WithRequestTimeout(router, 50*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/slow", nil)
+	server.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusServiceUnavailable, w.Code, w.Body.String())
+	}
+	if w.Body.String() != requestTimeoutBody {
+		t.Errorf("Expected body %s, got %s", requestTimeoutBody, w.Body.String())
+	}
+	if !<-canceled {
+		t.Error("Expected the request context to be canceled at the deadline")
+	}
+
+	// A handler that ignores the context is answered at the deadline, not
+	// when it finally writes
+	started := time.Now()
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/stuck", nil)
+	server.ServeHTTP(w, req)
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the response at the deadline, got it after %s", elapsed)
+	}
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != requestTimeoutBody {
+		t.Errorf("Expected the timeout response, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Downloads that have started aren't cut off at the deadline
+	for path, want := range map[string]bool{
+		"/api/tables/users/export/csv":         true,
+		"/api/tables/users/rows/1/cell/avatar": true,
+		"/api/backup":                          true,
+		"/api/tables/users/data":               false,
+	} {
+		req, _ := http.NewRequest("GET", path, nil)
+		if got := isStreamingRoute(req); got != want {
+			t.Errorf("%s: expected streaming %v, got %v", path, want, got)
+		}
+	}
+
+	// Fast requests pass through untouched
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables", nil)
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Streamed responses reach the client as they are written, not at the end
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/stream", nil)
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !w.Flushed || w.Body.String() != "first chunk" {
+		t.Errorf("Expected the flushed chunk to pass through, got %d (flushed %v): %s", w.Code, w.Flushed, w.Body.String())
+	}
+}
+
+func TestGetTableDataJSONPath(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE profiles (id INTEGER PRIMARY KEY, meta TEXT)`,
+		`INSERT INTO profiles (meta) VALUES ('{"address": {"city": "Lisbon"}, "tags": ["a", "b"]}'), ('{"tags": []}')`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/profiles/data?json_path="+url.QueryEscape("meta:$.address.city,meta:$.tags[1]"), nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response models.TableData
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(response.Rows))
+	}
+	if response.Rows[0]["meta.address.city"] != "Lisbon" || response.Rows[0]["meta.tags[1]"] != "b" {
+		t.Errorf("Expected extracted values Lisbon and b, got %v", response.Rows[0])
+	}
+	if response.Rows[1]["meta.address.city"] != nil {
+		t.Errorf("Expected a missing path to extract NULL, got %v", response.Rows[1]["meta.address.city"])
+	}
+
+	for _, param := range []string{"meta:address", "meta:$.a'); DROP TABLE profiles; --", "meta"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/tables/profiles/data?json_path="+url.QueryEscape(param), nil)
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for json_path %q, got %d", http.StatusBadRequest, param, w.Code)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/profiles/data?json_path="+url.QueryEscape("missing:$.a"), nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"column":"missing"`) {
+		t.Errorf("Expected status %d naming the unknown column, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+}
+
+func TestGetRowidChunks(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	setup := []string{
+		`CREATE TABLE events (name TEXT)`,
+		`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 250) INSERT INTO events (name) SELECT 'event-' || i FROM n`,
+		// Leave gaps in the rowid sequence
+		`DELETE FROM events WHERE rowid % 7 = 0`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.ExecuteSQL(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/events/chunks?size=40", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var chunks []models.RowidChunk
+	if err := json.Unmarshal(w.Body.Bytes(), &chunks); err != nil {
+		t.Fatal(err)
+	}
+
+	total, err := database.CountRows("events", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	covered := 0
+	var previousEnd int64
+	for i, chunk := range chunks {
+		if chunk.Start <= previousEnd || chunk.End < chunk.Start {
+			t.Errorf("Chunk %d [%d, %d] overlaps or is out of order", i, chunk.Start, chunk.End)
+		}
+		if chunk.Count > 40 {
+			t.Errorf("Chunk %d has %d rows, more than the requested size", i, chunk.Count)
+		}
+
+		count, err := database.CountRows("events", fmt.Sprintf("rowid BETWEEN %d AND %d", chunk.Start, chunk.End))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != chunk.Count {
+			t.Errorf("Chunk %d reports %d rows but its range holds %d", i, chunk.Count, count)
+		}
+		covered += count
+		previousEnd = chunk.End
+	}
+	if covered != total {
+		t.Errorf("Expected the chunks to cover all %d rows, covered %d", total, covered)
+	}
+
+	// WITHOUT ROWID tables have no rowid to split on
+	if _, err := database.ExecuteSQL(`CREATE TABLE tags (name TEXT PRIMARY KEY) WITHOUT ROWID`); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/tags/chunks", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"column":"rowid"`) {
+		t.Errorf("Expected status %d for a table without rowid, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+}
+
+func TestExecuteSQLSchemaChanged(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	execute := func(query string) models.SQLQueryResult {
+		body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: query})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/sql/execute", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var result models.SQLQueryResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result
+	}
+
+	result := execute("CREATE TABLE invoices (id INTEGER PRIMARY KEY, amount REAL)")
+	if !result.SchemaChanged {
+		t.Fatal("Expected schema_changed after CREATE TABLE")
+	}
+	found := false
+	for _, table := range result.Tables {
+		if table.Name == "invoices" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the new table in the refreshed list, got %v", result.Tables)
+	}
+
+	if result := execute("INSERT INTO invoices (amount) VALUES (9.5)"); result.SchemaChanged || result.Tables != nil {
+		t.Errorf("Expected no schema change for an INSERT, got %+v", result)
+	}
+}
+
+func TestExecuteSQLElapsedTime(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	execute := func(query string) models.SQLQueryResult {
+		body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: query})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/sql/execute", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var result models.SQLQueryResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result
+	}
+
+	result := execute("WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 100000) SELECT SUM(i) FROM n")
+	if result.ElapsedMs <= 0 {
+		t.Errorf("Expected a positive elapsed_ms for a SELECT, got %v", result.ElapsedMs)
+	}
+
+	result = execute("INSERT INTO users (name, email, age) VALUES ('Timed', 'timed@example.com', 40)")
+	if result.ElapsedMs <= 0 {
+		t.Errorf("Expected a positive elapsed_ms for an INSERT, got %v", result.ElapsedMs)
+	}
+}
+
+func TestExecuteSQLMaxRows(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{MaxRows: 100})
+	router := handler.SetupRoutes()
+
+	execute := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/sql/execute", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	result := func(body string) models.SQLQueryResult {
+		w := execute(body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var result models.SQLQueryResult
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	series := `WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1000) SELECT i FROM n`
+
+	if r := result(`{"sql": "` + series + `"}`); r.RowCount != 100 || !r.Truncated {
+		t.Errorf("Expected 100 rows truncated by the server limit, got %d (truncated=%v)", r.RowCount, r.Truncated)
+	}
+
+	// A result that fits exactly isn't truncated
+	if r := result(`{"sql": "` + series + ` LIMIT 100"}`); r.RowCount != 100 || r.Truncated {
+		t.Errorf("Expected all 100 rows without truncation, got %d (truncated=%v)", r.RowCount, r.Truncated)
+	}
+
+	// The request can lower the limit, but not raise or lift it
+	if r := result(`{"sql": "` + series + `", "maxRows": 10}`); r.RowCount != 10 || !r.Truncated {
+		t.Errorf("Expected 10 rows, got %d (truncated=%v)", r.RowCount, r.Truncated)
+	}
+	for _, override := range []string{"500", "0"} {
+		if r := result(`{"sql": "` + series + `", "maxRows": ` + override + `}`); r.RowCount != 100 || !r.Truncated {
+			t.Errorf("maxRows %s: expected the server limit of 100 rows, got %d (truncated=%v)", override, r.RowCount, r.Truncated)
+		}
+	}
+
+	if w := execute(`{"sql": "SELECT 1", "maxRows": -1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for a negative maxRows, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	// Scripts are capped the same way
+	body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: series + "; " + series})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/sql/execute-script", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	var script models.SQLScriptResult
+	json.Unmarshal(w.Body.Bytes(), &script)
+	if w.Code != http.StatusOK || len(script.Statements) != 2 {
+		t.Fatalf("Expected 2 statement results, got %d: %s", w.Code, w.Body.String())
+	}
+	for i, statement := range script.Statements {
+		if statement.RowCount != 100 || !statement.Truncated {
+			t.Errorf("Statement %d: expected 100 rows truncated by the server limit, got %d (truncated=%v)", i, statement.RowCount, statement.Truncated)
+		}
+	}
+
+	// Without a server limit a request may lift it
+	router = NewHandler(database, fstest.MapFS{}, Config{}).SetupRoutes()
+	if r := result(`{"sql": "` + series + `", "maxRows": 0}`); r.RowCount != 1000 || r.Truncated {
+		t.Errorf("Expected all 1000 rows, got %d (truncated=%v)", r.RowCount, r.Truncated)
+	}
+}
+
+func TestExecuteSQLStatementKinds(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	execute := func(query string) models.SQLQueryResult {
+		body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: query})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/sql/execute", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var result models.SQLQueryResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result
+	}
+
+	result := execute("-- adults\nWITH adults AS (SELECT name FROM users WHERE age >= 30) SELECT name FROM adults")
+	if len(result.Columns) != 1 || result.Columns[0] != "name" || result.RowCount != 1 {
+		t.Errorf("Expected the CTE query's rows, got %+v", result)
+	}
+
+	result = execute("PRAGMA table_list")
+	if result.RowCount == 0 || result.Columns[0] == "rows_affected" {
+		t.Errorf("Expected PRAGMA rows, got %+v", result)
+	}
+
+	result = execute("WITH young AS (SELECT id FROM users WHERE age < 30) DELETE FROM users WHERE id IN (SELECT id FROM young)")
+	if result.RowsAffected != 1 {
+		t.Errorf("Expected WITH ... DELETE to report 1 affected row, got %+v", result)
+	}
+}
+
+func TestQueryHistory(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if err := database.EnableQueryHistory(3, true); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	execute := func(query string) {
+		body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: query})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/sql/execute", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+	}
+	history := func() []models.QueryHistoryEntry {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/sql/history", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response struct {
+			History []models.QueryHistoryEntry `json:"history"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response.History
+	}
+
+	execute("SELECT 1")
+	execute("SELECT * FROM users")
+	execute("UPDATE users SET age = age + 1")
+	execute("SELECT * FROM missing")
+
+	// Only the last 3 are kept, newest first
+	entries := history()
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 history entries, got %+v", entries)
+	}
+	if entries[0].SQL != "SELECT * FROM missing" || entries[0].OK || entries[0].Error == "" {
+		t.Errorf("Expected the failed query first with its error, got %+v", entries[0])
+	}
+	if entries[1].SQL != "UPDATE users SET age = age + 1" || !entries[1].OK || entries[1].RowCount != 2 {
+		t.Errorf("Expected the UPDATE with 2 affected rows, got %+v", entries[1])
+	}
+	if entries[2].SQL != "SELECT * FROM users" || entries[2].RowCount != 2 || entries[2].ExecutedAt.IsZero() {
+		t.Errorf("Expected the SELECT with 2 rows, got %+v", entries[2])
+	}
+
+	// The stored history is loaded when the database is opened again
+	reopened, err := db.NewSQLiteDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if err := reopened.EnableQueryHistory(2, true); err != nil {
+		t.Fatal(err)
+	}
+	if stored := reopened.QueryHistory(); len(stored) != 2 || stored[0].SQL != "SELECT * FROM missing" || stored[1].SQL != "UPDATE users SET age = age + 1" {
+		t.Errorf("Expected the 2 newest stored entries, got %+v", stored)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/sql/history", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if entries := history(); len(entries) != 0 {
+		t.Errorf("Expected an empty history after clearing, got %+v", entries)
+	}
+
+	// The history table stays out of the table list
+	tables, err := database.GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		if strings.HasPrefix(table.Name, "_sqliter_") {
+			t.Errorf("Expected internal tables to be hidden, got %s", table.Name)
+		}
+	}
+}
+
+func TestSavedQueries(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	list := func() []models.SavedQuery {
+		w := request("GET", "/api/sql/saved", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response struct {
+			Queries []models.SavedQuery `json:"queries"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response.Queries
+	}
+
+	// Nothing is saved before the table exists
+	if saved := list(); len(saved) != 0 {
+		t.Fatalf("Expected no saved queries, got %+v", saved)
+	}
+
+	if w := request("POST", "/api/sql/saved", `{"name": "adults", "sql": "SELECT * FROM users WHERE age >= 18"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if w := request("POST", "/api/sql/saved", `{"name": "by age", "sql": "SELECT * FROM users ORDER BY age"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// A taken name conflicts unless overwriting
+	if w := request("POST", "/api/sql/saved", `{"name": "adults", "sql": "SELECT 1"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for a taken name, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+	if w := request("POST", "/api/sql/saved?overwrite=true", `{"name": "adults", "sql": "SELECT * FROM users WHERE age >= 21"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	for _, body := range []string{`{"name": "", "sql": "SELECT 1"}`, `{"name": "empty", "sql": " "}`} {
+		w := request("POST", "/api/sql/saved", body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, body, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), `"code":"INVALID_SAVED_QUERY"`) {
+			t.Errorf("Expected code INVALID_SAVED_QUERY for %s, got %s", body, w.Body.String())
+		}
+	}
+
+	saved := list()
+	if len(saved) != 2 || saved[0].Name != "adults" || saved[0].SQL != "SELECT * FROM users WHERE age >= 21" || saved[1].Name != "by age" {
+		t.Errorf("Expected both queries ordered by name with the overwritten SQL, got %+v", saved)
+	}
+
+	if w := request("DELETE", "/api/sql/saved/"+url.PathEscape("by age"), ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w := request("DELETE", "/api/sql/saved/missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a missing query, got %d", http.StatusNotFound, w.Code)
+	}
+	if saved := list(); len(saved) != 1 {
+		t.Errorf("Expected 1 saved query after deleting, got %+v", saved)
+	}
+
+	// The internal table stays out of the table list
+	tables, err := database.GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		if table.Name == "_sqliter_saved_queries" {
+			t.Error("Expected the saved queries table to be hidden")
+		}
+	}
+}
+
+func TestPreviewSQL(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	preview := func(query string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: query})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/sql/preview", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for query, affected := range map[string]int{
+		"DELETE FROM users":                             2,
+		"UPDATE users SET age = age + 1 WHERE age > 26": 1,
+		"WITH old AS (SELECT id FROM users WHERE age > 26) DELETE FROM users WHERE id IN (SELECT id FROM old)": 1,
+	} {
+		w := preview(query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d for %q, got %d: %s", http.StatusOK, query, w.Code, w.Body.String())
+		}
+		var result models.SQLQueryResult
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.RowsAffected != affected {
+			t.Errorf("Expected %q to affect %d rows, got %d", query, affected, result.RowsAffected)
+		}
+	}
+
+	// Nothing was committed
+	result, err := database.ExecuteSQL("SELECT COUNT(*), SUM(age) FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, sum := result.Rows[0][0], result.Rows[0][1]; count != int64(2) || sum != int64(55) {
+		t.Errorf("Expected the preview to be rolled back, got %v rows with ages summing to %v", count, sum)
+	}
+
+	for _, query := range []string{"SELECT * FROM users", "DROP TABLE users", "INSERT INTO users (name, email) VALUES ('x', 'x@example.com')", "DELETE FROM users; DELETE FROM users", "DELETE FROM missing"} {
+		if w := preview(query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %q, got %d: %s", http.StatusBadRequest, query, w.Code, w.Body.String())
+		}
+	}
+	if w := preview("DELETE FROM missing"); !strings.Contains(w.Body.String(), `"code":"NO_SUCH_TABLE"`) {
+		t.Errorf("Expected code NO_SUCH_TABLE, got %s", w.Body.String())
+	}
+}
+
+func TestExecuteSQLParams(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	execute := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/sql/execute", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	result := func(body string) models.SQLQueryResult {
+		w := execute(body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var result models.SQLQueryResult
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	r := result(`{"sql": "SELECT name FROM users WHERE age > ? AND name LIKE ?", "params": [26, "J%"]}`)
+	if r.RowCount != 1 || r.Rows[0][0] != "John Doe" {
+		t.Errorf("Expected John Doe, got %+v", r.Rows)
+	}
+
+	// Values are bound, never spliced into the statement
+	r = result(`{"sql": "INSERT INTO users (name, email, age) VALUES (?, ?, ?)", "params": ["Robert'); DROP TABLE users;--", "bobby@example.com", null]}`)
+	if r.RowsAffected != 1 {
+		t.Errorf("Expected 1 inserted row, got %+v", r)
+	}
+	r = result(`{"sql": "SELECT age FROM users WHERE email = :email", "params": ["bobby@example.com"]}`)
+	if r.RowCount != 1 || r.Rows[0][0] != nil {
+		t.Errorf("Expected the inserted row with a NULL age, got %+v", r.Rows)
+	}
+
+	for _, body := range []string{
+		`{"sql": "SELECT * FROM users WHERE id = ?"}`,
+		`{"sql": "SELECT * FROM users WHERE id = ?", "params": [1, 2]}`,
+		`{"sql": "SELECT * FROM users", "params": [1]}`,
+		`{"sql": "SELECT * FROM users WHERE id = ?", "params": [[1]]}`,
+		// Placeholders of a statement that doesn't compile yet can't be counted
+		`{"sql": "CREATE TABLE later (x); INSERT INTO later VALUES (?)", "params": [1, 2]}`,
+	} {
+		w := execute(body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d: %s", http.StatusBadRequest, body, w.Code, w.Body.String())
+		}
+	}
+	w := execute(`{"sql": "SELECT * FROM users WHERE id = ?", "params": [1, 2]}`)
+	if !strings.Contains(w.Body.String(), "1 placeholders but 2 params") {
+		t.Errorf("Expected a placeholder count error, got %s", w.Body.String())
+	}
+	var response struct {
+		Error errorBody `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Error.Code != "INVALID_PARAMS" {
+		t.Errorf("Expected code INVALID_PARAMS, got %s", response.Error.Code)
+	}
+	if tables, err := database.GetTables(); err != nil {
+		t.Fatal(err)
+	} else {
+		for _, table := range tables {
+			if table.Name == "later" {
+				t.Error("Expected nothing to run when the params can't be checked")
+			}
+		}
+	}
+
+	// Integral params are bound as integers, exactly
+	r = result(`{"sql": "SELECT typeof(?), ? = 9007199254740993, typeof(?)", "params": [3, 9007199254740993, 1.5]}`)
+	if r.Rows[0][0] != "integer" || r.Rows[0][1] != float64(1) || r.Rows[0][2] != "real" {
+		t.Errorf("Expected integer, 1 and real, got %+v", r.Rows)
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	send := func(method, path, body string) (*httptest.ResponseRecorder, errorBody) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		var response struct {
+			Error errorBody `json:"error"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response.Error
+	}
+
+	for _, tt := range []struct {
+		name, method, path, body string
+		status                   int
+		code, column             string
+	}{
+		{"unique", "POST", "/api/tables/users/rows", `{"data": {"name": "Copy", "email": "john@example.com"}}`, http.StatusBadRequest, "UNIQUE_VIOLATION", "email"},
+		{"not null", "POST", "/api/tables/users/rows", `{"data": {"email": "nameless@example.com"}}`, http.StatusBadRequest, "NOT_NULL_VIOLATION", "name"},
+		{"unknown column", "GET", "/api/tables/users/columns/nickname/distinct", "", http.StatusNotFound, "NOT_FOUND", "nickname"},
+		{"bad parameter", "GET", "/api/tables/users/data?limit=abc", "", http.StatusBadRequest, "BAD_REQUEST", ""},
+		{"unknown endpoint", "GET", "/api/nowhere", "", http.StatusNotFound, "NOT_FOUND", ""},
+	} {
+		w, got := send(tt.method, tt.path, tt.body)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.status, w.Code, w.Body.String())
+			continue
+		}
+		if got.Code != tt.code || got.Column != tt.column || got.Message == "" {
+			t.Errorf("%s: expected code %s on column %q, got %+v", tt.name, tt.code, tt.column, got)
+		}
+	}
+
+	// Preflights are answered before routing, so they never get an error
+	w, _ := send("OPTIONS", "/api/tables/users/columns/age", "")
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("Expected an empty %d preflight response, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	if methods := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(methods, "PATCH") {
+		t.Errorf("Expected PATCH to be allowed, got %q", methods)
+	}
+}
+
+func TestExecuteSQLScript(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	run := func(script string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.ExecuteSQLRequest{SQL: script})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/sql/execute-script", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := run(`
+		CREATE TABLE audit (id INTEGER PRIMARY KEY, user_id INTEGER, note TEXT);
+		CREATE TRIGGER users_audit AFTER UPDATE ON users BEGIN
+			INSERT INTO audit (user_id, note) VALUES (NEW.id, 'updated; age');
+		END;
+		UPDATE users SET age = age + 1;
+		SELECT COUNT(*) AS entries FROM audit;`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var result models.SQLScriptResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if len(result.Statements) != 4 {
+		t.Fatalf("Expected 4 statement results, got %d", len(result.Statements))
+	}
+	if result.RowsAffected != 2 || result.Statements[2].RowsAffected != 2 {
+		t.Errorf("Expected 2 rows affected by the update, got %d (total %d)", result.Statements[2].RowsAffected, result.RowsAffected)
+	}
+	if got := result.Statements[3].Rows; len(got) != 1 || got[0][0] != float64(2) {
+		t.Errorf("Expected the trigger to log 2 entries, got %v", got)
+	}
+	if !result.SchemaChanged {
+		t.Error("Expected schema_changed after creating a table and a trigger")
+	}
+
+	// A failing statement rolls back the ones before it
+	w = run(`INSERT INTO audit (note) VALUES ('kept?'); UPDATE users SET age = 0; INSERT INTO missing VALUES (1)`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	var failure struct {
+		Error errorBody `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &failure)
+	if failure.Error.Index == nil || *failure.Error.Index != 2 || failure.Error.Statement != "INSERT INTO missing VALUES (1)" {
+		t.Errorf("Expected the third statement to be reported, got %+v", failure.Error)
+	}
+
+	if count, err := database.CountRows("audit", ""); err != nil || count != 2 {
+		t.Errorf("Expected the audit insert to be rolled back, got %d rows (%v)", count, err)
+	}
+	if count, err := database.CountRows("users", "age = 0"); err != nil || count != 0 {
+		t.Errorf("Expected the users update to be rolled back, got %d rows (%v)", count, err)
+	}
+
+	// The script runs in its own transaction, so it can't begin or end one,
+	// and nothing runs when it tries
+	for _, script := range []string{
+		`BEGIN; UPDATE users SET age = 0; COMMIT`,
+		`UPDATE users SET age = 0; begin transaction`,
+		`UPDATE users SET age = 0; ROLLBACK`,
+		`UPDATE users SET age = 0; END TRANSACTION`,
+	} {
+		w = run(script)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d: %s", script, http.StatusBadRequest, w.Code, w.Body.String())
+		}
+		failure.Error = errorBody{}
+		json.Unmarshal(w.Body.Bytes(), &failure)
+		if failure.Error.Index == nil || !strings.Contains(failure.Error.Message, "single transaction") {
+			t.Errorf("%s: expected the transaction statement to be reported, got %+v", script, failure.Error)
+		}
+	}
+	if count, err := database.CountRows("users", "age = 0"); err != nil || count != 0 {
+		t.Errorf("Expected no statement of a rejected script to run, got %d rows (%v)", count, err)
+	}
+
+	// Savepoints nest inside the script's transaction
+	w = run(`SAVEPOINT retry; UPDATE users SET age = 0; ROLLBACK TO retry; RELEASE retry`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if count, err := database.CountRows("users", "age = 0"); err != nil || count != 0 {
+		t.Errorf("Expected the update to be rolled back to the savepoint, got %d rows (%v)", count, err)
+	}
+}
+
+func TestBulkInsert(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/tables/users/rows/bulk", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"rows": [
+		{"name": "Ann", "email": "ann@example.com", "age": 41},
+		{"name": "Bob", "email": "bob@example.com", "age": null},
+		{"name": "Cid", "email": "cid@example.com", "age": 19}
+	]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["rows_affected"] != float64(3) {
+		t.Errorf("Expected 3 rows affected, got %v", response["rows_affected"])
+	}
+
+	for _, tt := range []struct {
+		name, body string
+		want, row  int
+	}{
+		{"different columns", `{"rows": [{"name": "Dee", "email": "dee@example.com"}, {"name": "Eve", "age": 30}]}`, http.StatusBadRequest, 1},
+		{"constraint violation", `{"rows": [{"name": "Dee", "email": "dee@example.com"}, {"name": "Eve", "email": "eve@example.com"}, {"name": "Ann", "email": "ann@example.com"}]}`, http.StatusBadRequest, 2},
+	} {
+		w := post(tt.body)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, w.Code, w.Body.String())
+			continue
+		}
+		var failure struct {
+			Error errorBody `json:"error"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &failure)
+		if failure.Error.Row == nil || *failure.Error.Row != tt.row {
+			t.Errorf("%s: expected row %d to be reported, got %+v", tt.name, tt.row, failure.Error)
+		}
+	}
+
+	if w := post(`{"rows": [{"nickname": "Dee"}]}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown column, got %d", http.StatusNotFound, w.Code)
+	}
+
+	// Failed batches are rolled back entirely
+	count, err := database.CountRows("users", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 {
+		t.Errorf("Expected 5 users after one successful batch, got %d", count)
+	}
+}
+
+func TestBulkInsertReturning(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`CREATE TABLE tasks (id INTEGER PRIMARY KEY, title TEXT NOT NULL, status TEXT DEFAULT 'open')`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/tables/tasks/rows/bulk?returning=true", strings.NewReader(`{"rows": [{"title": "write"}, {"title": "review"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		RowsAffected int                      `json:"rows_affected"`
+		Rows         []map[string]interface{} `json:"rows"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		{"id": float64(1), "title": "write", "status": "open"},
+		{"id": float64(2), "title": "review", "status": "open"},
+	}
+	if response.RowsAffected != 2 || !reflect.DeepEqual(response.Rows, want) {
+		t.Errorf("Expected the inserted rows with their ids and defaults, got %+v", response)
+	}
+}
+
+func TestInsertRowDeclaredLengthLimit(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQL(`CREATE TABLE codes (id INTEGER PRIMARY KEY, code VARCHAR(10), note TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+
+	send := func(method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/api/tables/codes/rows", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", `{"data": {"code": "ABCDEFGHIJK"}}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+	var response struct {
+		Error errorBody `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Error.Code != "VALUE_TOO_LONG" || response.Error.Column != "code" || !strings.Contains(response.Error.Message, "limit of 10") {
+		t.Errorf("Expected the error to name the column and limit, got %+v", response.Error)
+	}
+
+	// Values within the limit, and columns without a declared length, are accepted
+	if w := send("POST", `{"data": {"code": "ÀBCDEFGHIJ", "note": "`+strings.Repeat("n", 100)+`"}}`); w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	if w := send("PUT", `{"data": {"code": "ABCDEFGHIJK"}, "where": {"id": 1}}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d for an over-length update, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	count, err := database.CountRows("codes", "code = 'ABCDEFGHIJK'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no over-length value to be written, found %d", count)
+	}
+}
+
+func TestRowKeyFormat(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQLScript(`
+		CREATE TABLE enrollments (student INTEGER, course TEXT, grade TEXT, PRIMARY KEY (course, student));
+		INSERT INTO enrollments VALUES (1, 'math', 'B'), (2, 'math', 'C');
+		CREATE TABLE notes (body TEXT);
+		INSERT INTO notes VALUES ('first'), ('second');
+		CREATE TABLE files (hash BLOB PRIMARY KEY, name TEXT);
+		INSERT INTO files VALUES (x'00ff', 'first'), (x'01', 'second');
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{models.RowKeyObject, models.RowKeyEmbedded, models.RowKeyToken} {
+		t.Run(format, func(t *testing.T) {
+			handler := NewHandler(database, fstest.MapFS{}, Config{RowKeyFormat: format})
+			router := handler.SetupRoutes()
+
+			firstRow := func(path string) models.Row {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest("GET", path, nil)
+				router.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+				}
+				var data models.TableData
+				json.Unmarshal(w.Body.Bytes(), &data)
+				if len(data.Rows) != 1 {
+					t.Fatalf("Expected 1 row, got %d", len(data.Rows))
+				}
+				return data.Rows[0]
+			}
+			update := func(table string, row models.Row, data map[string]interface{}) {
+				body := map[string]interface{}{"data": data}
+				if format == models.RowKeyEmbedded {
+					where := map[string]interface{}{}
+					for _, name := range []string{"course", "student", "rowid"} {
+						if value, ok := row[name]; ok {
+							where[name] = value
+						}
+					}
+					body["where"] = where
+				} else {
+					body["key"] = row[models.RowKeyField]
+				}
+				encoded, _ := json.Marshal(body)
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest("PUT", "/api/tables/"+table+"/rows", bytes.NewBuffer(encoded))
+				req.Header.Set("Content-Type", "application/json")
+				router.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+				}
+				var result map[string]interface{}
+				json.Unmarshal(w.Body.Bytes(), &result)
+				if result["rows_affected"] != float64(1) {
+					t.Errorf("Expected 1 row affected, got %v", result["rows_affected"])
+				}
+			}
+
+			row := firstRow("/api/tables/enrollments/data?sort_column=grade&sort_direction=desc&limit=1")
+			switch format {
+			case models.RowKeyObject:
+				want := map[string]interface{}{"course": "math", "student": float64(2)}
+				if !reflect.DeepEqual(row[models.RowKeyField], want) {
+					t.Errorf("Expected key %v, got %v", want, row[models.RowKeyField])
+				}
+			case models.RowKeyEmbedded:
+				if _, ok := row[models.RowKeyField]; ok {
+					t.Errorf("Expected no %s field, got %v", models.RowKeyField, row)
+				}
+			case models.RowKeyToken:
+				if _, ok := row[models.RowKeyField].(string); !ok {
+					t.Errorf("Expected a token, got %v", row[models.RowKeyField])
+				}
+			}
+			update("enrollments", row, map[string]interface{}{"grade": format})
+
+			count, err := database.CountRows("enrollments", fmt.Sprintf("student = 2 AND grade = '%s'", format))
+			if err != nil || count != 1 {
+				t.Errorf("Expected the keyed row to be updated, got %d rows (%v)", count, err)
+			}
+
+			// Tables without a primary key are keyed by rowid
+			row = firstRow("/api/tables/notes/data?limit=1")
+			update("notes", row, map[string]interface{}{"body": format})
+			count, err = database.CountRows("notes", fmt.Sprintf("rowid = 1 AND body = '%s'", format))
+			if err != nil || count != 1 {
+				t.Errorf("Expected the rowid-keyed row to be updated, got %d rows (%v)", count, err)
+			}
+
+			// BLOB keys are sent as {"__blob__": ...} and bound back as bytes
+			if format != models.RowKeyEmbedded {
+				row = firstRow("/api/tables/files/data?limit=1")
+				update("files", row, map[string]interface{}{"name": format})
+				count, err = database.CountRows("files", fmt.Sprintf("hash = x'00ff' AND name = '%s'", format))
+				if err != nil || count != 1 {
+					t.Errorf("Expected the BLOB-keyed row to be updated, got %d rows (%v)", count, err)
+				}
+			}
+		})
+	}
+
+	// A key and a where clause can't be combined
+	handler := NewHandler(database, fstest.MapFS{}, Config{})
+	router := handler.SetupRoutes()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/api/tables/enrollments/rows",
+		strings.NewReader(`{"data": {"grade": "A"}, "where": {"student": 1}, "key": {"course": "math", "student": 1}}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	database, dbPath := setupTestDB(t)
+	defer database.Close()
+	defer os.Remove(dbPath)
+
+	if _, err := database.ExecuteSQLScript(`
+		CREATE TABLE documents (id INTEGER PRIMARY KEY, body TEXT);
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 10)
+		INSERT INTO documents SELECT i, printf('%.1000c', 'x') FROM n;
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(database, fstest.MapFS{}, Config{MaxResponseBytes: 3500})
+	router := handler.SetupRoutes()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/tables/documents/data?limit=100", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var data models.TableData
+	json.Unmarshal(w.Body.Bytes(), &data)
+	if !data.TruncatedBySize || len(data.Rows) != 3 || data.Total != 10 {
+		t.Errorf("Expected 3 of 10 rows truncated by size, got %d of %d (truncated_by_size=%v)", len(data.Rows), data.Total, data.TruncatedBySize)
+	}
+
+	// Narrow rows fit within the budget and aren't flagged
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/tables/documents/data?limit=100&columns=id", nil)
+	router.ServeHTTP(w, req)
+	data = models.TableData{}
+	json.Unmarshal(w.Body.Bytes(), &data)
+	if data.TruncatedBySize || len(data.Rows) != 10 {
+		t.Errorf("Expected all 10 narrow rows, got %d (truncated_by_size=%v)", len(data.Rows), data.TruncatedBySize)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/sql/execute", strings.NewReader(`{"sql": "SELECT * FROM documents"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var result models.SQLQueryResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if !result.TruncatedBySize || result.RowCount != 3 {
+		t.Errorf("Expected 3 query rows truncated by size, got %d (truncated_by_size=%v)", result.RowCount, result.TruncatedBySize)
+	}
+	// The statements of a script share one budget
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/sql/execute-script", strings.NewReader(`{"sql": "SELECT * FROM documents WHERE id <= 2; SELECT * FROM documents"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	var script models.SQLScriptResult
+	json.Unmarshal(w.Body.Bytes(), &script)
+	if w.Code != http.StatusOK || len(script.Statements) != 2 {
+		t.Fatalf("Expected 2 statement results, got %d: %s", w.Code, w.Body.String())
+	}
+	if first, second := script.Statements[0], script.Statements[1]; first.RowCount != 2 || first.TruncatedBySize || second.RowCount != 1 || !second.TruncatedBySize {
+		t.Errorf("Expected 2 rows then 1 truncated by size, got %d (%v) and %d (%v)", first.RowCount, first.TruncatedBySize, second.RowCount, second.TruncatedBySize)
+	}
+}
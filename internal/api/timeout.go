@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+// requestTimeoutBody is sent with the 503 response when a request runs out of time.
+const requestTimeoutBody = `{"error":{"code":"REQUEST_TIMEOUT","message":"request timed out"}}`
+
+// streamingRoutes are the downloads that write their response while they read
+// the database. The timeout only bounds the wait for their response to start:
+// once it has, their context isn't canceled, so long downloads aren't cut off.
+var streamingRoutes = []string{
+	"/api/tables/*/export/*",
+	"/api/tables/*/rows/*/cell/*",
+	"/api/tables/*/blob/*",
+	"/api/sql/export",
+	"/api/export/sql",
+	"/api/backup",
+}
+
+// isStreamingRoute reports whether r is for one of the streamingRoutes.
+func isStreamingRoute(r *http.Request) bool {
+	for _, pattern := range streamingRoutes {
+		if ok, _ := path.Match(pattern, r.URL.Path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WithRequestTimeout bounds every request to the given wall-clock time. The
+// request context is canceled at the deadline, so context-aware work such as
+// database queries stops. Handlers run in their own goroutine, so a client
+// whose response hasn't started by the deadline gets a 503 then, even if the
+// handler ignores the context; whatever it writes later is discarded.
+// Responses aren't buffered, so streamed downloads reach the client as they
+// are written; see streamingRoutes for the downloads the deadline doesn't cut
+// off. A non-positive timeout returns next unchanged.
+func WithRequestTimeout(next http.Handler, timeout time.Duration) http.Handler {
+	if timeout <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if isStreamingRoute(r) {
+			ctx, cancel = context.WithCancel(r.Context())
+		} else {
+			ctx, cancel = context.WithTimeout(r.Context(), timeout)
+		}
+		defer cancel()
+		tw := &timeoutWriter{w: w, header: make(http.Header), ctx: ctx}
+		r = r.WithContext(ctx)
+
+		// done receives what the handler panicked with, or nil
+		done := make(chan interface{}, 1)
+		go func() {
+			defer func() { done <- recover() }()
+			next.ServeHTTP(tw, r)
+		}()
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		var p interface{}
+		select {
+		case p = <-done:
+		case <-timer.C:
+			if tw.timeout() {
+				cancel()
+			} else {
+				// The response has started, so let the handler finish it
+				p = <-done
+			}
+		}
+		if p != nil {
+			panic(p)
+		}
+	})
+}
+
+// timeoutWriter replaces a response that hasn't started by the request's
+// deadline with the 503 timeout response and discards what the handler writes
+// after that. The handler gets a header map of its own, so the timeout never
+// races with it.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+	ctx    context.Context
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeHeader(status)
+}
+
+func (w *timeoutWriter) writeHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.writeTimeout()
+		return
+	}
+	header := w.w.Header()
+	for key, values := range w.header {
+		header[key] = values
+	}
+	w.w.WriteHeader(status)
+}
+
+// timeout sends the 503 unless the response has already started, and reports
+// whether it did.
+func (w *timeoutWriter) timeout() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.wroteHeader {
+		return w.timedOut
+	}
+	w.wroteHeader = true
+	w.writeTimeout()
+	return true
+}
+
+func (w *timeoutWriter) writeTimeout() {
+	w.timedOut = true
+	w.w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.w.WriteHeader(http.StatusServiceUnavailable)
+	w.w.Write([]byte(requestTimeoutBody))
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeHeader(http.StatusOK)
+	if w.timedOut {
+		return len(data), nil
+	}
+	return w.w.Write(data)
+}
+
+// Flush passes flushes through so streamed responses aren't held back.
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	if flusher, ok := w.w.(http.Flusher); ok {
+		w.writeHeader(http.StatusOK)
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sqliter/internal/models"
+	"strings"
+)
+
+// attachAliasPattern matches aliases that can be used unquoted in queries.
+var attachAliasPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// AttachDatabase attaches the database file at path under alias, so SQL
+// console queries can read and write alias.table. database/sql pools
+// connections and ATTACH only affects one of them, so the attachment is
+// recorded and applied to each connection before it runs a query. On a
+// read-only server the file is attached read-only as well.
+func (s *SQLiteDB) AttachDatabase(alias, path string) error {
+	if !attachAliasPattern.MatchString(alias) {
+		return fmt.Errorf("invalid alias %q, must be letters, digits and underscores", alias)
+	}
+	if strings.EqualFold(alias, "main") || strings.EqualFold(alias, "temp") {
+		return fmt.Errorf("alias '%s' is reserved", alias)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return &NotFoundError{Kind: "database file", Name: path}
+	}
+	if info.IsDir() {
+		return fmt.Errorf("path is a directory: %s", path)
+	}
+
+	s.mu.Lock()
+	for existing := range s.attachments {
+		if strings.EqualFold(existing, alias) {
+			s.mu.Unlock()
+			return &ConflictError{Kind: "attached database", Name: alias}
+		}
+	}
+	s.attachments[alias] = absPath
+	s.mu.Unlock()
+
+	// ATTACH accepts any file, so read its schema to check it's a database
+	ctx := context.Background()
+	err = func() error {
+		conn, err := s.attachedConn(ctx, s.db)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		var tables int
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s.sqlite_master", quoteIdentifier(alias))
+		if err := conn.QueryRowContext(ctx, query).Scan(&tables); err != nil {
+			return fmt.Errorf("failed to attach database: %w", err)
+		}
+		return nil
+	}()
+	if err != nil {
+		s.mu.Lock()
+		delete(s.attachments, alias)
+		s.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// DetachDatabase removes an attachment made with AttachDatabase. Each
+// connection detaches it before its next query.
+func (s *SQLiteDB) DetachDatabase(alias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.attachments[alias]; !ok {
+		return &NotFoundError{Kind: "attached database", Name: alias}
+	}
+	delete(s.attachments, alias)
+	return nil
+}
+
+// AttachedDatabases returns the current attachments ordered by alias.
+func (s *SQLiteDB) AttachedDatabases() []models.AttachedDatabase {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attached := make([]models.AttachedDatabase, 0, len(s.attachments))
+	for alias, path := range s.attachments {
+		attached = append(attached, models.AttachedDatabase{Alias: alias, Path: path})
+	}
+	sort.Slice(attached, func(i, j int) bool { return attached[i].Alias < attached[j].Alias })
+	return attached
+}
+
+// attachedConn takes a connection from the pool and brings its attached
+// databases in line with the recorded attachments. The caller must close it.
+func (s *SQLiteDB) attachedConn(ctx context.Context, pool *sql.DB) (*sql.Conn, error) {
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.syncAttachments(ctx, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (s *SQLiteDB) syncAttachments(ctx context.Context, conn *sql.Conn) error {
+	s.mu.Lock()
+	wanted := make(map[string]string, len(s.attachments))
+	for alias, path := range s.attachments {
+		wanted[alias] = path
+	}
+	s.mu.Unlock()
+
+	rows, err := conn.QueryContext(ctx, "SELECT name, file FROM pragma_database_list WHERE name NOT IN ('main', 'temp')")
+	if err != nil {
+		return fmt.Errorf("failed to list attached databases: %w", err)
+	}
+	current := make(map[string]string)
+	for rows.Next() {
+		var name, file string
+		if err := rows.Scan(&name, &file); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan attached database: %w", err)
+		}
+		current[name] = file
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for name, file := range current {
+		if path, ok := wanted[name]; ok && path == file {
+			delete(wanted, name)
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, "DETACH DATABASE "+quoteIdentifier(name)); err != nil {
+			return fmt.Errorf("failed to detach database '%s': %w", name, err)
+		}
+	}
+	for alias, path := range wanted {
+		source := path
+		if s.readOnly {
+			source = "file:" + (&url.URL{Path: path}).EscapedPath() + "?mode=ro"
+		}
+		if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS "+quoteIdentifier(alias), source); err != nil {
+			return fmt.Errorf("failed to attach database '%s': %w", alias, err)
+		}
+	}
+
+	return nil
+}
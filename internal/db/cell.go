@@ -0,0 +1,213 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"sqliter/internal/models"
+	"strings"
+)
+
+// sqliteMaxLength is SQLite's own maximum length of a string or BLOB, which
+// caps BLOB cell uploads when no smaller limit is given.
+const sqliteMaxLength int64 = 1000000000
+
+// BlobSizeError reports a BLOB cell upload over the size limit.
+type BlobSizeError struct {
+	Column string
+	Limit  int64
+}
+
+func (e *BlobSizeError) Error() string {
+	return fmt.Sprintf("upload for '%s' exceeds the maximum BLOB size of %d bytes", e.Column, e.Limit)
+}
+
+// ColumnTypeError reports a value written to a column of the wrong type.
+type ColumnTypeError struct {
+	Column string
+	Type   string
+	Want   string
+}
+
+func (e *ColumnTypeError) Error() string {
+	return fmt.Sprintf("column '%s' has type '%s', expected %s", e.Column, e.Type, e.Want)
+}
+
+// rowIdentityColumn returns the column that identifies a single row of the
+// table: its single-column primary key, or rowid when the table has none.
+func (s *SQLiteDB) rowIdentityColumn(tableName string, columns []string) (string, error) {
+	schema, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return "", err
+	}
+
+	var primaryKeys []string
+	for _, col := range schema {
+		if col.PrimaryKey {
+			primaryKeys = append(primaryKeys, col.Name)
+		}
+	}
+
+	exists := make(map[string]bool, len(schema))
+	for _, col := range schema {
+		exists[col.Name] = true
+	}
+	for _, col := range columns {
+		if !exists[col] {
+			return "", &NotFoundError{Kind: "column", Name: col}
+		}
+	}
+
+	switch len(primaryKeys) {
+	case 0:
+		return "rowid", nil
+	case 1:
+		return primaryKeys[0], nil
+	default:
+		return "", fmt.Errorf("table '%s' has a composite primary key", tableName)
+	}
+}
+
+// GetCell returns a single cell's value as bytes together with its SQLite
+// storage class (as reported by typeof()). A NULL cell returns nil bytes.
+// Rows outside the table's scopes are reported as not found.
+func (s *SQLiteDB) GetCell(tableName, rowID, column string, scopes ...models.Scope) ([]byte, string, error) {
+	var value bytes.Buffer
+	var storageClass string
+	err := s.WriteCell(tableName, rowID, column, &value, func(class string, size int64) {
+		storageClass = class
+	}, scopes...)
+	if err != nil {
+		return nil, "", err
+	}
+	return value.Bytes(), storageClass, nil
+}
+
+// WriteCell writes a single cell's value to w straight from the row, without
+// copying it into a buffer first. start is called before anything is written
+// with the cell's storage class (as reported by typeof()) and its size in
+// bytes; a NULL cell writes nothing. Rows outside the table's scopes are
+// reported as not found.
+func (s *SQLiteDB) WriteCell(tableName, rowID, column string, w io.Writer, start func(storageClass string, size int64), scopes ...models.Scope) error {
+	keyColumn, err := s.rowIdentityColumn(tableName, []string{column})
+	if err != nil {
+		return err
+	}
+
+	condition := fmt.Sprintf("%s = ?", quoteIdentifier(keyColumn))
+	return s.writeCell(tableName, column, condition, []interface{}{rowID}, rowID, w, start, scopes)
+}
+
+// WriteCellByKey writes a cell like WriteCell, from the row where identifies.
+// where must set the full primary key, so composite keys work too.
+func (s *SQLiteDB) WriteCellByKey(tableName, column string, where map[string]interface{}, w io.Writer, start func(storageClass string, size int64), scopes ...models.Scope) error {
+	columns, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return err
+	}
+	if err := requireColumns(columns, column); err != nil {
+		return err
+	}
+	if err := s.RequirePrimaryKey(tableName, where); err != nil {
+		return err
+	}
+
+	condition, args, err := whereEquals(columns, where)
+	if err != nil {
+		return err
+	}
+	return s.writeCell(tableName, column, condition, args, fmt.Sprint(where), w, start, scopes)
+}
+
+// writeCell streams the cell of the row matching condition for WriteCell and
+// WriteCellByKey; row names the row in a not found error.
+func (s *SQLiteDB) writeCell(tableName, column, condition string, args []interface{}, row string, w io.Writer, start func(storageClass string, size int64), scopes []models.Scope) error {
+	query := fmt.Sprintf("SELECT typeof(%s), %s FROM %s WHERE %s",
+		quoteIdentifier(column), quoteIdentifier(column), quoteIdentifier(tableName), condition)
+	if len(scopes) > 0 {
+		scopeSQL, scopeArgs := scopeCondition(scopes)
+		query += " AND " + scopeSQL
+		args = append(args, scopeArgs...)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to read cell: %w", err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read cell: %w", err)
+		}
+		return &NotFoundError{Kind: "row", Name: row}
+	}
+
+	// RawBytes points into the driver's copy of the value, valid until the
+	// rows are closed
+	var storageClass string
+	var value sql.RawBytes
+	if err := rows.Scan(&storageClass, &value); err != nil {
+		return fmt.Errorf("failed to read cell: %w", err)
+	}
+	start(storageClass, int64(len(value)))
+	if _, err := w.Write(value); err != nil {
+		return fmt.Errorf("failed to write cell: %w", err)
+	}
+	return nil
+}
+
+// WriteBlobCell writes r into a BLOB cell, replacing its value. Uploads over
+// limit bytes are rejected; a non-positive limit means SQLite's own. The
+// driver doesn't expose SQLite's incremental BLOB I/O, so the upload is held
+// in memory and stored with a single UPDATE, and limit bounds that memory.
+// Rows outside the table's scopes are reported as not found.
+func (s *SQLiteDB) WriteBlobCell(tableName, rowID, column string, r io.Reader, limit int64, scopes ...models.Scope) (int64, error) {
+	if limit <= 0 || limit > sqliteMaxLength {
+		limit = sqliteMaxLength
+	}
+
+	keyColumn, err := s.rowIdentityColumn(tableName, []string{column})
+	if err != nil {
+		return 0, err
+	}
+
+	schema, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+	for _, col := range schema {
+		if col.Name == column && !strings.Contains(strings.ToUpper(col.Type), "BLOB") {
+			return 0, &ColumnTypeError{Column: column, Type: col.Type, Want: "BLOB"}
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return 0, &BlobSizeError{Column: column, Limit: limit}
+	}
+
+	where := fmt.Sprintf("%s = ?", quoteIdentifier(keyColumn))
+	args := []interface{}{data, rowID}
+	if len(scopes) > 0 {
+		condition, scopeArgs := scopeCondition(scopes)
+		where += " AND " + condition
+		args = append(args, scopeArgs...)
+	}
+
+	result, err := s.writer.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s",
+		quoteIdentifier(tableName), quoteIdentifier(column), where), args...)
+	if err != nil {
+		return 0, s.parseConstraintError(err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if affected == 0 {
+		return 0, &NotFoundError{Kind: "row", Name: rowID}
+	}
+
+	return int64(len(data)), nil
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"fmt"
+	"sqliter/internal/models"
+)
+
+// GetRowidChunks partitions a table into consecutive rowid ranges of up to
+// size rows each, in rowid order. The ranges don't overlap and together cover
+// every row, so they can be read independently, e.g. in parallel. Views and
+// WITHOUT ROWID tables have no rowid, which is a NotFoundError.
+func (s *SQLiteDB) GetRowidChunks(tableName string, size int, scopes ...models.Scope) ([]models.RowidChunk, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive")
+	}
+
+	if _, err := s.GetTableSchema(tableName); err != nil {
+		return nil, err
+	}
+	var hasRowid bool
+	err := s.db.QueryRow("SELECT type = 'table' AND NOT wr FROM pragma_table_list WHERE schema = 'main' AND name = ?", tableName).Scan(&hasRowid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up table: %w", err)
+	}
+	if !hasRowid {
+		return nil, &NotFoundError{Kind: "column", Name: "rowid"}
+	}
+
+	where := ""
+	var args []interface{}
+	if len(scopes) > 0 {
+		var condition string
+		condition, args = scopeCondition(scopes)
+		where = " WHERE " + condition
+	}
+
+	query := fmt.Sprintf(`SELECT MIN(rowid), MAX(rowid), COUNT(*) FROM (
+		SELECT rowid, (ROW_NUMBER() OVER (ORDER BY rowid) - 1) / %d AS chunk FROM %s%s
+	) GROUP BY chunk ORDER BY chunk`, size, quoteIdentifier(tableName), where)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute rowid chunks: %w", err)
+	}
+	defer rows.Close()
+
+	chunks := []models.RowidChunk{}
+	for rows.Next() {
+		var chunk models.RowidChunk
+		if err := rows.Scan(&chunk.Start, &chunk.End, &chunk.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan rowid chunk: %w", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, rows.Err()
+}
@@ -0,0 +1,265 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sqliter/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvCell renders a scanned value as a CSV field:
+//   - NULL is written as opts.Null
+//   - BLOBs are base64-encoded, or replaced by "<binary N bytes>"
+//   - integers and reals use plain decimal notation, never an exponent
+//   - booleans are written as 1 or 0, like SQLite stores them
+//   - timestamps parsed by the driver are written as RFC 3339
+//   - text is written unchanged
+func csvCell(value interface{}, opts models.CSVOptions) string {
+	switch v := value.(type) {
+	case nil:
+		return opts.Null
+	case []byte:
+		if opts.Binary == models.CSVBinaryPlaceholder {
+			return fmt.Sprintf("<binary %d bytes>", len(v))
+		}
+		return base64.StdEncoding.EncodeToString(v)
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// csvFlushRows is how many rows a CSV export writes between flushes, so large
+// exports reach the client as they are produced.
+const csvFlushRows = 1000
+
+// writeCSVRows writes a header followed by every remaining row of the result
+// set, flushing the writer every csvFlushRows rows.
+func writeCSVRows(rows *sql.Rows, columnNames []string, opts models.CSVOptions, writer *csv.Writer) error {
+	if err := writer.Write(columnNames); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for count := 1; rows.Next(); count++ {
+		values := make([]interface{}, len(columnNames))
+		valuePtrs := make([]interface{}, len(columnNames))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		csvRow := make([]string, len(columnNames))
+		for i, val := range values {
+			csvRow[i] = csvCell(val, opts)
+		}
+
+		if err := writer.Write(csvRow); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+		if count%csvFlushRows == 0 {
+			writer.Flush()
+			if err := writer.Error(); err != nil {
+				return fmt.Errorf("failed to write CSV rows: %w", err)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// CSVImportError lists the rows of a CSV import that could not be inserted.
+// All other rows were imported.
+type CSVImportError struct {
+	Rows []RowError
+}
+
+func (e *CSVImportError) Error() string {
+	return fmt.Sprintf("%d rows failed to import, first %v", len(e.Rows), &e.Rows[0])
+}
+
+// csvValue converts a CSV field to a value for a column of the given declared
+// type, following SQLite's affinity rules. Empty fields are NULL except in
+// text columns, and BLOB columns take base64 as written by the CSV export.
+func csvValue(field, columnType string) (interface{}, error) {
+	upper := strings.ToUpper(columnType)
+	if hasTextAffinity(columnType) {
+		return field, nil
+	}
+	if field == "" {
+		return nil, nil
+	}
+
+	switch {
+	case strings.Contains(upper, "INT"):
+		value, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not an integer", field)
+		}
+		return value, nil
+	case strings.Contains(upper, "BLOB"):
+		value, err := base64.StdEncoding.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("BLOB values must be base64-encoded")
+		}
+		return value, nil
+	case upper == "":
+		return field, nil
+	case strings.Contains(upper, "REAL") || strings.Contains(upper, "FLOA") || strings.Contains(upper, "DOUB"):
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a number", field)
+		}
+		return value, nil
+	default:
+		// NUMERIC affinity keeps values that don't look like numbers as text
+		if value, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64); err == nil {
+			return value, nil
+		}
+		if value, err := strconv.ParseFloat(strings.TrimSpace(field), 64); err == nil {
+			return value, nil
+		}
+		return field, nil
+	}
+}
+
+// csvImportColumns maps the CSV header to the table's columns, matching names
+// case-insensitively. Without a header, fields map to the columns in order.
+func csvImportColumns(schema []models.Column, header []string) ([]models.Column, error) {
+	if header == nil {
+		return schema, nil
+	}
+
+	byName := make(map[string]models.Column, len(schema))
+	for _, col := range schema {
+		byName[strings.ToLower(col.Name)] = col
+	}
+	columns := make([]models.Column, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		col, ok := byName[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, &NotFoundError{Kind: "column", Name: name}
+		}
+		if seen[col.Name] {
+			return nil, fmt.Errorf("column '%s' appears twice in the CSV header", col.Name)
+		}
+		seen[col.Name] = true
+		columns[i] = col
+	}
+	return columns, nil
+}
+
+// ImportTableCSV inserts the rows of a CSV file into an existing table in a
+// single transaction. Fields are mapped by the header row when hasHeader is
+// set, otherwise by column position, and converted to the column types. Rows
+// that can't be converted or inserted are skipped and reported in a
+// CSVImportError together with the number of rows that were imported.
+func (s *SQLiteDB) ImportTableCSV(tableName string, reader io.Reader, hasHeader bool) (int64, error) {
+	schema, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+
+	csvReader := csv.NewReader(reader)
+	var header []string
+	if hasHeader {
+		header, err = csvReader.Read()
+		if err == io.EOF {
+			return 0, fmt.Errorf("the CSV file is empty")
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read CSV header: %w", err)
+		}
+	}
+	columns, err := csvImportColumns(schema, header)
+	if err != nil {
+		return 0, err
+	}
+	csvReader.FieldsPerRecord = len(columns)
+
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.Name
+	}
+	insert, err := s.prepareInsert(tableName, names, false)
+	if err != nil {
+		return 0, err
+	}
+	defer insert.close()
+
+	// A failed INSERT only undoes its own row, so the good rows can still be
+	// committed together
+	var imported int64
+	var failed []RowError
+	for index := 0; ; index++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, csv.ErrFieldCount) {
+			failed = append(failed, RowError{Index: index, Err: fmt.Errorf("expected %d fields, got %d", len(columns), len(record))})
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		values, err := csvRowValues(columns, record)
+		if err != nil {
+			failed = append(failed, RowError{Index: index, Err: err})
+			continue
+		}
+		if _, rowErr := insert.insert(index, values); rowErr != nil {
+			failed = append(failed, *rowErr)
+			continue
+		}
+		imported++
+	}
+
+	if err := insert.commit(); err != nil {
+		return 0, err
+	}
+
+	if len(failed) > 0 {
+		return imported, &CSVImportError{Rows: failed}
+	}
+	return imported, nil
+}
+
+// csvRowValues converts a CSV record to the insert values of its columns.
+func csvRowValues(columns []models.Column, record []string) ([]interface{}, error) {
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		value, err := csvValue(record[i], col.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column '%s': %w", col.Name, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,425 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sqliter/internal/models"
+	"strconv"
+	"strings"
+)
+
+// logicalColumnTypes maps the client-facing logical type names accepted by the
+// DDL builders to the SQLite type keyword that yields the matching affinity.
+var logicalColumnTypes = map[string]string{
+	"string":   "TEXT",
+	"int":      "INTEGER",
+	"float":    "REAL",
+	"bool":     "INTEGER",
+	"datetime": "TEXT",
+	"blob":     "BLOB",
+	"json":     "TEXT",
+}
+
+// rawColumnTypePattern matches SQLite type names such as "VARCHAR(255)" or
+// "DECIMAL(10, 2)" that are passed through as-is.
+var rawColumnTypePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_ ]*(\(\s*[+-]?\d+\s*(,\s*[+-]?\d+\s*)?\))?$`)
+
+// resolveColumnType maps a logical type to its SQLite type, or validates and
+// returns a raw SQLite type string unchanged.
+func resolveColumnType(columnType string) (string, error) {
+	columnType = strings.TrimSpace(columnType)
+	if columnType == "" {
+		return "", nil
+	}
+
+	if sqliteType, ok := logicalColumnTypes[strings.ToLower(columnType)]; ok {
+		return sqliteType, nil
+	}
+
+	if !rawColumnTypePattern.MatchString(columnType) {
+		return "", fmt.Errorf("invalid column type: %s", columnType)
+	}
+
+	return columnType, nil
+}
+
+// defaultValueSQL renders a column default as a SQL literal. Numbers, NULL and
+// the CURRENT_* keywords are emitted verbatim, well-formed string literals are
+// kept, and anything else is quoted as a string.
+func defaultValueSQL(value string) string {
+	switch strings.ToUpper(value) {
+	case "NULL", "TRUE", "FALSE", "CURRENT_TIMESTAMP", "CURRENT_DATE", "CURRENT_TIME":
+		return strings.ToUpper(value)
+	}
+
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+
+	if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") &&
+		!strings.Contains(strings.ReplaceAll(value[1:len(value)-1], "''", ""), "'") {
+		return value
+	}
+
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// columnDefinitionSQL builds the column definition clause used by CREATE TABLE
+// and ALTER TABLE ADD COLUMN. inlinePrimaryKey controls whether a primary key
+// column gets an inline PRIMARY KEY constraint.
+func columnDefinitionSQL(col models.Column, inlinePrimaryKey bool) (string, error) {
+	if strings.TrimSpace(col.Name) == "" {
+		return "", fmt.Errorf("column name is required")
+	}
+
+	columnType, err := resolveColumnType(col.Type)
+	if err != nil {
+		return "", err
+	}
+
+	parts := []string{quoteIdentifier(col.Name)}
+	if columnType != "" {
+		parts = append(parts, columnType)
+	}
+	if col.PrimaryKey && inlinePrimaryKey {
+		parts = append(parts, "PRIMARY KEY")
+	}
+	if col.NotNull {
+		parts = append(parts, "NOT NULL")
+	}
+	if col.Unique {
+		parts = append(parts, "UNIQUE")
+	}
+	if col.DefaultValue != nil {
+		parts = append(parts, "DEFAULT "+defaultValueSQL(*col.DefaultValue))
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+func (s *SQLiteDB) CreateTable(tableName string, columns []models.Column) error {
+	if strings.TrimSpace(tableName) == "" {
+		return fmt.Errorf("table name is required")
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("at least one column is required")
+	}
+
+	// SQLite column names are case-insensitive
+	seen := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		name := strings.ToLower(col.Name)
+		if seen[name] {
+			return fmt.Errorf("duplicate column name: %s", col.Name)
+		}
+		seen[name] = true
+	}
+
+	var primaryKeys []string
+	for _, col := range columns {
+		if col.PrimaryKey {
+			primaryKeys = append(primaryKeys, quoteIdentifier(col.Name))
+		}
+	}
+
+	// A composite primary key has to be declared as a table constraint
+	inlinePrimaryKey := len(primaryKeys) == 1
+
+	definitions := make([]string, 0, len(columns)+1)
+	for _, col := range columns {
+		definition, err := columnDefinitionSQL(col, inlinePrimaryKey)
+		if err != nil {
+			return err
+		}
+		definitions = append(definitions, definition)
+	}
+	if len(primaryKeys) > 1 {
+		definitions = append(definitions, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKeys, ", ")))
+	}
+
+	query := fmt.Sprintf("CREATE TABLE %s (%s)", quoteIdentifier(tableName), strings.Join(definitions, ", "))
+	if _, err := s.writer.Exec(query); err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteDB) AddColumn(tableName string, col models.Column) error {
+	if col.PrimaryKey {
+		return fmt.Errorf("cannot add a primary key column to an existing table")
+	}
+	// Existing rows get the default, so a NOT NULL column needs a non-NULL one
+	if col.NotNull && (col.DefaultValue == nil || strings.EqualFold(strings.TrimSpace(*col.DefaultValue), "NULL")) {
+		return fmt.Errorf("cannot add NOT NULL column '%s' without a non-NULL default value", col.Name)
+	}
+
+	if _, err := s.GetTableSchema(tableName); err != nil {
+		return err
+	}
+
+	definition, err := columnDefinitionSQL(col, false)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteIdentifier(tableName), definition)
+	if _, err := s.writer.Exec(query); err != nil {
+		return fmt.Errorf("failed to add column: %w", err)
+	}
+
+	return nil
+}
+
+// NotFoundError reports that a schema object referenced by name does not exist.
+type NotFoundError struct {
+	Kind string
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s '%s' does not exist", e.Kind, e.Name)
+}
+
+// ConflictError reports that a schema object would collide with an existing one.
+type ConflictError struct {
+	Kind string
+	Name string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s '%s' already exists", e.Kind, e.Name)
+}
+
+// renameColumnVersion is the first SQLite release with ALTER TABLE RENAME COLUMN.
+const renameColumnVersion = "3.25.0"
+
+// versionAtLeast compares dotted version numbers such as "3.45.1".
+func versionAtLeast(version, minimum string) bool {
+	have := strings.Split(version, ".")
+	want := strings.Split(minimum, ".")
+	for i := range want {
+		var h, w int
+		if i < len(have) {
+			h, _ = strconv.Atoi(have[i])
+		}
+		w, _ = strconv.Atoi(want[i])
+		if h != w {
+			return h > w
+		}
+	}
+	return true
+}
+
+// RenameTable renames a table, failing if any schema object already uses the
+// new name. SQLite compares names case-insensitively.
+func (s *SQLiteDB) RenameTable(oldName, newName string) error {
+	if strings.TrimSpace(newName) == "" {
+		return fmt.Errorf("new table name is required")
+	}
+	if strings.HasPrefix(oldName, internalTablePrefix) {
+		return &NotFoundError{Kind: "table", Name: oldName}
+	}
+	if strings.HasPrefix(newName, internalTablePrefix) {
+		return fmt.Errorf("table names starting with %s are reserved", internalTablePrefix)
+	}
+
+	exists, err := s.schemaObjectExists("table", oldName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &NotFoundError{Kind: "table", Name: oldName}
+	}
+
+	var taken int
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE name = ? COLLATE NOCASE AND name != ?`
+	if err := s.db.QueryRow(query, newName, oldName).Scan(&taken); err != nil {
+		return fmt.Errorf("failed to look up table: %w", err)
+	}
+	if taken > 0 {
+		return &ConflictError{Kind: "table", Name: newName}
+	}
+
+	query = fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteIdentifier(oldName), quoteIdentifier(newName))
+	if _, err := s.writer.Exec(query); err != nil {
+		return fmt.Errorf("failed to rename table: %w", err)
+	}
+
+	return nil
+}
+
+// RenameColumn renames a column of a table. It needs SQLite 3.25.0 or later.
+func (s *SQLiteDB) RenameColumn(tableName, oldName, newName string) error {
+	if strings.TrimSpace(newName) == "" {
+		return fmt.Errorf("new column name is required")
+	}
+
+	version, err := s.SQLiteVersion()
+	if err != nil {
+		return err
+	}
+	if !versionAtLeast(version, renameColumnVersion) {
+		return fmt.Errorf("renaming columns requires SQLite %s or later, but this build uses %s", renameColumnVersion, version)
+	}
+
+	columns, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, col := range columns {
+		if col.Name == oldName {
+			found = true
+		} else if strings.EqualFold(col.Name, newName) {
+			return &ConflictError{Kind: "column", Name: newName}
+		}
+	}
+	if !found {
+		return &NotFoundError{Kind: "column", Name: oldName}
+	}
+
+	query := fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s",
+		quoteIdentifier(tableName), quoteIdentifier(oldName), quoteIdentifier(newName))
+	if _, err := s.writer.Exec(query); err != nil {
+		return fmt.Errorf("failed to rename column: %w", err)
+	}
+
+	return nil
+}
+
+// schemaObjectExists reports whether an object of the given sqlite_master type exists.
+func (s *SQLiteDB) schemaObjectExists(objectType, name string) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = ? AND name = ?`
+	if err := s.db.QueryRow(query, objectType, name).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to look up %s: %w", objectType, err)
+	}
+	return count > 0, nil
+}
+
+func (s *SQLiteDB) CreateView(viewName, selectSQL string) error {
+	if strings.TrimSpace(viewName) == "" {
+		return fmt.Errorf("view name is required")
+	}
+
+	// Only a single SELECT statement may follow CREATE VIEW ... AS
+	selectSQL = strings.TrimRight(strings.TrimSpace(selectSQL), "; \t\r\n")
+	normalized := strings.ToUpper(selectSQL)
+	if !strings.HasPrefix(normalized, "SELECT") && !strings.HasPrefix(normalized, "WITH") {
+		return fmt.Errorf("view definition must be a SELECT statement")
+	}
+	if len(splitStatements(selectSQL)) != 1 {
+		return fmt.Errorf("view definition must be a single SELECT statement")
+	}
+
+	query := fmt.Sprintf("CREATE VIEW %s AS %s", quoteIdentifier(viewName), selectSQL)
+	if _, err := s.writer.Exec(query); err != nil {
+		return fmt.Errorf("failed to create view: %w", err)
+	}
+
+	return nil
+}
+
+// GetViews returns the database's views with their defining SQL, ordered by
+// name.
+func (s *SQLiteDB) GetViews() ([]models.View, error) {
+	rows, err := s.db.Query(`SELECT name, sql FROM sqlite_master WHERE type = 'view' ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []models.View{}
+	for rows.Next() {
+		var view models.View
+		if err := rows.Scan(&view.Name, &view.SQL); err != nil {
+			return nil, fmt.Errorf("failed to scan view row: %w", err)
+		}
+		views = append(views, view)
+	}
+
+	return views, rows.Err()
+}
+
+// viewDefinition returns the CREATE VIEW statement of a view, or "" when the
+// name isn't a view.
+func viewDefinition(qr queryer, name string) (string, error) {
+	var definition string
+	err := qr.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'view' AND name = ?`, name).Scan(&definition)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up view: %w", err)
+	}
+	return definition, nil
+}
+
+func (s *SQLiteDB) DropView(viewName string) error {
+	return s.dropSchemaObject("view", viewName)
+}
+
+// DropTable drops a user table. SQLiter's internal tables are hidden from
+// the table list and reported as missing.
+func (s *SQLiteDB) DropTable(tableName string) error {
+	if strings.HasPrefix(tableName, internalTablePrefix) {
+		return &NotFoundError{Kind: "table", Name: tableName}
+	}
+	return s.dropSchemaObject("table", tableName)
+}
+
+// GetTriggers returns the triggers on a table, or on every table and view
+// when tableName is empty, ordered by name.
+func (s *SQLiteDB) GetTriggers(tableName string) ([]models.Trigger, error) {
+	query := `SELECT name, tbl_name, sql FROM sqlite_master WHERE type = 'trigger'`
+	var args []interface{}
+	if tableName != "" {
+		if err := requireTable(s.db, tableName); err != nil {
+			return nil, err
+		}
+		query += ` AND tbl_name = ?`
+		args = append(args, tableName)
+	}
+	query += ` ORDER BY name`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query triggers: %w", err)
+	}
+	defer rows.Close()
+
+	triggers := []models.Trigger{}
+	for rows.Next() {
+		var trigger models.Trigger
+		if err := rows.Scan(&trigger.Name, &trigger.Table, &trigger.SQL); err != nil {
+			return nil, fmt.Errorf("failed to scan trigger row: %w", err)
+		}
+		triggers = append(triggers, trigger)
+	}
+
+	return triggers, rows.Err()
+}
+
+func (s *SQLiteDB) DropTrigger(triggerName string) error {
+	return s.dropSchemaObject("trigger", triggerName)
+}
+
+func (s *SQLiteDB) dropSchemaObject(objectType, name string) error {
+	exists, err := s.schemaObjectExists(objectType, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &NotFoundError{Kind: objectType, Name: name}
+	}
+
+	query := fmt.Sprintf("DROP %s %s", strings.ToUpper(objectType), quoteIdentifier(name))
+	if _, err := s.writer.Exec(query); err != nil {
+		return fmt.Errorf("failed to drop %s: %w", objectType, err)
+	}
+
+	return nil
+}
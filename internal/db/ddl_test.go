@@ -0,0 +1,174 @@
+package db
+
+import (
+	"os"
+	"reflect"
+	"sqliter/internal/models"
+	"testing"
+)
+
+func setupEmptyDB(t testing.TB) *SQLiteDB {
+	tmpfile, err := os.CreateTemp("", "test*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpfile.Close()
+	t.Cleanup(func() { os.Remove(tmpfile.Name()) })
+
+	database, err := NewSQLiteDB(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	return database
+}
+
+func TestCreateTableLogicalTypes(t *testing.T) {
+	database := setupEmptyDB(t)
+
+	columns := []models.Column{
+		{Name: "id", Type: "int", PrimaryKey: true},
+		{Name: "name", Type: "string", NotNull: true},
+		{Name: "score", Type: "float"},
+		{Name: "active", Type: "bool"},
+		{Name: "created_at", Type: "datetime"},
+		{Name: "avatar", Type: "blob"},
+		{Name: "meta", Type: "json"},
+		{Name: "code", Type: "VARCHAR(16)"},
+	}
+	if err := database.CreateTable("things", columns); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := database.GetTableSchema("things")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"id":         "INTEGER",
+		"name":       "TEXT",
+		"score":      "REAL",
+		"active":     "INTEGER",
+		"created_at": "TEXT",
+		"avatar":     "BLOB",
+		"meta":       "TEXT",
+		"code":       "VARCHAR(16)",
+	}
+	if len(schema) != len(expected) {
+		t.Fatalf("Expected %d columns, got %d", len(expected), len(schema))
+	}
+	for _, col := range schema {
+		if col.Type != expected[col.Name] {
+			t.Errorf("Expected column '%s' to have type %s, got %s", col.Name, expected[col.Name], col.Type)
+		}
+	}
+}
+
+func TestCreateTableRejectsInvalidType(t *testing.T) {
+	database := setupEmptyDB(t)
+
+	columns := []models.Column{
+		{Name: "id", Type: "INTEGER); DROP TABLE x; --"},
+	}
+	if err := database.CreateTable("bad", columns); err == nil {
+		t.Error("Expected an error for an invalid column type")
+	}
+}
+
+func TestAddColumnLogicalType(t *testing.T) {
+	database := setupEmptyDB(t)
+
+	if err := database.CreateTable("things", []models.Column{{Name: "id", Type: "int", PrimaryKey: true}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AddColumn("things", models.Column{Name: "price", Type: "float"}); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := database.GetTableSchema("things")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schema) != 2 || schema[1].Name != "price" || schema[1].Type != "REAL" {
+		t.Errorf("Expected added column 'price' of type REAL, got %+v", schema)
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version, minimum string
+		want             bool
+	}{
+		{"3.25.0", "3.25.0", true},
+		{"3.45.1", "3.25.0", true},
+		{"3.24.9", "3.25.0", false},
+		{"3.9.2", "3.25.0", false},
+		{"4.0", "3.25.0", true},
+	}
+	for _, tt := range tests {
+		if got := versionAtLeast(tt.version, tt.minimum); got != tt.want {
+			t.Errorf("versionAtLeast(%q, %q) = %v, want %v", tt.version, tt.minimum, got, tt.want)
+		}
+	}
+}
+
+func TestIndexes(t *testing.T) {
+	database := setupEmptyDB(t)
+	if _, err := database.ExecuteSQLScript(`
+		CREATE TABLE items (id INTEGER PRIMARY KEY, sku TEXT UNIQUE, name TEXT, "where" TEXT, deleted INTEGER);
+		CREATE INDEX items_lower_name ON items (lower(name));
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := database.CreateIndex("items", models.CreateIndexRequest{
+		Columns: []string{"where", "name"},
+		Unique:  true,
+		Where:   "deleted = 0 AND name != 'WHERE'",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.Index{
+		Name:    "idx_items_where_name",
+		Columns: []string{"where", "name"},
+		Unique:  true,
+		Origin:  "c",
+		Partial: true,
+		Where:   "deleted = 0 AND name != 'WHERE'",
+	}
+	if !reflect.DeepEqual(*created, want) {
+		t.Errorf("Expected %+v, got %+v", want, *created)
+	}
+
+	indexes, err := database.GetIndexes("items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != 3 {
+		t.Fatalf("Expected 3 indexes, got %+v", indexes)
+	}
+	if got := indexes[1]; got.Name != "items_lower_name" || got.Columns[0] != "<expression>" || got.Unique || got.Partial {
+		t.Errorf("Unexpected expression index %+v", got)
+	}
+	if got := indexes[2]; got.Origin != "u" || !got.Unique || got.Columns[0] != "sku" {
+		t.Errorf("Unexpected constraint index %+v", got)
+	}
+
+	failures := []models.CreateIndexRequest{
+		{},
+		{Columns: []string{"missing"}},
+		{Name: "items_lower_name", Columns: []string{"name"}},
+		{Columns: []string{"sku"}, Where: "1; DROP TABLE items"},
+	}
+	for _, req := range failures {
+		if _, err := database.CreateIndex("items", req); err == nil {
+			t.Errorf("Expected %+v to be rejected", req)
+		}
+	}
+	if _, err := database.GetTableSchema("items"); err != nil {
+		t.Errorf("Expected the table to survive, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"fmt"
+	"sqliter/internal/models"
+)
+
+// MaxDistinctValues caps the number of distinct values returned for a
+// column, so high-cardinality columns don't produce huge lists.
+const MaxDistinctValues = 1000
+
+// GetColumnDistinctValues returns up to limit distinct values of a column in
+// ascending order, NULL first, for populating filters. The limit is capped at
+// MaxDistinctValues and scopes restrict the rows the values come from.
+func (s *SQLiteDB) GetColumnDistinctValues(tableName, column string, limit int, scopes ...models.Scope) ([]interface{}, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+	if limit > MaxDistinctValues {
+		limit = MaxDistinctValues
+	}
+
+	columns, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireColumns(columns, column); err != nil {
+		return nil, err
+	}
+
+	source, args := scopedSource(tableName, scopes)
+	query := fmt.Sprintf("SELECT DISTINCT %s FROM %s ORDER BY 1 LIMIT ?", quoteIdentifier(column), source)
+	rows, err := s.db.Query(query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query distinct values: %w", err)
+	}
+	defer rows.Close()
+
+	values := []interface{}{}
+	for rows.Next() {
+		var value interface{}
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to scan distinct value: %w", err)
+		}
+		values = append(values, models.JSONValue(value))
+	}
+
+	return values, rows.Err()
+}
@@ -0,0 +1,228 @@
+package db
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"sqliter/internal/models"
+	"strings"
+)
+
+// GenerateInsertSQL renders the selected rows as INSERT statements that can be
+// replayed into another database. Rows are chosen by primary key (or rowid)
+// and/or structured filters. Values are rendered with SQLite's quote(), so
+// text is escaped, BLOBs become X'..' literals and reals keep full precision.
+func (s *SQLiteDB) GenerateInsertSQL(tableName string, ids []interface{}, filters []models.Filter, scopes ...models.Scope) (string, int, error) {
+	if len(ids) == 0 && len(filters) == 0 {
+		return "", 0, fmt.Errorf("ids or filters are required")
+	}
+
+	columns, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return "", 0, err
+	}
+
+	names := make([]string, len(columns))
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = quoteIdentifier(col.Name)
+		quoted[i] = "quote(" + quoteIdentifier(col.Name) + ")"
+	}
+
+	var conditions []string
+	var args []interface{}
+	orderBy := ""
+	if len(ids) > 0 {
+		keyColumn, err := s.rowIdentityColumn(tableName, nil)
+		if err != nil {
+			return "", 0, err
+		}
+		orderBy = " ORDER BY " + quoteIdentifier(keyColumn)
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+		conditions = append(conditions, fmt.Sprintf("%s IN (%s)", quoteIdentifier(keyColumn), placeholders))
+		args = append(args, ids...)
+	}
+	if len(filters) > 0 {
+		condition, filterArgs, err := filtersCondition(columns, filters)
+		if err != nil {
+			return "", 0, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, filterArgs...)
+	}
+	if len(scopes) > 0 {
+		condition, scopeArgs := scopeCondition(scopes)
+		conditions = append(conditions, condition)
+		args = append(args, scopeArgs...)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s%s",
+		strings.Join(quoted, ", "), quoteIdentifier(tableName), strings.Join(conditions, " AND "), orderBy)
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to query rows: %w", err)
+	}
+	defer rows.Close()
+
+	var b strings.Builder
+	count, err := writeInsertStatements(&b, rows, tableName, names)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return b.String(), count, nil
+}
+
+// writeInsertStatements writes an INSERT for each row of a query selecting
+// quote() of the named columns, and returns the number of rows written.
+func writeInsertStatements(w io.StringWriter, rows *sql.Rows, tableName string, quotedNames []string) (int, error) {
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES (", quoteIdentifier(tableName), strings.Join(quotedNames, ", "))
+	literals := make([]string, len(quotedNames))
+	ptrs := make([]interface{}, len(quotedNames))
+	for i := range literals {
+		ptrs[i] = &literals[i]
+	}
+
+	count := 0
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return count, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		w.WriteString(prefix)
+		w.WriteString(strings.Join(literals, ", "))
+		if _, err := w.WriteString(");\n"); err != nil {
+			return count, fmt.Errorf("failed to write row: %w", err)
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return count, nil
+}
+
+// schemaEntry is an object of sqlite_master that a dump recreates.
+type schemaEntry struct {
+	Type, Name, SQL string
+}
+
+// DumpSQL writes the whole database as a SQL script that recreates it: the
+// tables with their rows, then views, indexes and triggers, so triggers don't
+// fire while the rows are loaded. Values are rendered with quote(), so text is
+// escaped and BLOBs become X'..' literals. Everything is read in a single
+// transaction and the script runs in one as well. Tables listed in scopes only
+// have their matching rows dumped.
+func (s *SQLiteDB) DumpSQL(w io.Writer, scopes map[string][]models.Scope) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin dump transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	entries, err := dumpSchemaEntries(tx)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	out.WriteString("PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\n")
+
+	// FTS tables with external content are rebuilt once their content is loaded
+	var rebuilds []string
+	for _, entry := range entries {
+		if entry.Type != "table" {
+			continue
+		}
+		if entry.Name == "sqlite_sequence" {
+			// Created along with the first AUTOINCREMENT table; only its rows are restored
+			out.WriteString("DELETE FROM sqlite_sequence;\n")
+		} else {
+			out.WriteString(entry.SQL + ";\n")
+		}
+
+		if match := ftsModulePattern.FindStringSubmatch(entry.SQL); match != nil {
+			if content, ok := ftsContentOption(match[1]); ok {
+				if content != "" {
+					rebuilds = append(rebuilds, entry.Name)
+				}
+				continue
+			}
+		}
+		if err := s.dumpTableRows(tx, out, entry.Name, scopes[entry.Name]); err != nil {
+			return err
+		}
+	}
+	for _, name := range rebuilds {
+		fmt.Fprintf(out, "INSERT INTO %s(%s) VALUES ('rebuild');\n", quoteIdentifier(name), quoteIdentifier(name))
+	}
+
+	for _, kind := range []string{"view", "index", "trigger"} {
+		for _, entry := range entries {
+			if entry.Type == kind {
+				out.WriteString(entry.SQL + ";\n")
+			}
+		}
+	}
+
+	out.WriteString("COMMIT;\n")
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write dump: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// dumpSchemaEntries lists the schema objects to dump in creation order. The
+// shadow tables holding virtual table data are left out, since the virtual
+// tables recreate them, as are automatic indexes, statistics tables and
+// SQLiter's own tables.
+func dumpSchemaEntries(qr queryer) ([]schemaEntry, error) {
+	rows, err := qr.Query(`SELECT type, name, sql FROM sqlite_master
+		WHERE sql IS NOT NULL
+		AND name NOT IN (SELECT name FROM pragma_table_list WHERE schema = 'main' AND type = 'shadow')
+		AND name NOT LIKE 'sqlite_stat%'
+		AND substr(tbl_name, 1, ?) != ?
+		ORDER BY rowid`, len(internalTablePrefix), internalTablePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schema: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []schemaEntry
+	for rows.Next() {
+		var entry schemaEntry
+		if err := rows.Scan(&entry.Type, &entry.Name, &entry.SQL); err != nil {
+			return nil, fmt.Errorf("failed to scan schema: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// dumpTableRows writes an INSERT for every row of the table within its scopes.
+func (s *SQLiteDB) dumpTableRows(qr queryer, out io.StringWriter, tableName string, scopes []models.Scope) error {
+	columns, err := s.getTableSchema(qr, tableName)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(columns))
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = quoteIdentifier(col.Name)
+		quoted[i] = "quote(" + quoteIdentifier(col.Name) + ")"
+	}
+
+	source, args := scopedSource(tableName, scopes)
+	rows, err := qr.Query(fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoted, ", "), source), args...)
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	_, err = writeInsertStatements(out, rows, tableName, names)
+	return err
+}
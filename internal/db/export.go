@@ -0,0 +1,82 @@
+package db
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sqliter/internal/models"
+)
+
+// ExportTableJSON streams the table to w as a JSON array of row objects whose
+// keys follow the column order. Rows are written as they are scanned, so the
+// result is never held in memory. Filtering and sorting are validated before
+// anything is written; a failure after that leaves the array unterminated.
+// BLOBs are base64-encoded like in the CSV export.
+func (s *SQLiteDB) ExportTableJSON(tableName string, q models.TableQuery, w io.Writer) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin export transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := s.queryTableExport(tx, tableName, q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get column names: %w", err)
+	}
+	keys := make([][]byte, len(columnNames))
+	for i, name := range columnNames {
+		if keys[i], err = json.Marshal(name); err != nil {
+			return fmt.Errorf("failed to encode column name: %w", err)
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	out.WriteByte('[')
+	values := make([]interface{}, len(columnNames))
+	valuePtrs := make([]interface{}, len(columnNames))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	for first := true; rows.Next(); first = false {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if !first {
+			out.WriteByte(',')
+		}
+		out.WriteString("\n{")
+		for i, value := range values {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("failed to encode column '%s': %w", columnNames[i], err)
+			}
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			out.Write(keys[i])
+			out.WriteByte(':')
+			out.Write(encoded)
+		}
+		if _, err := out.WriteString("}"); err != nil {
+			return fmt.Errorf("failed to write row: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+	out.WriteString("\n]\n")
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write rows: %w", err)
+	}
+
+	rows.Close()
+	return tx.Commit()
+}
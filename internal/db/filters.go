@@ -0,0 +1,65 @@
+package db
+
+import (
+	"fmt"
+	"sqliter/internal/models"
+	"strings"
+)
+
+// filterOperators is the allowlist of structured filter operators.
+var filterOperators = map[string]bool{
+	"=": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"LIKE": true, "IN": true, "IS NULL": true,
+}
+
+// FilterError reports a structured filter that can't be applied.
+type FilterError struct {
+	Column string
+	Reason string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("invalid filter on '%s': %s", e.Column, e.Reason)
+}
+
+// filtersCondition renders structured filters as an AND-ed condition with
+// every value bound as a parameter. Columns are checked against the schema.
+func filtersCondition(columns []models.Column, filters []models.Filter) (string, []interface{}, error) {
+	conditions := make([]string, 0, len(filters))
+	var args []interface{}
+
+	for _, f := range filters {
+		if err := requireColumns(columns, f.Column); err != nil {
+			return "", nil, err
+		}
+		op := strings.ToUpper(strings.TrimSpace(f.Op))
+		if !filterOperators[op] {
+			return "", nil, &FilterError{Column: f.Column, Reason: fmt.Sprintf("unsupported operator %q", f.Op)}
+		}
+		column := quoteIdentifier(f.Column)
+
+		switch op {
+		case "IS NULL":
+			conditions = append(conditions, column+" IS NULL")
+		case "IN":
+			values, ok := f.Value.([]interface{})
+			if !ok || len(values) == 0 {
+				return "", nil, &FilterError{Column: f.Column, Reason: "IN requires a non-empty list of values"}
+			}
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+			conditions = append(conditions, fmt.Sprintf("%s IN (%s)", column, placeholders))
+			args = append(args, values...)
+		default:
+			if f.Value == nil {
+				return "", nil, &FilterError{Column: f.Column, Reason: op + " requires a value"}
+			}
+			if _, isList := f.Value.([]interface{}); isList {
+				return "", nil, &FilterError{Column: f.Column, Reason: op + " requires a single value"}
+			}
+			conditions = append(conditions, fmt.Sprintf("%s %s ?", column, op))
+			args = append(args, f.Value)
+		}
+	}
+
+	return strings.Join(conditions, " AND "), args, nil
+}
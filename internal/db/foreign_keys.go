@@ -0,0 +1,204 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sqliter/internal/models"
+	"strings"
+)
+
+// GetForeignKeys returns the table's foreign key columns with their
+// referenced table and column and their ON UPDATE/ON DELETE actions.
+func (s *SQLiteDB) GetForeignKeys(tableName string) ([]models.ForeignKey, error) {
+	if err := requireTable(s.db, tableName); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("PRAGMA foreign_key_list(%s)", quoteIdentifier(tableName))
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	foreignKeys := []models.ForeignKey{}
+	for rows.Next() {
+		var fk models.ForeignKey
+		var to sql.NullString
+		var match string
+
+		if err := rows.Scan(&fk.ID, &fk.Seq, &fk.ReferencedTable, &fk.Column, &to, &fk.OnUpdate, &fk.OnDelete, &match); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key row: %w", err)
+		}
+		fk.ReferencedColumn = to.String
+		foreignKeys = append(foreignKeys, fk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read foreign keys: %w", err)
+	}
+
+	// A foreign key without explicit target columns references the primary
+	// key, column by column
+	for i := range foreignKeys {
+		if foreignKeys[i].ReferencedColumn != "" {
+			continue
+		}
+		keys, err := primaryKeyColumns(s.db, foreignKeys[i].ReferencedTable)
+		if err != nil {
+			return nil, err
+		}
+		if seq := foreignKeys[i].Seq; seq < len(keys) {
+			foreignKeys[i].ReferencedColumn = keys[seq]
+		}
+	}
+
+	return foreignKeys, nil
+}
+
+// GetRowByForeignKey fetches the row of the referenced table that a value of
+// a foreign key column points to, within that table's scopes. Composite
+// foreign keys can't be followed by a single value and are rejected.
+func (s *SQLiteDB) GetRowByForeignKey(fromTable, fromColumn string, value interface{}, scopes map[string][]models.Scope) (*models.TableData, error) {
+	foreignKeys, err := s.GetForeignKeys(fromTable)
+	if err != nil {
+		return nil, err
+	}
+
+	var fk *models.ForeignKey
+	for i := range foreignKeys {
+		if foreignKeys[i].Column == fromColumn {
+			fk = &foreignKeys[i]
+			break
+		}
+	}
+	if fk == nil {
+		return nil, fmt.Errorf("column '%s' is not a foreign key", fromColumn)
+	}
+	for _, other := range foreignKeys {
+		if other.ID == fk.ID && other.Seq != fk.Seq {
+			return nil, fmt.Errorf("column '%s' is part of a composite foreign key, which can't be followed yet", fromColumn)
+		}
+	}
+
+	data, err := s.GetTableData(fk.ReferencedTable, models.TableQuery{
+		Limit:   1,
+		Filters: []models.Filter{{Column: fk.ReferencedColumn, Op: "=", Value: value}},
+		Scopes:  scopes[fk.ReferencedTable],
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(data.Rows) == 0 {
+		return nil, &NotFoundError{Kind: "row", Name: fmt.Sprint(value)}
+	}
+
+	return data, nil
+}
+
+// ExpandForeignKeyLabels adds a "<column>__label" field to every row for each
+// entry in expansions (foreign key column -> display column of the referenced
+// table), looked up from the referenced table.
+func (s *SQLiteDB) ExpandForeignKeyLabels(tableName string, rows []models.Row, expansions map[string]string) error {
+	if len(expansions) == 0 {
+		return nil
+	}
+
+	foreignKeys, err := s.GetForeignKeys(tableName)
+	if err != nil {
+		return err
+	}
+
+	for column, labelColumn := range expansions {
+		var fk *models.ForeignKey
+		for i := range foreignKeys {
+			if foreignKeys[i].Column == column {
+				fk = &foreignKeys[i]
+				break
+			}
+		}
+		if fk == nil {
+			return fmt.Errorf("column '%s' is not a foreign key", column)
+		}
+
+		referencedColumns, err := s.GetTableSchema(fk.ReferencedTable)
+		if err != nil {
+			return err
+		}
+		labelExists := false
+		for _, col := range referencedColumns {
+			if col.Name == labelColumn {
+				labelExists = true
+				break
+			}
+		}
+		if !labelExists {
+			return fmt.Errorf("invalid label column '%s' for table '%s'", labelColumn, fk.ReferencedTable)
+		}
+
+		labels, err := s.lookupLabels(fk, labelColumn, rows)
+		if err != nil {
+			return err
+		}
+
+		labelField := column + "__label"
+		for _, row := range rows {
+			if val := row[column]; val != nil {
+				row[labelField] = labels[fmt.Sprint(val)]
+			} else {
+				row[labelField] = nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// lookupLabels fetches the label for every distinct foreign key value in rows,
+// keyed by the value's string form.
+func (s *SQLiteDB) lookupLabels(fk *models.ForeignKey, labelColumn string, rows []models.Row) (map[string]interface{}, error) {
+	labels := make(map[string]interface{})
+
+	seen := make(map[string]bool)
+	var keys []interface{}
+	for _, row := range rows {
+		val := row[fk.Column]
+		if val == nil || seen[fmt.Sprint(val)] {
+			continue
+		}
+		seen[fmt.Sprint(val)] = true
+		keys = append(keys, val)
+	}
+	if len(keys) == 0 {
+		return labels, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+	query := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN (%s)",
+		quoteIdentifier(fk.ReferencedColumn),
+		quoteIdentifier(labelColumn),
+		quoteIdentifier(fk.ReferencedTable),
+		quoteIdentifier(fk.ReferencedColumn),
+		placeholders)
+
+	labelRows, err := s.db.Query(query, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up labels: %w", err)
+	}
+	defer labelRows.Close()
+
+	for labelRows.Next() {
+		var key, label interface{}
+		if err := labelRows.Scan(&key, &label); err != nil {
+			return nil, fmt.Errorf("failed to scan label row: %w", err)
+		}
+		if b, ok := label.([]byte); ok {
+			label = string(b)
+		}
+		if b, ok := key.([]byte); ok {
+			key = string(b)
+		}
+		labels[fmt.Sprint(key)] = label
+	}
+
+	return labels, labelRows.Err()
+}
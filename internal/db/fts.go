@@ -0,0 +1,350 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sqliter/internal/models"
+	"strings"
+)
+
+// ftsModulePattern captures the argument list of a CREATE VIRTUAL TABLE ...
+// USING fts3/fts4/fts5(...) statement.
+var ftsModulePattern = regexp.MustCompile(`(?is)\bUSING\s+fts[345]\s*\((.*)\)\s*$`)
+
+// unquoteSQL strips one layer of SQL quoting from an identifier or literal.
+func unquoteSQL(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 {
+		switch value[0] {
+		case '"', '\'', '`':
+			if value[len(value)-1] == value[0] {
+				quote := string(value[0])
+				return strings.ReplaceAll(value[1:len(value)-1], quote+quote, quote)
+			}
+		case '[':
+			if value[len(value)-1] == ']' {
+				return value[1 : len(value)-1]
+			}
+		}
+	}
+	return value
+}
+
+// ftsContentOption returns the content= option of an FTS table's argument
+// list, and whether it has one. An empty value marks a contentless table.
+func ftsContentOption(args string) (string, bool) {
+	for _, arg := range strings.Split(args, ",") {
+		if option, value, ok := strings.Cut(arg, "="); ok && strings.EqualFold(strings.TrimSpace(option), "content") {
+			return unquoteSQL(value), true
+		}
+	}
+	return "", false
+}
+
+// ftsIndexedColumns returns the columns of tableName already indexed by an
+// external-content FTS table (one declared with content='tableName').
+func (s *SQLiteDB) ftsIndexedColumns(tableName string) (map[string]bool, error) {
+	rows, err := s.db.Query(`SELECT sql FROM sqlite_master WHERE type = 'table' AND sql LIKE 'CREATE VIRTUAL TABLE%'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list virtual tables: %w", err)
+	}
+	defer rows.Close()
+
+	indexed := make(map[string]bool)
+	for rows.Next() {
+		var createSQL string
+		if err := rows.Scan(&createSQL); err != nil {
+			return nil, fmt.Errorf("failed to scan virtual table: %w", err)
+		}
+
+		match := ftsModulePattern.FindStringSubmatch(createSQL)
+		if match == nil {
+			continue
+		}
+
+		var content string
+		var columns []string
+		for _, arg := range strings.Split(match[1], ",") {
+			if option, value, ok := strings.Cut(arg, "="); ok {
+				if strings.EqualFold(strings.TrimSpace(option), "content") {
+					content = unquoteSQL(value)
+				}
+				continue
+			}
+			// Column arguments may carry options such as UNINDEXED
+			if fields := strings.Fields(arg); len(fields) > 0 {
+				if len(fields) > 1 && strings.EqualFold(fields[1], "UNINDEXED") {
+					continue
+				}
+				columns = append(columns, unquoteSQL(fields[0]))
+			}
+		}
+
+		if !strings.EqualFold(content, tableName) {
+			continue
+		}
+		for _, col := range columns {
+			indexed[col] = true
+		}
+	}
+
+	return indexed, rows.Err()
+}
+
+// GetFTSCandidates lists the text columns of a table that are not yet covered
+// by a full-text index.
+func (s *SQLiteDB) GetFTSCandidates(tableName string) (*models.FTSCandidates, error) {
+	schema, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	indexed, err := s.ftsIndexedColumns(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.FTSCandidates{Table: tableName, Columns: []string{}, Indexed: []string{}}
+	for _, col := range schema {
+		if !hasTextAffinity(col.Type) {
+			continue
+		}
+		if indexed[col.Name] {
+			result.Indexed = append(result.Indexed, col.Name)
+		} else {
+			result.Columns = append(result.Columns, col.Name)
+		}
+	}
+
+	return result, nil
+}
+
+// ftsCommands are the FTS maintenance commands that can be run on an FTS table.
+var ftsCommands = map[string]bool{"rebuild": true, "integrity-check": true}
+
+// NotFTSTableError reports a maintenance command aimed at a table that isn't
+// a full-text index.
+type NotFTSTableError struct {
+	Name string
+}
+
+func (e *NotFTSTableError) Error() string {
+	return fmt.Sprintf("table '%s' is not an FTS table", e.Name)
+}
+
+// FTSCommandError reports an FTS maintenance command that SQLite rejected,
+// such as an integrity check that found the index out of sync.
+type FTSCommandError struct {
+	Command string
+	Err     error
+}
+
+func (e *FTSCommandError) Error() string {
+	return fmt.Sprintf("FTS %s failed: %v", e.Command, e.Err)
+}
+
+func (e *FTSCommandError) Unwrap() error {
+	return e.Err
+}
+
+// IsFTSCommand reports whether command is a supported FTS maintenance command.
+func IsFTSCommand(command string) bool {
+	return ftsCommands[command]
+}
+
+// RunFTSCommand runs an FTS maintenance command, "rebuild" or
+// "integrity-check", through the special INSERT INTO t(t) VALUES(command)
+// statement that FTS tables accept.
+func (s *SQLiteDB) RunFTSCommand(tableName, command string) error {
+	if !IsFTSCommand(command) {
+		return fmt.Errorf("unsupported FTS command %q", command)
+	}
+	if err := requireTable(s.db, tableName); err != nil {
+		return err
+	}
+
+	var createSQL string
+	err := s.db.QueryRow(`SELECT COALESCE(sql, '') FROM sqlite_master WHERE type = 'table' AND name = ?`, tableName).Scan(&createSQL)
+	if err != nil || !ftsModulePattern.MatchString(createSQL) {
+		return &NotFTSTableError{Name: tableName}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s(%s) VALUES (?)", quoteIdentifier(tableName), quoteIdentifier(tableName))
+	if _, err := s.writer.Exec(query, command); err != nil {
+		return &FTSCommandError{Command: command, Err: err}
+	}
+	return nil
+}
+
+// ErrFTS5Unavailable is returned when SQLite was built without FTS5.
+var ErrFTS5Unavailable = errors.New("full-text search needs SQLite's FTS5 module, which this build doesn't include (build with -tags sqlite_fts5)")
+
+// fts5Pattern matches the CREATE VIRTUAL TABLE statement of an FTS5 table.
+var fts5Pattern = regexp.MustCompile(`(?is)\bUSING\s+fts5\s*\(`)
+
+// CreateFTSIndex creates an external-content FTS5 table named <table>_fts
+// over the columns, fills it from the table and adds triggers that keep it in
+// sync with inserts, updates and deletes. It returns the FTS table's name.
+func (s *SQLiteDB) CreateFTSIndex(tableName string, columns []string) (string, error) {
+	if len(columns) == 0 {
+		return "", fmt.Errorf("at least one column is required")
+	}
+	schema, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return "", err
+	}
+	if err := requireColumns(schema, columns...); err != nil {
+		return "", err
+	}
+
+	// External content is looked up by rowid
+	if _, err := s.db.Exec(fmt.Sprintf("SELECT rowid FROM %s LIMIT 0", quoteIdentifier(tableName))); err != nil {
+		return "", fmt.Errorf("table '%s' has no rowid, so it can't have a full-text index", tableName)
+	}
+
+	ftsName := tableName + "_fts"
+	exists, err := s.schemaObjectExists("table", ftsName)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", &ConflictError{Kind: "table", Name: ftsName}
+	}
+
+	quoted := make([]string, len(columns))
+	newValues := make([]string, len(columns))
+	oldValues := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = quoteIdentifier(col)
+		newValues[i] = "new." + quoteIdentifier(col)
+		oldValues[i] = "old." + quoteIdentifier(col)
+	}
+	fts := quoteIdentifier(ftsName)
+	table := quoteIdentifier(tableName)
+	insertNew := fmt.Sprintf("INSERT INTO %s(rowid, %s) VALUES (new.rowid, %s);", fts, strings.Join(quoted, ", "), strings.Join(newValues, ", "))
+	deleteOld := fmt.Sprintf("INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.rowid, %s);", fts, fts, strings.Join(quoted, ", "), strings.Join(oldValues, ", "))
+
+	statements := []string{
+		fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts5(%s, content='%s')", fts, strings.Join(quoted, ", "), strings.ReplaceAll(tableName, "'", "''")),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER INSERT ON %s BEGIN %s END", quoteIdentifier(ftsName+"_ai"), table, insertNew),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER DELETE ON %s BEGIN %s END", quoteIdentifier(ftsName+"_ad"), table, deleteOld),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER UPDATE ON %s BEGIN %s %s END", quoteIdentifier(ftsName+"_au"), table, deleteOld, insertNew),
+		fmt.Sprintf("INSERT INTO %s(%s) VALUES ('rebuild')", fts, fts),
+	}
+
+	tx, err := s.writer.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			if strings.Contains(err.Error(), "no such module: fts5") {
+				return "", ErrFTS5Unavailable
+			}
+			return "", fmt.Errorf("failed to create full-text index: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit full-text index: %w", err)
+	}
+	return ftsName, nil
+}
+
+// ftsTableFor returns the external-content FTS table indexing tableName, and
+// whether it is an FTS5 table. The first one by name wins if there are several.
+func (s *SQLiteDB) ftsTableFor(tableName string) (string, bool, error) {
+	rows, err := s.db.Query(`SELECT name, sql FROM sqlite_master WHERE type = 'table' AND sql LIKE 'CREATE VIRTUAL TABLE%' ORDER BY name`)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to list virtual tables: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, createSQL string
+		if err := rows.Scan(&name, &createSQL); err != nil {
+			return "", false, fmt.Errorf("failed to scan virtual table: %w", err)
+		}
+		match := ftsModulePattern.FindStringSubmatch(createSQL)
+		if match == nil {
+			continue
+		}
+		if content, ok := ftsContentOption(match[1]); ok && strings.EqualFold(content, tableName) {
+			return name, fts5Pattern.MatchString(createSQL), nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", false, err
+	}
+
+	return "", false, &NotFoundError{Kind: "full-text index for table", Name: tableName}
+}
+
+// SearchFTS returns the rows of tableName matching an FTS query through its
+// full-text index, best matches first. Each row carries its bm25 score as
+// _rank, where lower is better; FTS3/4 indexes have no ranking, so their
+// matches come in rowid order with a _rank of 0. Rows outside the table's
+// scopes are left out.
+func (s *SQLiteDB) SearchFTS(tableName, query string, limit, offset int, scopes ...models.Scope) (*models.FTSSearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("search query is required")
+	}
+	schema, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return nil, err
+	}
+	ftsName, isFTS5, err := s.ftsTableFor(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	fts := quoteIdentifier(ftsName)
+	source, rowid := quoteIdentifier(tableName)+" AS c", "c.rowid"
+	var args []interface{}
+	if len(scopes) > 0 {
+		condition, scopeArgs := scopeCondition(scopes)
+		source = fmt.Sprintf("(SELECT rowid AS _fts_rowid, * FROM %s WHERE %s) AS c", quoteIdentifier(tableName), condition)
+		rowid = "c._fts_rowid"
+		args = scopeArgs
+	}
+	from := fmt.Sprintf("FROM %s JOIN %s ON %s = %s.rowid WHERE %s MATCH ?", fts, source, rowid, fts, fts)
+	args = append(args, query)
+
+	result := &models.FTSSearchResult{FTSTable: ftsName, Rows: []models.Row{}}
+	if err := s.db.QueryRow("SELECT COUNT(*) "+from, args...).Scan(&result.Total); err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+
+	selected := make([]string, len(schema))
+	for i, col := range schema {
+		selected[i] = "c." + quoteIdentifier(col.Name)
+	}
+	rank, orderBy := "0", fts+".rowid"
+	if isFTS5 {
+		rank, orderBy = fts+".rank", fts+".rank"
+	}
+	rows, err := s.db.Query(fmt.Sprintf("SELECT %s, %s AS _rank %s ORDER BY %s LIMIT ? OFFSET ?",
+		strings.Join(selected, ", "), rank, from, orderBy), append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	defer rows.Close()
+
+	columnNames := make([]string, 0, len(schema)+1)
+	for _, col := range schema {
+		columnNames = append(columnNames, col.Name)
+	}
+	columnNames = append(columnNames, "_rank")
+	for rows.Next() {
+		row, err := scanRow(rows, columnNames)
+		if err != nil {
+			return nil, err
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mattn/go-sqlite3"
+	"golang.org/x/text/unicode/norm"
+)
+
+// driverName is the go-sqlite3 driver registered with SQLiter's custom SQL functions.
+const driverName = "sqlite3_sqliter"
+
+// foldFunctionName is the SQL function that case-folds text and strips diacritics.
+const foldFunctionName = "sqliter_fold"
+
+// likeEscaper escapes LIKE wildcards so search terms match literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func init() {
+	sql.Register(driverName, newDriver(nil))
+}
+
+// newDriver returns a driver whose connections get SQLiter's custom functions
+// and then run the given PRAGMA statements.
+func newDriver(pragmas []string) *sqlite3.SQLiteDriver {
+	return &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc(foldFunctionName, foldText, true); err != nil {
+				return err
+			}
+			for _, pragma := range pragmas {
+				if _, err := conn.Exec(pragma, nil); err != nil {
+					return fmt.Errorf("failed to run %q: %w", pragma, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+var (
+	pragmaDriversMu sync.Mutex
+	// pragmaDrivers maps a list of init PRAGMAs to its registered driver name
+	pragmaDrivers = make(map[string]string)
+)
+
+// normalizePragma validates a single PRAGMA statement, adding the PRAGMA
+// keyword if it was left out ("foreign_keys=ON").
+func normalizePragma(pragma string) (string, error) {
+	pragma = strings.TrimRight(strings.TrimSpace(pragma), "; \t\r\n")
+	if strings.Contains(pragma, ";") {
+		return "", fmt.Errorf("init pragma must be a single statement: %s", pragma)
+	}
+	fields := strings.Fields(pragma)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("init pragma cannot be empty")
+	}
+	if !strings.EqualFold(fields[0], "PRAGMA") {
+		pragma = "PRAGMA " + pragma
+	}
+	return pragma, nil
+}
+
+// driverWithPragmas returns the name of a driver that runs the PRAGMAs on
+// every new connection, registering it on first use. database/sql pools
+// connections, so setting a PRAGMA once would only affect one of them.
+func driverWithPragmas(pragmas []string) (string, error) {
+	if len(pragmas) == 0 {
+		return driverName, nil
+	}
+
+	normalized := make([]string, len(pragmas))
+	for i, pragma := range pragmas {
+		var err error
+		if normalized[i], err = normalizePragma(pragma); err != nil {
+			return "", err
+		}
+	}
+
+	key := strings.Join(normalized, "\n")
+	pragmaDriversMu.Lock()
+	defer pragmaDriversMu.Unlock()
+
+	if name, ok := pragmaDrivers[key]; ok {
+		return name, nil
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	name := driverName + "_" + hex.EncodeToString(sum[:8])
+	sql.Register(name, newDriver(normalized))
+	pragmaDrivers[key] = name
+	return name, nil
+}
+
+// foldText lowercases text and removes combining marks, so "José" becomes "jose".
+func foldText(text string) string {
+	decomposed := norm.NFD.String(text)
+
+	var b strings.Builder
+	b.Grow(len(decomposed))
+	for _, r := range decomposed {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return norm.NFC.String(b.String())
+}
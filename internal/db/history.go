@@ -0,0 +1,184 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"sqliter/internal/models"
+	"time"
+)
+
+const queryHistoryTable = internalTablePrefix + "query_history"
+
+// queryHistory is a ring buffer of the most recent console statements.
+// Results are never kept, only the statement and how it went.
+type queryHistory struct {
+	entries []models.QueryHistoryEntry
+	// next is where the next entry goes; entries before it are newer than
+	// the ones from it on once the buffer is full
+	next    int
+	full    bool
+	persist bool
+}
+
+func (h *queryHistory) add(entry models.QueryHistoryEntry) {
+	h.entries[h.next] = entry
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns the entries newest first.
+func (h *queryHistory) list() []models.QueryHistoryEntry {
+	count := h.next
+	if h.full {
+		count = len(h.entries)
+	}
+	list := make([]models.QueryHistoryEntry, count)
+	for i := range list {
+		list[i] = h.entries[(h.next-1-i+len(h.entries))%len(h.entries)]
+	}
+	return list
+}
+
+// EnableQueryHistory starts recording the last size statements run through
+// ExecuteSQL. With persist, the history is also stored in the database, and
+// entries stored by an earlier run are loaded; a read-only database keeps its
+// history in memory only.
+func (s *SQLiteDB) EnableQueryHistory(size int, persist bool) error {
+	if size <= 0 {
+		return fmt.Errorf("query history size must be positive")
+	}
+
+	history := &queryHistory{entries: make([]models.QueryHistoryEntry, size), persist: persist && !s.readOnly}
+	if history.persist {
+		stored, err := s.storedQueryHistory(size)
+		if err != nil {
+			return err
+		}
+		// Stored entries come newest first
+		for i := len(stored) - 1; i >= 0; i-- {
+			history.add(stored[i])
+		}
+	}
+
+	s.mu.Lock()
+	s.history = history
+	s.mu.Unlock()
+	return nil
+}
+
+// QueryHistory returns the recorded statements, newest first.
+func (s *SQLiteDB) QueryHistory() []models.QueryHistoryEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.history == nil {
+		return []models.QueryHistoryEntry{}
+	}
+	return s.history.list()
+}
+
+// ClearQueryHistory forgets all recorded statements, including stored ones.
+func (s *SQLiteDB) ClearQueryHistory() error {
+	s.mu.Lock()
+	history := s.history
+	if history == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	history.entries = make([]models.QueryHistoryEntry, len(history.entries))
+	history.next, history.full = 0, false
+	s.mu.Unlock()
+
+	// The delete waits for the writer, so it runs without holding the lock
+	if history.persist {
+		if _, err := s.writer.Exec(fmt.Sprintf("DELETE FROM %s", quoteIdentifier(queryHistoryTable))); err != nil {
+			return fmt.Errorf("failed to clear query history: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordQuery adds a statement to the history when it's enabled. rowCount is
+// the number of rows returned, or affected by a statement that returns none.
+// Storing the entry is best effort, a failure doesn't fail the statement.
+func (s *SQLiteDB) recordQuery(sqlQuery string, executedAt time.Time, rowCount int, err error) {
+	s.mu.Lock()
+	history := s.history
+	if history == nil {
+		s.mu.Unlock()
+		return
+	}
+
+	entry := models.QueryHistoryEntry{
+		SQL:        sqlQuery,
+		ExecutedAt: executedAt.UTC(),
+		ElapsedMs:  elapsedMs(executedAt),
+		RowCount:   rowCount,
+		OK:         err == nil,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	history.add(entry)
+	s.mu.Unlock()
+
+	if history.persist {
+		s.storeQuery(entry, len(history.entries))
+	}
+}
+
+func (s *SQLiteDB) ensureQueryHistoryTable() error {
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY, sql TEXT NOT NULL, executed_at INTEGER NOT NULL, elapsed_ms REAL NOT NULL, row_count INTEGER NOT NULL, error TEXT)", quoteIdentifier(queryHistoryTable))
+	if _, err := s.writer.Exec(query); err != nil {
+		return fmt.Errorf("failed to create query history table: %w", err)
+	}
+	return nil
+}
+
+// storedQueryHistory returns up to size stored entries, newest first.
+func (s *SQLiteDB) storedQueryHistory(size int) ([]models.QueryHistoryEntry, error) {
+	if err := s.ensureQueryHistoryTable(); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT sql, executed_at, elapsed_ms, row_count, COALESCE(error, '') FROM %s ORDER BY id DESC LIMIT ?", quoteIdentifier(queryHistoryTable))
+	rows, err := s.db.Query(query, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load query history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.QueryHistoryEntry
+	for rows.Next() {
+		var entry models.QueryHistoryEntry
+		var executedAt int64
+		if err := rows.Scan(&entry.SQL, &executedAt, &entry.ElapsedMs, &entry.RowCount, &entry.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan query history row: %w", err)
+		}
+		entry.ExecutedAt = time.Unix(0, executedAt).UTC()
+		entry.OK = entry.Error == ""
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// storeQuery appends an entry to the stored history and drops the entries
+// that no longer fit. Failures are only logged.
+func (s *SQLiteDB) storeQuery(entry models.QueryHistoryEntry, size int) {
+	var errorText interface{}
+	if !entry.OK {
+		errorText = entry.Error
+	}
+	table := quoteIdentifier(queryHistoryTable)
+	insert := fmt.Sprintf("INSERT INTO %s (sql, executed_at, elapsed_ms, row_count, error) VALUES (?, ?, ?, ?, ?)", table)
+	if _, err := s.writer.Exec(insert, entry.SQL, entry.ExecutedAt.UnixNano(), entry.ElapsedMs, entry.RowCount, errorText); err != nil {
+		log.Printf("Failed to store query history: %v", err)
+		return
+	}
+	if _, err := s.writer.Exec(fmt.Sprintf("DELETE FROM %s WHERE id <= (SELECT MAX(id) FROM %s) - ?", table, table), size); err != nil {
+		log.Printf("Failed to trim stored query history: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sqliter/internal/models"
+	"strings"
+)
+
+// expressionIndexColumn stands in for an indexed expression, which has no
+// column name.
+const expressionIndexColumn = "<expression>"
+
+// GetIndexes returns the table's indexes ordered by name, including the ones
+// SQLite creates for UNIQUE and PRIMARY KEY constraints.
+func (s *SQLiteDB) GetIndexes(tableName string) ([]models.Index, error) {
+	if err := requireTable(s.db, tableName); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT il.name, il."unique", il.origin, il.partial, m.sql
+		FROM pragma_index_list(?) il
+		LEFT JOIN sqlite_master m ON m.type = 'index' AND m.name = il.name
+		ORDER BY il.name`
+	rows, err := s.db.Query(query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	defer rows.Close()
+
+	indexes := []models.Index{}
+	for rows.Next() {
+		var index models.Index
+		var definition sql.NullString
+		if err := rows.Scan(&index.Name, &index.Unique, &index.Origin, &index.Partial, &definition); err != nil {
+			return nil, fmt.Errorf("failed to scan index row: %w", err)
+		}
+		if index.Partial {
+			index.Where = partialIndexPredicate(definition.String)
+		}
+		indexes = append(indexes, index)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range indexes {
+		if indexes[i].Columns, err = s.indexColumns(indexes[i].Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return indexes, nil
+}
+
+// indexColumns returns the key columns of an index in order.
+func (s *SQLiteDB) indexColumns(indexName string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT name FROM pragma_index_info(?) ORDER BY seqno`, indexName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index columns: %w", err)
+	}
+	defer rows.Close()
+
+	columns := []string{}
+	for rows.Next() {
+		var name sql.NullString
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan index column: %w", err)
+		}
+		if !name.Valid {
+			columns = append(columns, expressionIndexColumn)
+			continue
+		}
+		columns = append(columns, name.String)
+	}
+
+	return columns, rows.Err()
+}
+
+// partialIndexPredicate extracts the WHERE clause of a CREATE INDEX
+// statement. Only a WHERE outside quotes and parentheses counts, so quoted
+// names and expressions containing the word are skipped.
+func partialIndexPredicate(definition string) string {
+	depth := 0
+	for i := 0; i < len(definition); i++ {
+		switch ch := definition[i]; ch {
+		case '\'', '"', '`':
+			if end := strings.IndexByte(definition[i+1:], ch); end >= 0 {
+				i += end + 1
+			}
+		case '[':
+			if end := strings.IndexByte(definition[i+1:], ']'); end >= 0 {
+				i += end + 1
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+		default:
+			if depth == 0 && isKeywordAt(definition, i, "WHERE") {
+				return strings.TrimSpace(definition[i+len("WHERE"):])
+			}
+		}
+	}
+	return ""
+}
+
+// isKeywordAt reports whether keyword appears at position i as a whole word.
+func isKeywordAt(text string, i int, keyword string) bool {
+	end := i + len(keyword)
+	if end > len(text) || !strings.EqualFold(text[i:end], keyword) {
+		return false
+	}
+	return (i == 0 || !isIdentifierByte(text[i-1])) && (end == len(text) || !isIdentifierByte(text[end]))
+}
+
+func isIdentifierByte(b byte) bool {
+	return b == '_' || b == '$' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
+
+// CreateIndex builds and runs a CREATE INDEX statement from a structured
+// request and returns the new index. The name defaults to
+// idx_<table>_<columns>. A partial index predicate is passed through as SQL,
+// but must not add further statements.
+func (s *SQLiteDB) CreateIndex(tableName string, req models.CreateIndexRequest) (*models.Index, error) {
+	if len(req.Columns) == 0 {
+		return nil, fmt.Errorf("at least one column is required")
+	}
+
+	columns, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireColumns(columns, req.Columns...); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "idx_" + tableName + "_" + strings.Join(req.Columns, "_")
+	}
+
+	var taken int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = ? COLLATE NOCASE`, name).Scan(&taken); err != nil {
+		return nil, fmt.Errorf("failed to look up index: %w", err)
+	}
+	if taken > 0 {
+		return nil, &ConflictError{Kind: "index", Name: name}
+	}
+
+	quoted := make([]string, len(req.Columns))
+	for i, col := range req.Columns {
+		quoted[i] = quoteIdentifier(col)
+	}
+	statement := "CREATE INDEX"
+	if req.Unique {
+		statement = "CREATE UNIQUE INDEX"
+	}
+	query := fmt.Sprintf("%s %s ON %s (%s)", statement, quoteIdentifier(name), quoteIdentifier(tableName), strings.Join(quoted, ", "))
+	if where := strings.TrimSpace(req.Where); where != "" {
+		query += " WHERE " + where
+		if len(splitStatements(query)) != 1 {
+			return nil, fmt.Errorf("index predicate must not contain further statements")
+		}
+	}
+
+	if _, err := s.writer.Exec(query); err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", s.parseConstraintError(err))
+	}
+
+	indexes, err := s.GetIndexes(tableName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range indexes {
+		if indexes[i].Name == name {
+			return &indexes[i], nil
+		}
+	}
+	return nil, &NotFoundError{Kind: "index", Name: name}
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"sqliter/internal/models"
+	"strings"
+)
+
+// jsonPathPattern accepts the subset of SQLite JSON paths made of object keys
+// and array indexes, such as $.address.city or $.tags[0].
+var jsonPathPattern = regexp.MustCompile(`^\$(\.[A-Za-z_][A-Za-z0-9_]*|\[[0-9]+\])*$`)
+
+// IsValidJSONPath reports whether a JSON path may be passed to json_extract.
+func IsValidJSONPath(path string) bool {
+	return jsonPathPattern.MatchString(path)
+}
+
+// jsonPathAlias names the computed column holding a JSON path's value, e.g.
+// "meta.address.city" for column meta and path $.address.city.
+func jsonPathAlias(p models.JSONPath) string {
+	return p.Column + strings.TrimPrefix(p.Path, "$")
+}
+
+// jsonPathColumns renders a json_extract select expression for each path,
+// with the paths as bound arguments. It fails if a path or column is invalid,
+// or if SQLite was built without the JSON functions.
+func (s *SQLiteDB) jsonPathColumns(columns []models.Column, paths []models.JSONPath) (string, []interface{}, error) {
+	if len(paths) == 0 {
+		return "", nil, nil
+	}
+
+	if _, err := s.db.Exec("SELECT json('{}')"); err != nil {
+		return "", nil, fmt.Errorf("JSON functions are not available in this SQLite build: %w", err)
+	}
+
+	exists := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		exists[col.Name] = true
+	}
+
+	expressions := make([]string, len(paths))
+	args := make([]interface{}, len(paths))
+	for i, p := range paths {
+		if !exists[p.Column] {
+			return "", nil, &NotFoundError{Kind: "column", Name: p.Column}
+		}
+		if !IsValidJSONPath(p.Path) {
+			return "", nil, &FilterError{Column: p.Column, Reason: fmt.Sprintf("invalid JSON path: %s", p.Path)}
+		}
+		expressions[i] = fmt.Sprintf("json_extract(%s, ?) AS %s", quoteIdentifier(p.Column), quoteIdentifier(jsonPathAlias(p)))
+		args[i] = p.Path
+	}
+
+	return strings.Join(expressions, ", "), args, nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"sqliter/internal/models"
+	"strconv"
+	"unicode/utf8"
+)
+
+// declaredLengthPattern captures N from a declared type such as VARCHAR(N).
+var declaredLengthPattern = regexp.MustCompile(`\(\s*(\d+)\s*\)\s*$`)
+
+// LengthError reports a text value longer than its column's declared length.
+type LengthError struct {
+	Column string
+	Limit  int
+	Length int
+}
+
+func (e *LengthError) Error() string {
+	return fmt.Sprintf("value for '%s' is %d characters long, exceeding the declared limit of %d", e.Column, e.Length, e.Limit)
+}
+
+// columnLengthLimits returns the declared maximum length of each text column
+// with one, such as VARCHAR(255). SQLite itself ignores these lengths.
+func (s *SQLiteDB) columnLengthLimits(tableName string) (map[string]int, error) {
+	columns, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return lengthLimits(columns), nil
+}
+
+// lengthLimits extracts the declared text lengths from a table schema.
+func lengthLimits(columns []models.Column) map[string]int {
+	limits := make(map[string]int)
+	for _, col := range columns {
+		if !hasTextAffinity(col.Type) {
+			continue
+		}
+		match := declaredLengthPattern.FindStringSubmatch(col.Type)
+		if match == nil {
+			continue
+		}
+		if limit, err := strconv.Atoi(match[1]); err == nil {
+			limits[col.Name] = limit
+		}
+	}
+
+	return limits
+}
+
+// checkLength rejects a text value longer than the column's declared limit.
+func checkLength(limits map[string]int, column string, value interface{}) error {
+	limit, ok := limits[column]
+	if !ok {
+		return nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	if length := utf8.RuneCountInString(text); length > limit {
+		return &LengthError{Column: column, Limit: limit, Length: length}
+	}
+	return nil
+}
+
+// checkLengths validates every column value against the declared lengths.
+func (s *SQLiteDB) checkLengths(tableName string, columns []string, values []interface{}) error {
+	limits, err := s.columnLengthLimits(tableName)
+	if err != nil {
+		return err
+	}
+	for i, col := range columns {
+		if err := checkLength(limits, col, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
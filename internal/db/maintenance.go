@@ -0,0 +1,270 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sqliter/internal/models"
+	"strings"
+)
+
+func (s *SQLiteDB) GetWALStatus() (*models.WALStatus, error) {
+	status := &models.WALStatus{}
+	if err := s.db.QueryRow("PRAGMA journal_mode").Scan(&status.JournalMode); err != nil {
+		return nil, fmt.Errorf("failed to get journal mode: %w", err)
+	}
+
+	info, err := os.Stat(s.path + "-wal")
+	if err == nil {
+		status.WALFileExists = true
+		status.WALFileSize = info.Size()
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to stat WAL file: %w", err)
+	}
+
+	s.mu.Lock()
+	status.LastCheckpoint = s.lastCheckpoint
+	s.mu.Unlock()
+
+	return status, nil
+}
+
+// journalModes are the values PRAGMA journal_mode accepts.
+var journalModes = map[string]bool{"delete": true, "truncate": true, "persist": true, "memory": true, "wal": true, "off": true}
+
+// IsValidJournalMode reports whether mode is a journal mode SQLite accepts.
+func IsValidJournalMode(mode string) bool {
+	return journalModes[strings.ToLower(mode)]
+}
+
+// IsConnectionJournalMode reports whether mode only applies to the connection
+// that sets it, unlike WAL, which is stored in the file, and delete, the
+// default of every connection to a file that isn't in WAL mode. Such modes
+// have to be set on every connection with an init pragma.
+func IsConnectionJournalMode(mode string) bool {
+	switch strings.ToLower(mode) {
+	case "truncate", "persist", "memory", "off":
+		return true
+	}
+	return false
+}
+
+// SetJournalMode switches the database to the journal mode and returns the
+// mode now in effect, which SQLite may keep unchanged when it can't switch,
+// e.g. WAL on an in-memory database. Only modes that apply to every
+// connection, wal and delete, can be set this way.
+func (s *SQLiteDB) SetJournalMode(mode string) (string, error) {
+	if !IsValidJournalMode(mode) {
+		return "", fmt.Errorf("invalid journal mode %q", mode)
+	}
+	if IsConnectionJournalMode(mode) {
+		return "", fmt.Errorf("journal mode %q only applies to one connection, set it on every connection with an init pragma", mode)
+	}
+
+	var effective string
+	if err := s.db.QueryRow("PRAGMA journal_mode = " + strings.ToLower(mode)).Scan(&effective); err != nil {
+		return "", fmt.Errorf("failed to set journal mode: %w", err)
+	}
+	return effective, nil
+}
+
+// Checkpoint copies the WAL contents back into the database file and truncates the WAL.
+func (s *SQLiteDB) Checkpoint() (*models.CheckpointResult, error) {
+	result := &models.CheckpointResult{}
+	err := s.db.QueryRow("PRAGMA wal_checkpoint(TRUNCATE)").Scan(&result.Busy, &result.LogFrames, &result.CheckpointedFrames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to checkpoint: %w", err)
+	}
+
+	s.mu.Lock()
+	s.lastCheckpoint = result
+	s.mu.Unlock()
+
+	return result, nil
+}
+
+// IntegrityCheck runs PRAGMA integrity_check and returns the problems it
+// found, or the single message "ok" when the database is intact.
+func (s *SQLiteDB) IntegrityCheck() ([]string, error) {
+	rows, err := s.db.Query("PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check integrity: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []string
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			return nil, fmt.Errorf("failed to scan integrity check result: %w", err)
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// ForeignKeyCheck runs PRAGMA foreign_key_check and returns the rows whose
+// foreign keys point at missing parent rows. It works whether or not foreign
+// key enforcement is on.
+func (s *SQLiteDB) ForeignKeyCheck() ([]models.ForeignKeyViolation, error) {
+	rows, err := s.db.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	violations := []models.ForeignKeyViolation{}
+	for rows.Next() {
+		var violation models.ForeignKeyViolation
+		var rowID sql.NullInt64
+		if err := rows.Scan(&violation.Table, &rowID, &violation.Parent, &violation.ForeignKeyID); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key violation: %w", err)
+		}
+		if rowID.Valid {
+			violation.RowID = &rowID.Int64
+		}
+		violations = append(violations, violation)
+	}
+
+	return violations, rows.Err()
+}
+
+// AnalyzeTable refreshes the query planner statistics for a table. The CSV
+// import calls it after committing when asked to, so that large loads don't
+// leave stale stats.
+func (s *SQLiteDB) AnalyzeTable(tableName string) error {
+	if _, err := s.writer.Exec("ANALYZE " + quoteIdentifier(tableName)); err != nil {
+		return fmt.Errorf("failed to analyze table: %w", err)
+	}
+	return nil
+}
+
+// ErrTargetExists is returned when a save target already exists and overwriting wasn't confirmed.
+var ErrTargetExists = errors.New("target file already exists")
+
+// SaveAs writes a consistent copy of the database to path using VACUUM INTO.
+// The copy is written to a temporary file next to path and renamed over it,
+// so a failed save leaves an existing file untouched.
+func (s *SQLiteDB) SaveAs(path string, overwrite bool) error {
+	if path == "" {
+		return fmt.Errorf("target path is required")
+	}
+
+	target, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("invalid target path: %w", err)
+	}
+	current, err := filepath.Abs(s.path)
+	if err == nil && target == current {
+		return fmt.Errorf("target path is the current database")
+	}
+
+	if info, err := os.Stat(filepath.Dir(target)); err != nil || !info.IsDir() {
+		return fmt.Errorf("target directory does not exist: %s", filepath.Dir(target))
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(target); err == nil {
+		if !overwrite {
+			return fmt.Errorf("%w: %s", ErrTargetExists, path)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("target path is a directory: %s", path)
+		}
+		mode = info.Mode().Perm()
+	}
+
+	// VACUUM INTO accepts an existing file only when it is empty
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".sqliter-save-*.db")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if _, err := s.db.Exec("VACUUM INTO ?", tmp.Name()); err != nil {
+		return fmt.Errorf("failed to save database: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to save database: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to replace existing file: %w", err)
+	}
+
+	return nil
+}
+
+// OpenCopy opens a copy written by SaveAs with this database's settings: the
+// same read-only mode, connection pragmas and attachments, and the query
+// history, whose entries carry over.
+func (s *SQLiteDB) OpenCopy(path string) (*SQLiteDB, error) {
+	open := NewSQLiteDB
+	if s.readOnly {
+		open = NewReadOnlySQLiteDB
+	}
+	saved, err := open(path, s.initPragmas...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	for alias, attached := range s.attachments {
+		saved.attachments[alias] = attached
+	}
+	var history *queryHistory
+	if s.history != nil {
+		copied := *s.history
+		copied.entries = append([]models.QueryHistoryEntry(nil), s.history.entries...)
+		history = &copied
+	}
+	s.mu.Unlock()
+
+	// A stored history was copied with the file and is loaded from there
+	if history != nil && history.persist {
+		if err := saved.EnableQueryHistory(len(history.entries), true); err != nil {
+			saved.Close()
+			return nil, err
+		}
+	} else {
+		saved.history = history
+	}
+
+	return saved, nil
+}
+
+// Backup writes a consistent copy of the database file to w. The copy is
+// made with VACUUM INTO a temporary file, which only reads the database, so
+// in WAL mode writers carry on while it runs. The temporary file is removed
+// afterwards.
+func (s *SQLiteDB) Backup(w io.Writer) error {
+	tmp, err := os.CreateTemp("", "sqliter-backup-*.db")
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// VACUUM INTO accepts an existing file only when it is empty
+	if _, err := s.db.Exec("VACUUM INTO ?", tmp.Name()); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	if _, err := io.Copy(w, tmp); err != nil {
+		return fmt.Errorf("failed to send backup: %w", err)
+	}
+	return nil
+}
+
+// SQLiteVersion returns the version of the linked SQLite library.
+func (s *SQLiteDB) SQLiteVersion() (string, error) {
+	var version string
+	if err := s.db.QueryRow("SELECT sqlite_version()").Scan(&version); err != nil {
+		return "", fmt.Errorf("failed to get SQLite version: %w", err)
+	}
+	return version, nil
+}
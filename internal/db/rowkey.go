@@ -0,0 +1,275 @@
+package db
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sqliter/internal/models"
+	"strings"
+)
+
+// rowKeyAliasPrefix names the extra result columns that carry row keys.
+const rowKeyAliasPrefix = "__sqliter_key_"
+
+// primaryKeyColumns returns the table's primary key columns in key order, or
+// rowid for tables without a declared primary key. Views have no key.
+func primaryKeyColumns(qr queryer, tableName string) ([]string, error) {
+	var isView bool
+	err := qr.QueryRow("SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'view' AND name = ?", tableName).Scan(&isView)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up table: %w", err)
+	}
+	if isView {
+		return nil, nil
+	}
+
+	rows, err := qr.Query("SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk", tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get primary key: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan primary key column: %w", err)
+		}
+		keys = append(keys, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return []string{"rowid"}, nil
+	}
+	return keys, nil
+}
+
+// PrimaryKeyColumns returns the columns identifying a single row of the
+// table: its primary key in key order, or rowid when it has none.
+func (s *SQLiteDB) PrimaryKeyColumns(tableName string) ([]string, error) {
+	if err := requireTable(s.db, tableName); err != nil {
+		return nil, err
+	}
+	keys, err := primaryKeyColumns(s.db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, &NoPrimaryKeyError{Table: tableName}
+	}
+	return keys, nil
+}
+
+// NoPrimaryKeyError reports a view, which has neither a primary key nor a
+// rowid to identify its rows by.
+type NoPrimaryKeyError struct {
+	Table string
+}
+
+func (e *NoPrimaryKeyError) Error() string {
+	return fmt.Sprintf("view '%s' has no primary key", e.Table)
+}
+
+// IncompleteKeyError reports row conditions that leave out primary key
+// columns, so they may match more than one row.
+type IncompleteKeyError struct {
+	Missing []string
+}
+
+func (e *IncompleteKeyError) Error() string {
+	return fmt.Sprintf("where must specify the full primary key, missing: %s", strings.Join(e.Missing, ", "))
+}
+
+// RequirePrimaryKey checks that where sets every primary key column of the
+// table, so an update or delete can't hit more than one row. Like SQLite,
+// column names are matched case-insensitively.
+func (s *SQLiteDB) RequirePrimaryKey(tableName string, where map[string]interface{}) error {
+	keys, err := s.PrimaryKeyColumns(tableName)
+	if err != nil {
+		return err
+	}
+
+	given := make(map[string]bool, len(where))
+	for col := range where {
+		given[strings.ToLower(col)] = true
+	}
+	var missing []string
+	for _, key := range keys {
+		if !given[strings.ToLower(key)] {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return &IncompleteKeyError{Missing: missing}
+	}
+	return nil
+}
+
+// rowKeySelect returns the select list entries that fetch the key columns
+// under their aliases.
+func rowKeySelect(keys []string) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s AS %s", quoteIdentifier(key), quoteIdentifier(fmt.Sprintf("%s%d", rowKeyAliasPrefix, i)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// keyOrderBy orders rows by their key, matching the row value comparison of
+// cursorCondition.
+func keyOrderBy(keys []string) string {
+	terms := make([]string, len(keys))
+	for i, key := range keys {
+		terms[i] = quoteIdentifier(key) + " ASC"
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+// takeRowKey moves the aliased key values out of a row.
+func takeRowKey(row models.Row, keys []string) map[string]interface{} {
+	key := make(map[string]interface{}, len(keys))
+	for i, name := range keys {
+		alias := fmt.Sprintf("%s%d", rowKeyAliasPrefix, i)
+		key[name] = row[alias]
+		delete(row, alias)
+	}
+	return key
+}
+
+// setRowKey surfaces a row's key in the requested format; an empty format
+// leaves the row unchanged.
+func setRowKey(row models.Row, key map[string]interface{}, format string) error {
+	switch format {
+	case models.RowKeyEmbedded:
+		for name, value := range key {
+			row[name] = value
+		}
+	case models.RowKeyObject:
+		row[models.RowKeyField] = key
+	case models.RowKeyToken:
+		token, err := encodeRowKey(key)
+		if err != nil {
+			return err
+		}
+		row[models.RowKeyField] = token
+	}
+	return nil
+}
+
+// encodeRowKey renders a row key as an opaque token, which is also the format
+// of paging cursors. BLOB values are encoded as models.Blob, which
+// DecodeRowKey turns back into bytes; integers stay numbers, which it decodes
+// exactly.
+func encodeRowKey(key map[string]interface{}) (string, error) {
+	values := make(map[string]interface{}, len(key))
+	for name, value := range key {
+		if blob, ok := value.([]byte); ok {
+			values[name] = models.JSONValue(blob)
+		} else {
+			values[name] = value
+		}
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode row key: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+// CursorError reports a paging cursor that can't be used.
+type CursorError struct {
+	Reason string
+}
+
+func (e *CursorError) Error() string {
+	return "invalid cursor: " + e.Reason
+}
+
+// cursorCondition selects the rows whose key comes after the cursor's, using
+// a row value comparison so composite keys page correctly.
+func cursorCondition(keys []string, cursor string) (string, []interface{}, error) {
+	values, err := DecodeRowKey(cursor)
+	if err != nil {
+		return "", nil, &CursorError{Reason: err.Error()}
+	}
+
+	quoted := make([]string, len(keys))
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		value, ok := values[key]
+		if !ok {
+			return "", nil, &CursorError{Reason: fmt.Sprintf("missing key column '%s'", key)}
+		}
+		quoted[i] = quoteIdentifier(key)
+		args[i] = value
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+
+	return fmt.Sprintf("(%s) > (%s)", strings.Join(quoted, ", "), placeholders), args, nil
+}
+
+// DecodeRowKey turns a row key from a client, either a key object or a token,
+// back into the column values identifying the row.
+func DecodeRowKey(key interface{}) (map[string]interface{}, error) {
+	switch k := key.(type) {
+	case map[string]interface{}:
+		values := make(map[string]interface{}, len(k))
+		for name, value := range k {
+			blob, err := blobValue(value)
+			if err != nil {
+				return nil, err
+			}
+			values[name] = blob
+		}
+		return values, nil
+	case string:
+		raw, err := base64.RawURLEncoding.DecodeString(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid row key token: %w", err)
+		}
+
+		// Decode numbers exactly, so 64-bit keys keep their precision
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var values map[string]interface{}
+		if err := decoder.Decode(&values); err != nil {
+			return nil, fmt.Errorf("invalid row key token: %w", err)
+		}
+		for name, value := range values {
+			if number, ok := value.(json.Number); ok {
+				if i, err := number.Int64(); err == nil {
+					values[name] = i
+				} else if f, err := number.Float64(); err == nil {
+					values[name] = f
+				}
+			} else if values[name], err = blobValue(value); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("row key must be an object or a token")
+	}
+}
+
+// blobValue turns a key value rendered as models.Blob, {"__blob__": "<base64>"},
+// back into the bytes to bind; other values are returned unchanged.
+func blobValue(value interface{}) (interface{}, error) {
+	object, ok := value.(map[string]interface{})
+	if !ok || len(object) != 1 {
+		return value, nil
+	}
+	encoded, ok := object["__blob__"].(string)
+	if !ok {
+		return value, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BLOB in row key: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"fmt"
+	"sqliter/internal/models"
+	"strings"
+	"time"
+)
+
+const savedQueriesTable = internalTablePrefix + "saved_queries"
+
+func (s *SQLiteDB) ensureSavedQueriesTable() error {
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (name TEXT PRIMARY KEY, sql TEXT NOT NULL, saved_at INTEGER NOT NULL)", quoteIdentifier(savedQueriesTable))
+	if _, err := s.writer.Exec(query); err != nil {
+		return fmt.Errorf("failed to create saved queries table: %w", err)
+	}
+	return nil
+}
+
+// SavedQueryError reports a saved query missing its name or SQL.
+type SavedQueryError struct {
+	Reason string
+}
+
+func (e *SavedQueryError) Error() string {
+	return e.Reason
+}
+
+// SaveQuery stores a named SQL snippet. A name that's already taken is a
+// ConflictError unless overwrite is set.
+func (s *SQLiteDB) SaveQuery(name, sqlQuery string, overwrite bool) (*models.SavedQuery, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &SavedQueryError{Reason: "saved query name is required"}
+	}
+	if strings.TrimSpace(sqlQuery) == "" {
+		return nil, &SavedQueryError{Reason: "saved query SQL cannot be empty"}
+	}
+
+	if err := s.ensureSavedQueriesTable(); err != nil {
+		return nil, err
+	}
+
+	saved := &models.SavedQuery{Name: name, SQL: sqlQuery, SavedAt: time.Now().UTC()}
+	conflict := "DO NOTHING"
+	if overwrite {
+		conflict = "DO UPDATE SET sql = excluded.sql, saved_at = excluded.saved_at"
+	}
+	query := fmt.Sprintf("INSERT INTO %s (name, sql, saved_at) VALUES (?, ?, ?) ON CONFLICT(name) %s", quoteIdentifier(savedQueriesTable), conflict)
+	result, err := s.writer.Exec(query, saved.Name, saved.SQL, saved.SavedAt.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to save query: %w", err)
+	}
+	if inserted, _ := result.RowsAffected(); inserted == 0 {
+		return nil, &ConflictError{Kind: "saved query", Name: name}
+	}
+
+	return saved, nil
+}
+
+// SavedQueries returns the saved SQL snippets ordered by name.
+func (s *SQLiteDB) SavedQueries() ([]models.SavedQuery, error) {
+	saved := []models.SavedQuery{}
+
+	exists, err := s.schemaObjectExists("table", savedQueriesTable)
+	if err != nil || !exists {
+		return saved, err
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf("SELECT name, sql, saved_at FROM %s ORDER BY name", quoteIdentifier(savedQueriesTable)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved queries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var query models.SavedQuery
+		var savedAt int64
+		if err := rows.Scan(&query.Name, &query.SQL, &savedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan saved query: %w", err)
+		}
+		query.SavedAt = time.Unix(0, savedAt).UTC()
+		saved = append(saved, query)
+	}
+
+	return saved, rows.Err()
+}
+
+// DeleteSavedQuery removes a saved SQL snippet.
+func (s *SQLiteDB) DeleteSavedQuery(name string) error {
+	exists, err := s.schemaObjectExists("table", savedQueriesTable)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &NotFoundError{Kind: "saved query", Name: name}
+	}
+
+	result, err := s.writer.Exec(fmt.Sprintf("DELETE FROM %s WHERE name = ?", quoteIdentifier(savedQueriesTable)), name)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved query: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return &NotFoundError{Kind: "saved query", Name: name}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sqliter/internal/models"
+)
+
+// diffSchemaAlias is the schema name the other database is attached under.
+const diffSchemaAlias = "sqliter_diff"
+
+// DiffSchema compares the tables and columns of the current database with the
+// database file at otherPath, which is attached for the duration of the call.
+func (s *SQLiteDB) DiffSchema(otherPath string) (*models.SchemaDiff, error) {
+	if otherPath == "" {
+		return nil, fmt.Errorf("database path is required")
+	}
+	// ATTACH would silently create a missing file
+	if _, err := os.Stat(otherPath); err != nil {
+		return nil, fmt.Errorf("cannot open database %s: %w", otherPath, err)
+	}
+
+	// ATTACH is per connection, so keep one connection for the whole comparison
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS "+diffSchemaAlias, otherPath); err != nil {
+		return nil, fmt.Errorf("failed to attach database: %w", err)
+	}
+	defer conn.ExecContext(ctx, "DETACH DATABASE "+diffSchemaAlias)
+
+	// Read both schemas in one transaction, which ends before the DETACH
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	currentTables, err := schemaTables(tx, "main")
+	if err != nil {
+		return nil, err
+	}
+	otherTables, err := schemaTables(tx, diffSchemaAlias)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.SchemaDiff{
+		TablesOnlyInCurrent: []string{},
+		TablesOnlyInOther:   []string{},
+		Tables:              []models.TableDiff{},
+	}
+	otherSet := make(map[string]bool, len(otherTables))
+	for _, name := range otherTables {
+		otherSet[name] = true
+	}
+	currentSet := make(map[string]bool, len(currentTables))
+	for _, name := range currentTables {
+		currentSet[name] = true
+		if !otherSet[name] {
+			diff.TablesOnlyInCurrent = append(diff.TablesOnlyInCurrent, name)
+		}
+	}
+	for _, name := range otherTables {
+		if !currentSet[name] {
+			diff.TablesOnlyInOther = append(diff.TablesOnlyInOther, name)
+		}
+	}
+
+	for _, name := range currentTables {
+		if !otherSet[name] {
+			continue
+		}
+		tableDiff, err := s.diffTableColumns(tx, name)
+		if err != nil {
+			return nil, err
+		}
+		if tableDiff != nil {
+			diff.Tables = append(diff.Tables, *tableDiff)
+		}
+	}
+
+	return diff, nil
+}
+
+// schemaTables lists the user tables of an attached schema, leaving out
+// SQLiter's own tables.
+func schemaTables(qr queryer, schema string) ([]string, error) {
+	query := fmt.Sprintf(`SELECT name FROM %s.sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%%' AND substr(name, 1, ?) != ? ORDER BY name`, quoteIdentifier(schema))
+	rows, err := qr.Query(query, len(internalTablePrefix), internalTablePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan table row: %w", err)
+		}
+		tables = append(tables, name)
+	}
+
+	return tables, rows.Err()
+}
+
+// diffTableColumns compares a table present in both schemas and returns nil
+// when the column definitions match.
+func (s *SQLiteDB) diffTableColumns(qr queryer, tableName string) (*models.TableDiff, error) {
+	currentColumns, err := s.getSchemaTableSchema(qr, "main", tableName)
+	if err != nil {
+		return nil, err
+	}
+	otherColumns, err := s.getSchemaTableSchema(qr, diffSchemaAlias, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	tableDiff := models.TableDiff{
+		Table:                tableName,
+		ColumnsOnlyInCurrent: []string{},
+		ColumnsOnlyInOther:   []string{},
+		ChangedColumns:       []models.ColumnChange{},
+	}
+
+	otherByName := make(map[string]models.Column, len(otherColumns))
+	for _, col := range otherColumns {
+		otherByName[col.Name] = col
+	}
+	currentByName := make(map[string]bool, len(currentColumns))
+	for _, col := range currentColumns {
+		currentByName[col.Name] = true
+		other, ok := otherByName[col.Name]
+		if !ok {
+			tableDiff.ColumnsOnlyInCurrent = append(tableDiff.ColumnsOnlyInCurrent, col.Name)
+			continue
+		}
+		if !sameColumnDefinition(col, other) {
+			tableDiff.ChangedColumns = append(tableDiff.ChangedColumns, models.ColumnChange{Column: col.Name, Current: col, Other: other})
+		}
+	}
+	for _, col := range otherColumns {
+		if !currentByName[col.Name] {
+			tableDiff.ColumnsOnlyInOther = append(tableDiff.ColumnsOnlyInOther, col.Name)
+		}
+	}
+
+	if len(tableDiff.ColumnsOnlyInCurrent) == 0 && len(tableDiff.ColumnsOnlyInOther) == 0 && len(tableDiff.ChangedColumns) == 0 {
+		return nil, nil
+	}
+	return &tableDiff, nil
+}
+
+func sameColumnDefinition(a, b models.Column) bool {
+	if a.Type != b.Type || a.NotNull != b.NotNull || a.PrimaryKey != b.PrimaryKey || a.Unique != b.Unique {
+		return false
+	}
+	if (a.DefaultValue == nil) != (b.DefaultValue == nil) {
+		return false
+	}
+	return a.DefaultValue == nil || *a.DefaultValue == *b.DefaultValue
+}
@@ -0,0 +1,236 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sqliter/internal/models"
+	"strings"
+	"unicode"
+)
+
+// ScriptError reports the statement of a script that failed. The whole
+// script was rolled back.
+type ScriptError struct {
+	// Index is the 0-based position of the failed statement.
+	Index     int
+	Statement string
+	Err       error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("statement %d failed, script rolled back: %v", e.Index+1, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// splitStatements splits a script on the semicolons that end statements.
+// Semicolons inside string literals, quoted identifiers, comments and the
+// BEGIN...END body of a CREATE TRIGGER are kept. Blank statements are dropped.
+func splitStatements(script string) []string {
+	var statements []string
+	var words []string // leading words of the current statement
+	var word strings.Builder
+	start := 0
+	depth := 0 // BEGIN/CASE ... END nesting inside a trigger body
+
+	isTrigger := func() bool {
+		// CREATE [TEMP|TEMPORARY] TRIGGER
+		if len(words) < 2 || words[0] != "CREATE" {
+			return false
+		}
+		return words[1] == "TRIGGER" ||
+			(len(words) >= 3 && (words[1] == "TEMP" || words[1] == "TEMPORARY") && words[2] == "TRIGGER")
+	}
+	endWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := strings.ToUpper(word.String())
+		word.Reset()
+		if len(words) < 3 {
+			words = append(words, w)
+		}
+		if !isTrigger() {
+			return
+		}
+		switch w {
+		case "BEGIN", "CASE":
+			depth++
+		case "END":
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	flush := func(end int) {
+		if stmt := strings.TrimSpace(script[start:end]); stmt != "" {
+			statements = append(statements, stmt)
+		}
+		start = end + 1
+		words = words[:0]
+		depth = 0
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			endWord()
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			// Doubled quotes inside a literal are escapes and simply reopen it
+			for i++; i < len(script) && script[i] != closing; i++ {
+			}
+		case c == '-' && i+1 < len(script) && script[i+1] == '-':
+			endWord()
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(script) && script[i+1] == '*':
+			endWord()
+			if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(script)
+			}
+		case c == ';':
+			endWord()
+			if depth == 0 {
+				flush(i)
+			}
+		case c == '_' || c > unicode.MaxASCII || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			word.WriteByte(c)
+		default:
+			endWord()
+		}
+	}
+	endWord()
+	flush(len(script))
+
+	return statements
+}
+
+// errTransactionControl rejects the statements of a script that would begin
+// or end the transaction ExecuteSQLScript runs it in.
+var errTransactionControl = errors.New("BEGIN, COMMIT, END and ROLLBACK aren't allowed, the script already runs in a single transaction")
+
+// isTransactionControl reports whether a statement begins or ends a
+// transaction. Savepoints, including ROLLBACK TO, nest inside the script's
+// transaction and are allowed.
+func isTransactionControl(stmt string) bool {
+	var words []string
+	scanWords(stmt, func(word string, depth int) bool {
+		words = append(words, word)
+		return len(words) < 3
+	})
+	if len(words) == 0 {
+		return false
+	}
+	switch words[0] {
+	case "BEGIN", "COMMIT", "END":
+		return true
+	case "ROLLBACK":
+		// ROLLBACK [TRANSACTION] TO [SAVEPOINT] name
+		if len(words) > 1 && words[1] == "TRANSACTION" {
+			words = words[1:]
+		}
+		return len(words) < 2 || words[1] != "TO"
+	}
+	return false
+}
+
+// ExecuteSQLScript runs a script of semicolon-separated statements in a
+// single transaction. If any statement fails, none of them take effect.
+// Statements that begin or end a transaction are rejected before any runs.
+func (s *SQLiteDB) ExecuteSQLScript(script string) (*models.SQLScriptResult, error) {
+	return s.ExecuteSQLScriptWithLimits(script, 0, 0)
+}
+
+// ExecuteSQLScriptWithLimits runs a script like ExecuteSQLScript. The result
+// of each SELECT stops after maxRows rows, and the results of all of them
+// together after maxResponseBytes of JSON; 0 means no limit.
+func (s *SQLiteDB) ExecuteSQLScriptWithLimits(script string, maxRows, maxResponseBytes int) (*models.SQLScriptResult, error) {
+	statements := splitStatements(script)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("empty SQL script")
+	}
+	for i, stmt := range statements {
+		if isTransactionControl(stmt) {
+			return nil, &ScriptError{Index: i, Statement: stmt, Err: errTransactionControl}
+		}
+	}
+
+	versionBefore, err := s.schemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	conn, err := s.attachedConn(ctx, s.writer)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &models.SQLScriptResult{Statements: make([]models.SQLStatementResult, 0, len(statements))}
+	budget := &responseBudget{max: maxResponseBytes}
+	for i, stmt := range statements {
+		var stmtResult *models.SQLQueryResult
+		if IsSelectQuery(stmt) {
+			rows, err := tx.Query(stmt)
+			if err != nil {
+				return nil, &ScriptError{Index: i, Statement: stmt, Err: s.noSuchTableError(err)}
+			}
+			stmtResult, err = readQueryResultWithin(rows, maxRows, budget)
+			rows.Close()
+			if err != nil {
+				return nil, &ScriptError{Index: i, Statement: stmt, Err: err}
+			}
+		} else {
+			res, err := tx.Exec(stmt)
+			if err != nil {
+				return nil, &ScriptError{Index: i, Statement: stmt, Err: s.noSuchTableError(s.parseConstraintError(err))}
+			}
+			rowsAffected, _ := res.RowsAffected()
+			stmtResult = &models.SQLQueryResult{
+				Columns:      []string{"rows_affected"},
+				Rows:         [][]interface{}{{rowsAffected}},
+				RowCount:     1,
+				RowsAffected: int(rowsAffected),
+			}
+			result.RowsAffected += int(rowsAffected)
+		}
+		result.Statements = append(result.Statements, models.SQLStatementResult{SQL: stmt, SQLQueryResult: *stmtResult})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Report DDL so clients can refresh their table list
+	versionAfter, err := s.schemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if versionAfter != versionBefore {
+		tables, err := s.GetTables()
+		if err != nil {
+			return nil, err
+		}
+		result.SchemaChanged = true
+		result.Tables = tables
+	}
+
+	return result, nil
+}
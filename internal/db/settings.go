@@ -0,0 +1,67 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// internalTablePrefix marks tables SQLiter creates for its own bookkeeping;
+// they are hidden from the table list.
+const internalTablePrefix = "_sqliter_"
+
+const settingsTable = internalTablePrefix + "settings"
+
+// MaxSettingSize caps the size in bytes of a single stored setting value.
+const MaxSettingSize = 64 * 1024
+
+func (s *SQLiteDB) ensureSettingsTable() error {
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (key TEXT PRIMARY KEY, value TEXT NOT NULL)", quoteIdentifier(settingsTable))
+	if _, err := s.writer.Exec(query); err != nil {
+		return fmt.Errorf("failed to create settings table: %w", err)
+	}
+	return nil
+}
+
+// GetSetting returns the raw JSON value stored under key.
+func (s *SQLiteDB) GetSetting(key string) (string, error) {
+	exists, err := s.schemaObjectExists("table", settingsTable)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", &NotFoundError{Kind: "setting", Name: key}
+	}
+
+	var value string
+	query := fmt.Sprintf("SELECT value FROM %s WHERE key = ?", quoteIdentifier(settingsTable))
+	if err := s.db.QueryRow(query, key).Scan(&value); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", &NotFoundError{Kind: "setting", Name: key}
+		}
+		return "", fmt.Errorf("failed to get setting: %w", err)
+	}
+
+	return value, nil
+}
+
+// SetSetting stores a raw JSON value under key, replacing any previous value.
+func (s *SQLiteDB) SetSetting(key, value string) error {
+	if key == "" {
+		return fmt.Errorf("setting key is required")
+	}
+	if len(value) > MaxSettingSize {
+		return fmt.Errorf("setting value exceeds the maximum size of %d bytes", MaxSettingSize)
+	}
+
+	if err := s.ensureSettingsTable(); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value", quoteIdentifier(settingsTable))
+	if _, err := s.writer.Exec(query, key, value); err != nil {
+		return fmt.Errorf("failed to save setting: %w", err)
+	}
+
+	return nil
+}
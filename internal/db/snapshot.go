@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sqliter/internal/models"
+	"time"
+)
+
+// SnapshotTimeout is how long an unused snapshot session stays open.
+const SnapshotTimeout = 5 * time.Minute
+
+// snapshot is an open read transaction shared by the pages of one browse session.
+type snapshot struct {
+	tx        *sql.Tx
+	timer     *time.Timer
+	expiresAt time.Time
+}
+
+// BeginSnapshot opens a read transaction and returns a token that pins all
+// subsequent reads made with it to the same view of the database. Writers
+// are only able to proceed concurrently in WAL mode.
+func (s *SQLiteDB) BeginSnapshot() (*models.Snapshot, error) {
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate snapshot token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin snapshot: %w", err)
+	}
+
+	// A deferred transaction only takes its snapshot on the first read
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM sqlite_master").Scan(&count); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to begin snapshot: %w", err)
+	}
+
+	snap := &snapshot{tx: tx, expiresAt: time.Now().Add(SnapshotTimeout)}
+	snap.timer = time.AfterFunc(SnapshotTimeout, func() { s.CloseSnapshot(token) })
+
+	s.mu.Lock()
+	s.snapshots[token] = snap
+	s.mu.Unlock()
+
+	return &models.Snapshot{Token: token, ExpiresAt: snap.expiresAt}, nil
+}
+
+// snapshotTx returns the transaction of an open snapshot and extends its timeout.
+func (s *SQLiteDB) snapshotTx(token string) (*sql.Tx, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap, ok := s.snapshots[token]
+	if !ok {
+		return nil, &NotFoundError{Kind: "snapshot", Name: token}
+	}
+	snap.timer.Reset(SnapshotTimeout)
+	snap.expiresAt = time.Now().Add(SnapshotTimeout)
+
+	return snap.tx, nil
+}
+
+// CloseSnapshot ends a snapshot session and releases its read transaction.
+func (s *SQLiteDB) CloseSnapshot(token string) error {
+	s.mu.Lock()
+	snap, ok := s.snapshots[token]
+	delete(s.snapshots, token)
+	s.mu.Unlock()
+
+	if !ok {
+		return &NotFoundError{Kind: "snapshot", Name: token}
+	}
+
+	snap.timer.Stop()
+	if err := snap.tx.Rollback(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteDB) closeSnapshots() {
+	s.mu.Lock()
+	tokens := make([]string, 0, len(s.snapshots))
+	for token := range s.snapshots {
+		tokens = append(tokens, token)
+	}
+	s.mu.Unlock()
+
+	for _, token := range tokens {
+		s.CloseSnapshot(token)
+	}
+}
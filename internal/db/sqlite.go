@@ -1,23 +1,76 @@
 package db
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net/url"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"sqliter/internal/models"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
+// queryer is implemented by both *sql.DB and *sql.Tx so reads can run inside
+// a snapshot transaction.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 type SQLiteDB struct {
-	db       *sql.DB
+	db *sql.DB
+	// writer is a single connection that all writes go through, so they
+	// queue in the pool instead of failing with "database is locked" while
+	// another connection holds the write lock. Reads use the db pool.
+	writer   *sql.DB
+	path     string
 	filename string
+	readOnly bool
+
+	// mu guards the fields below
+	mu             sync.Mutex
+	lastCheckpoint *models.CheckpointResult
+	snapshots      map[string]*snapshot
+	// attachments maps the alias of each attached database to its path
+	attachments map[string]string
+	// history records console statements once EnableQueryHistory is called
+	history *queryHistory
+
+	initPragmas []string
+}
+
+// NewSQLiteDB opens the database at dbPath. Each PRAGMA in initPragmas is run
+// on every connection the pool opens.
+func NewSQLiteDB(dbPath string, initPragmas ...string) (*SQLiteDB, error) {
+	return openSQLiteDB(dbPath, dbPath, false, initPragmas)
 }
 
-func NewSQLiteDB(dbPath string) (*SQLiteDB, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+// NewReadOnlySQLiteDB opens the existing database at dbPath with mode=ro, so
+// SQLite itself refuses every write.
+func NewReadOnlySQLiteDB(dbPath string, initPragmas ...string) (*SQLiteDB, error) {
+	dsn := "file:" + (&url.URL{Path: dbPath}).EscapedPath() + "?mode=ro"
+	return openSQLiteDB(dbPath, dsn, true, initPragmas)
+}
+
+func openSQLiteDB(dbPath, dsn string, readOnly bool, initPragmas []string) (*SQLiteDB, error) {
+	name, err := driverWithPragmas(initPragmas)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(name, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -26,17 +79,54 @@ func NewSQLiteDB(dbPath string) (*SQLiteDB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	writer := db
+	if !readOnly {
+		if writer, err = sql.Open(name, dsn); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		writer.SetMaxOpenConns(1)
+	}
+
 	filename := filepath.Base(dbPath)
-	return &SQLiteDB{db: db, filename: filename}, nil
+	return &SQLiteDB{
+		db:          db,
+		writer:      writer,
+		path:        dbPath,
+		filename:    filename,
+		readOnly:    readOnly,
+		snapshots:   make(map[string]*snapshot),
+		attachments: make(map[string]string),
+		initPragmas: initPragmas,
+	}, nil
+}
+
+// ReadOnly reports whether the database was opened read-only.
+func (s *SQLiteDB) ReadOnly() bool {
+	return s.readOnly
+}
+
+// InitPragmas returns the PRAGMAs run on every new connection.
+func (s *SQLiteDB) InitPragmas() []string {
+	return s.initPragmas
+}
+
+// quoteIdentifier wraps an identifier in double quotes, escaping any embedded quotes.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
 }
 
 func (s *SQLiteDB) Close() error {
+	s.closeSnapshots()
+	if s.writer != s.db {
+		s.writer.Close()
+	}
 	return s.db.Close()
 }
 
 func (s *SQLiteDB) GetTables() ([]models.Table, error) {
-	query := `SELECT name, type FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%' ORDER BY name`
-	rows, err := s.db.Query(query)
+	query := `SELECT name, type FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%' AND substr(name, 1, ?) != ? ORDER BY name`
+	rows, err := s.db.Query(query, len(internalTablePrefix), internalTablePrefix)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tables: %w", err)
 	}
@@ -54,17 +144,23 @@ func (s *SQLiteDB) GetTables() ([]models.Table, error) {
 	return tables, nil
 }
 
-func (s *SQLiteDB) getUniqueConstraints(tableName string) (map[string]bool, error) {
+// getUniqueConstraints returns the columns that are unique on their own, and
+// the multi-column unique constraints of the table in schema, or wherever an
+// unqualified name finds it when schema is empty. Partial unique indexes only
+// guarantee uniqueness for some rows, so they are ignored.
+func (s *SQLiteDB) getUniqueConstraints(qr queryer, schema, tableName string) (map[string]bool, []models.UniqueConstraint, error) {
 	uniqueColumns := make(map[string]bool)
+	var composite []models.UniqueConstraint
 
 	// Get list of indexes for the table
-	indexQuery := fmt.Sprintf("PRAGMA index_list(%s)", tableName)
-	indexRows, err := s.db.Query(indexQuery)
+	indexQuery := fmt.Sprintf("PRAGMA %sindex_list(%s)", schemaPrefix(schema), quoteIdentifier(tableName))
+	indexRows, err := qr.Query(indexQuery)
 	if err != nil {
-		return uniqueColumns, err
+		return nil, nil, err
 	}
 	defer indexRows.Close()
 
+	var uniqueIndexes []string
 	for indexRows.Next() {
 		var seq int
 		var indexName string
@@ -73,89 +169,137 @@ func (s *SQLiteDB) getUniqueConstraints(tableName string) (map[string]bool, erro
 		var partial int
 
 		if err := indexRows.Scan(&seq, &indexName, &unique, &origin, &partial); err != nil {
-			return uniqueColumns, err
+			return nil, nil, err
 		}
 
-		// Only process unique indexes
-		if unique == 1 {
-			// Get columns for this unique index
-			infoQuery := fmt.Sprintf("PRAGMA index_info(%s)", indexName)
-			infoRows, err := s.db.Query(infoQuery)
-			if err != nil {
-				return uniqueColumns, err
-			}
+		// Only process full (non-partial) unique indexes
+		if unique == 1 && partial == 0 {
+			uniqueIndexes = append(uniqueIndexes, indexName)
+		}
+	}
+	if err := indexRows.Err(); err != nil {
+		return nil, nil, err
+	}
 
-			for infoRows.Next() {
-				var seqno int
-				var cid int
-				var columnName string
+	for _, indexName := range uniqueIndexes {
+		// Get columns for this unique index
+		infoQuery := fmt.Sprintf("PRAGMA %sindex_info(%s)", schemaPrefix(schema), quoteIdentifier(indexName))
+		infoRows, err := qr.Query(infoQuery)
+		if err != nil {
+			return nil, nil, err
+		}
 
-				if err := infoRows.Scan(&seqno, &cid, &columnName); err != nil {
-					infoRows.Close()
-					return uniqueColumns, err
-				}
+		var columns []string
+		hasExpression := false
+		for infoRows.Next() {
+			var seqno int
+			var cid int
+			var columnName sql.NullString
 
-				// Mark this column as unique (only for single-column unique constraints)
-				// For multi-column unique constraints, we'll skip marking individual columns
-				var columnCount int
-				countQuery := fmt.Sprintf("SELECT COUNT(*) FROM pragma_index_info('%s')", indexName)
-				if err := s.db.QueryRow(countQuery).Scan(&columnCount); err == nil && columnCount == 1 {
-					uniqueColumns[columnName] = true
-				}
+			if err := infoRows.Scan(&seqno, &cid, &columnName); err != nil {
+				infoRows.Close()
+				return nil, nil, err
 			}
-			infoRows.Close()
+			// Expression indexes have no column name
+			if !columnName.Valid {
+				hasExpression = true
+				continue
+			}
+			columns = append(columns, columnName.String)
+		}
+		infoRows.Close()
+
+		if hasExpression {
+			continue
+		}
+		if len(columns) == 1 {
+			uniqueColumns[columns[0]] = true
+		} else if len(columns) > 1 {
+			composite = append(composite, models.UniqueConstraint{Name: indexName, Columns: columns})
 		}
 	}
 
-	return uniqueColumns, nil
+	return uniqueColumns, composite, nil
+}
+
+// GetCompositeUniqueConstraints returns the table's multi-column unique constraints.
+func (s *SQLiteDB) GetCompositeUniqueConstraints(tableName string) ([]models.UniqueConstraint, error) {
+	_, composite, err := s.getUniqueConstraints(s.db, "", tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unique constraints: %w", err)
+	}
+	if composite == nil {
+		composite = []models.UniqueConstraint{}
+	}
+	return composite, nil
+}
+
+// ConstraintError is a constraint violation reported by SQLite. Code is one
+// of UNIQUE_VIOLATION, NOT_NULL_VIOLATION, FOREIGN_KEY_VIOLATION or
+// CHECK_VIOLATION, and Column names the offending column when SQLite reports
+// one. Message explains the violation to a person.
+type ConstraintError struct {
+	Code    string
+	Column  string
+	Message string
+	Err     error
+}
+
+func (e *ConstraintError) Error() string {
+	return e.Message
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// constraintColumn returns the column of a "table.column" constraint failure
+// detail, or "" when the detail names none, as for composite keys where
+// SQLite lists several columns.
+func constraintColumn(detail string) string {
+	if strings.Contains(detail, ",") {
+		return ""
+	}
+	parts := strings.Split(detail, ".")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
 }
 
 func (s *SQLiteDB) parseConstraintError(err error) error {
 	errMsg := err.Error()
 
 	// Handle UNIQUE constraint violations
-	if strings.Contains(errMsg, "UNIQUE constraint failed:") {
-		// Extract table and column from error message
-		// Format: "UNIQUE constraint failed: users.email"
-		parts := strings.Split(errMsg, "UNIQUE constraint failed: ")
-		if len(parts) >= 2 {
-			tableColumn := parts[1]
-			columnParts := strings.Split(tableColumn, ".")
-			if len(columnParts) >= 2 {
-				column := columnParts[1]
-				return fmt.Errorf("The value for '%s' already exists. This field must be unique.", column)
-			}
+	// Format: "UNIQUE constraint failed: users.email"
+	if _, detail, ok := strings.Cut(errMsg, "UNIQUE constraint failed: "); ok {
+		if column := constraintColumn(detail); column != "" {
+			return &ConstraintError{Code: "UNIQUE_VIOLATION", Column: column, Err: err,
+				Message: fmt.Sprintf("The value for '%s' already exists. This field must be unique.", column)}
 		}
-		return fmt.Errorf("A unique constraint was violated. This value already exists.")
+		return &ConstraintError{Code: "UNIQUE_VIOLATION", Err: err,
+			Message: "A unique constraint was violated. This value already exists."}
 	}
 
 	// Handle NOT NULL constraint violations
-	if strings.Contains(errMsg, "NOT NULL constraint failed:") {
-		parts := strings.Split(errMsg, "NOT NULL constraint failed: ")
-		if len(parts) >= 2 {
-			tableColumn := parts[1]
-			columnParts := strings.Split(tableColumn, ".")
-			if len(columnParts) >= 2 {
-				column := columnParts[1]
-				return fmt.Errorf("The field '%s' is required and cannot be empty.", column)
-			}
+	if _, detail, ok := strings.Cut(errMsg, "NOT NULL constraint failed: "); ok {
+		if column := constraintColumn(detail); column != "" {
+			return &ConstraintError{Code: "NOT_NULL_VIOLATION", Column: column, Err: err,
+				Message: fmt.Sprintf("The field '%s' is required and cannot be empty.", column)}
 		}
-		return fmt.Errorf("A required field is missing.")
+		return &ConstraintError{Code: "NOT_NULL_VIOLATION", Err: err, Message: "A required field is missing."}
 	}
 
 	// Handle FOREIGN KEY constraint violations
 	if strings.Contains(errMsg, "FOREIGN KEY constraint failed") {
-		return fmt.Errorf("This operation violates a foreign key constraint. The referenced record may not exist.")
+		return &ConstraintError{Code: "FOREIGN_KEY_VIOLATION", Err: err,
+			Message: "This operation violates a foreign key constraint. The referenced record may not exist."}
 	}
 
 	// Handle CHECK constraint violations
-	if strings.Contains(errMsg, "CHECK constraint failed:") {
-		parts := strings.Split(errMsg, "CHECK constraint failed: ")
-		if len(parts) >= 2 {
-			constraint := parts[1]
-			return fmt.Errorf("The value violates a check constraint: %s", constraint)
-		}
-		return fmt.Errorf("The value violates a check constraint.")
+	if _, constraint, ok := strings.Cut(errMsg, "CHECK constraint failed: "); ok {
+		return &ConstraintError{Code: "CHECK_VIOLATION", Err: err,
+			Message: fmt.Sprintf("The value violates a check constraint: %s", constraint)}
 	}
 
 	// Return original error if we can't parse it
@@ -163,8 +307,68 @@ func (s *SQLiteDB) parseConstraintError(err error) error {
 }
 
 func (s *SQLiteDB) GetTableSchema(tableName string) ([]models.Column, error) {
-	query := fmt.Sprintf("PRAGMA table_info(%s)", tableName)
-	rows, err := s.db.Query(query)
+	return s.getTableSchema(s.db, tableName)
+}
+
+// schemaPrefix qualifies names with schema, e.g. "other". for an attached
+// database; an empty schema leaves them unqualified.
+func schemaPrefix(schema string) string {
+	if schema == "" {
+		return ""
+	}
+	return quoteIdentifier(schema) + "."
+}
+
+// requireTable checks that a table or view with the exact name exists, so
+// that an unknown name is reported before it is used in a query.
+func requireTable(qr queryer, tableName string) error {
+	return requireSchemaTable(qr, "", tableName)
+}
+
+// requireSchemaTable is requireTable for a table in the given schema.
+func requireSchemaTable(qr queryer, schema, tableName string) error {
+	var count int
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %ssqlite_master WHERE type IN ('table', 'view') AND name = ?`, schemaPrefix(schema))
+	if err := qr.QueryRow(query, tableName).Scan(&count); err != nil {
+		return fmt.Errorf("failed to look up table: %w", err)
+	}
+	if count == 0 {
+		return &NotFoundError{Kind: "table", Name: tableName}
+	}
+	return nil
+}
+
+// requireColumns checks that every name is a column of the table or the
+// rowid. Unknown names must be rejected before they are quoted into a WHERE
+// clause, where SQLite would read a double-quoted unknown identifier as a
+// string literal. Like SQLite, names are matched case-insensitively.
+func requireColumns(columns []models.Column, names ...string) error {
+	known := map[string]bool{"rowid": true, "oid": true, "_rowid_": true}
+	for _, col := range columns {
+		known[strings.ToLower(col.Name)] = true
+	}
+	for _, name := range names {
+		if !known[strings.ToLower(name)] {
+			return &NotFoundError{Kind: "column", Name: name}
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteDB) getTableSchema(qr queryer, tableName string) ([]models.Column, error) {
+	return s.getSchemaTableSchema(qr, "", tableName)
+}
+
+// getSchemaTableSchema reads the columns of a table in the given schema, such
+// as an attached database; an empty schema finds the table like an
+// unqualified name would.
+func (s *SQLiteDB) getSchemaTableSchema(qr queryer, schema, tableName string) ([]models.Column, error) {
+	if err := requireSchemaTable(qr, schema, tableName); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("PRAGMA %stable_info(%s)", schemaPrefix(schema), quoteIdentifier(tableName))
+	rows, err := qr.Query(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get table schema: %w", err)
 	}
@@ -182,7 +386,7 @@ func (s *SQLiteDB) GetTableSchema(tableName string) ([]models.Column, error) {
 		}
 
 		col.NotNull = notNull == 1
-		col.PrimaryKey = pk == 1
+		col.PrimaryKey = pk > 0
 		if defaultValue.Valid {
 			col.DefaultValue = &defaultValue.String
 		}
@@ -191,7 +395,7 @@ func (s *SQLiteDB) GetTableSchema(tableName string) ([]models.Column, error) {
 	}
 
 	// Get unique constraints for the table
-	uniqueColumns, err := s.getUniqueConstraints(tableName)
+	uniqueColumns, _, err := s.getUniqueConstraints(qr, schema, tableName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get unique constraints: %w", err)
 	}
@@ -206,45 +410,129 @@ func (s *SQLiteDB) GetTableSchema(tableName string) ([]models.Column, error) {
 	return columns, nil
 }
 
-func (s *SQLiteDB) GetTableData(tableName string, limit, offset int, sortColumn, sortDirection, whereClause string) (*models.TableData, error) {
-	columns, err := s.GetTableSchema(tableName)
+func (s *SQLiteDB) GetTableData(tableName string, q models.TableQuery) (*models.TableData, error) {
+	// Read through the snapshot's transaction when paging within a snapshot session
+	var qr queryer = s.db
+	if q.Snapshot != "" {
+		tx, err := s.snapshotTx(q.Snapshot)
+		if err != nil {
+			return nil, err
+		}
+		qr = tx
+	}
+
+	columns, err := s.getTableSchema(qr, tableName)
 	if err != nil {
 		return nil, err
 	}
 
-	// Build the base query with optional WHERE clause
-	baseQuery := fmt.Sprintf("SELECT * FROM %s", tableName)
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName)
+	// Narrow the returned columns to the requested projection or the column cap
+	selected, truncated, err := projectColumns(columns, q)
+	if err != nil {
+		return nil, err
+	}
+	selectList := "*"
+	if len(selected) != len(columns) {
+		quoted := make([]string, len(selected))
+		for i, col := range selected {
+			quoted[i] = quoteIdentifier(col.Name)
+		}
+		selectList = strings.Join(quoted, ", ")
+	}
 
-	if whereClause != "" {
-		baseQuery += fmt.Sprintf(" WHERE %s", whereClause)
-		countQuery += fmt.Sprintf(" WHERE %s", whereClause)
+	// Add computed columns for the requested JSON paths
+	jsonColumns, selectArgs, err := s.jsonPathColumns(columns, q.JSONPaths)
+	if err != nil {
+		return nil, err
+	}
+	if jsonColumns != "" {
+		selectList += ", " + jsonColumns
 	}
 
-	// Get total row count with filtering
-	var total int
-	if err := s.db.QueryRow(countQuery).Scan(&total); err != nil {
-		return nil, fmt.Errorf("failed to get total row count: %w", err)
+	// Rows come in key order unless a sort is requested, in which case a page
+	// can be continued after the key of its last row
+	sorted := q.SortColumn != "" || len(q.Sort) > 0
+	keyset := !sorted && (q.DefaultSort || q.After != "")
+	if q.After != "" && sorted {
+		return nil, &CursorError{Reason: "a cursor can't be combined with sorting"}
 	}
 
-	// Build the query with optional sorting
-	query := baseQuery
-	if sortColumn != "" && sortDirection != "" {
-		// Validate sortColumn exists to prevent SQL injection
-		columnExists := false
-		for _, col := range columns {
-			if col.Name == sortColumn {
-				columnExists = true
-				break
+	// Add the key columns under aliases when rows should carry their key
+	var keys []string
+	if q.RowKeyFormat != "" || keyset {
+		keys, err = primaryKeyColumns(qr, tableName)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) > 0 {
+			selectList += ", " + rowKeySelect(keys)
+		}
+	}
+	if q.After != "" && len(keys) == 0 {
+		return nil, &CursorError{Reason: "views can't be paged by cursor"}
+	}
+
+	// Build the base query with optional WHERE clause
+	source, args := scopedSource(tableName, q.Scopes)
+	if len(q.Scopes) > 0 && len(keys) == 1 && keys[0] == "rowid" {
+		source, args = scopedRowidSource(tableName, q.Scopes)
+	}
+	baseQuery := fmt.Sprintf("SELECT %s FROM %s", selectList, source)
+
+	condition, filterArgs, err := filterCondition(columns, q)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, filterArgs...)
+
+	// Get total row count with filtering. COUNT(*) scans the whole table, so
+	// it can be estimated or skipped, and cursor paging always skips it.
+	total, estimated := -1, false
+	if q.After == "" && q.Count != models.CountSkip {
+		if q.Count == models.CountEstimate && condition == "" && len(q.Scopes) == 0 {
+			total, estimated, err = estimatedRowCount(qr, tableName)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !estimated {
+			total, err = s.countRows(qr, source, condition, args...)
+			if err != nil {
+				return nil, err
 			}
 		}
-		if !columnExists {
-			return nil, fmt.Errorf("invalid sort column: %s", sortColumn)
+	}
+	if q.After != "" {
+		cursorCondition, cursorArgs, err := cursorCondition(keys, q.After)
+		if err != nil {
+			return nil, err
+		}
+		if condition != "" {
+			condition = fmt.Sprintf("(%s) AND ", condition)
+		}
+		condition += cursorCondition
+		args = append(args, cursorArgs...)
+	}
+	if condition != "" {
+		baseQuery += fmt.Sprintf(" WHERE %s", condition)
+	}
+
+	// Build the query with optional sorting
+	orderBy, err := orderByClause(columns, q)
+	if err != nil {
+		return nil, err
+	}
+	limit := q.Limit
+	if keyset && len(keys) > 0 {
+		orderBy = keyOrderBy(keys)
+		// Fetch one more row to tell whether there is a next page
+		if limit > 0 {
+			limit++
 		}
-		query += fmt.Sprintf(" ORDER BY %s %s", sortColumn, strings.ToUpper(sortDirection))
 	}
-	query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
-	rows, err := s.db.Query(query)
+	query := baseQuery + orderBy
+	query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, q.Offset)
+	rows, err := qr.Query(query, append(selectArgs, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query table data: %w", err)
 	}
@@ -256,281 +544,1582 @@ func (s *SQLiteDB) GetTableData(tableName string, limit, offset int, sortColumn,
 	}
 
 	var data []models.Row
+	var lastKey map[string]interface{}
+	more := false
+	budget := responseBudget{max: q.MaxResponseBytes}
 	for rows.Next() {
-		values := make([]interface{}, len(columnNames))
-		valuePtrs := make([]interface{}, len(columnNames))
-		for i := range values {
-			valuePtrs[i] = &values[i]
+		if q.Limit > 0 && len(data) == q.Limit {
+			more = true
+			break
 		}
-
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+		row, err := scanRow(rows, columnNames)
+		if err != nil {
+			return nil, err
 		}
-
-		row := make(models.Row)
-		for i, col := range columnNames {
-			val := values[i]
-			if val != nil {
-				switch v := val.(type) {
-				case []byte:
-					row[col] = string(v)
-				default:
-					row[col] = v
-				}
-			} else {
-				row[col] = nil
+		var key map[string]interface{}
+		if len(keys) > 0 {
+			key = takeRowKey(row, keys)
+			if err := setRowKey(row, key, q.RowKeyFormat); err != nil {
+				return nil, err
 			}
 		}
+		if !budget.fits(row) {
+			more = true
+			break
+		}
 		data = append(data, row)
+		lastKey = key
 	}
 
-	return &models.TableData{
-		Columns: columns,
-		Rows:    data,
-		Total:   total,
-	}, nil
-}
-
-func (s *SQLiteDB) InsertRow(tableName string, data map[string]interface{}) error {
-	if len(data) == 0 {
-		return fmt.Errorf("no data provided")
+	result := &models.TableData{
+		Columns:          selected,
+		Rows:             data,
+		Total:            total,
+		TotalEstimated:   estimated,
+		ColumnsTruncated: truncated,
+		TruncatedBySize:  budget.exceeded,
+	}
+	if total >= 0 {
+		result.Page, result.TotalPages = pageNumbers(q.Offset, q.Limit, total)
+	}
+	if keyset && more && lastKey != nil {
+		if result.NextCursor, err = encodeRowKey(lastKey); err != nil {
+			return nil, err
+		}
+	}
+	if result.ViewSQL, err = viewDefinition(qr, tableName); err != nil {
+		return nil, err
 	}
 
-	columns := make([]string, 0, len(data))
-	placeholders := make([]string, 0, len(data))
-	values := make([]interface{}, 0, len(data))
+	return result, nil
+}
 
-	for col, val := range data {
-		columns = append(columns, col)
-		placeholders = append(placeholders, "?")
-		values = append(values, val)
+// scanRow scans the current result row into a column-keyed map. Values stay
+// as the driver returns them, with BLOBs as []byte, so row keys and diffs can
+// bind and compare them; models.Row renders them when encoded as JSON.
+func scanRow(rows *sql.Rows, columnNames []string) (models.Row, error) {
+	values := make([]interface{}, len(columnNames))
+	valuePtrs := make([]interface{}, len(columnNames))
+	for i := range values {
+		valuePtrs[i] = &values[i]
 	}
 
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		tableName,
-		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "))
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
 
-	_, err := s.db.Exec(query, values...)
-	if err != nil {
-		return s.parseConstraintError(err)
+	row := make(models.Row)
+	for i, col := range columnNames {
+		val := values[i]
+		if val != nil {
+			row[col] = val
+		} else {
+			row[col] = nil
+		}
 	}
 
-	return nil
+	return row, nil
 }
 
-func (s *SQLiteDB) UpdateRow(tableName string, data map[string]interface{}, where map[string]interface{}) error {
-	if len(data) == 0 {
-		return fmt.Errorf("no data provided")
+// responseBudget tracks the JSON size of the rows added to a response, so huge
+// cells can't produce a response of hundreds of megabytes within the row limit.
+type responseBudget struct {
+	// max is the byte limit; 0 means no limit.
+	max      int
+	used     int
+	exceeded bool
+}
+
+// fits reports whether the row can be added without exceeding the budget, and
+// counts it if so. Once a row doesn't fit, no later row does either.
+func (b *responseBudget) fits(row interface{}) bool {
+	if b.max <= 0 {
+		return true
 	}
-	if len(where) == 0 {
-		return fmt.Errorf("no where clause provided")
+	if b.exceeded {
+		return false
 	}
-
-	setParts := make([]string, 0, len(data))
-	values := make([]interface{}, 0, len(data)+len(where))
-
-	for col, val := range data {
-		setParts = append(setParts, fmt.Sprintf("%s = ?", col))
-		values = append(values, val)
+	encoded, err := json.Marshal(row)
+	if err != nil || b.used+len(encoded) > b.max {
+		b.exceeded = true
+		return false
 	}
+	b.used += len(encoded)
+	return true
+}
 
-	whereParts := make([]string, 0, len(where))
-	for col, val := range where {
-		whereParts = append(whereParts, fmt.Sprintf("%s = ?", col))
-		values = append(values, val)
+// pageNumbers returns the 1-based page an offset falls on and the number of
+// pages needed for total rows. Without a positive limit there are no pages.
+func pageNumbers(offset, limit, total int) (int, int) {
+	if limit <= 0 {
+		return 0, 0
 	}
+	return offset/limit + 1, (total + limit - 1) / limit
+}
 
-	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
-		tableName,
-		strings.Join(setParts, ", "),
-		strings.Join(whereParts, " AND "))
+// projectColumns returns the schema columns a table query should return: the
+// requested projection if any, otherwise the first MaxColumns columns. The
+// boolean result reports whether columns were dropped because of MaxColumns.
+func projectColumns(columns []models.Column, q models.TableQuery) ([]models.Column, bool, error) {
+	if len(q.Columns) > 0 {
+		byName := make(map[string]models.Column, len(columns))
+		for _, col := range columns {
+			byName[col.Name] = col
+		}
 
-	_, err := s.db.Exec(query, values...)
-	if err != nil {
-		return s.parseConstraintError(err)
+		selected := make([]models.Column, 0, len(q.Columns))
+		for _, name := range q.Columns {
+			col, ok := byName[name]
+			if !ok {
+				return nil, false, fmt.Errorf("invalid column: %s", name)
+			}
+			selected = append(selected, col)
+		}
+		return selected, false, nil
 	}
 
-	return nil
-}
-
-func (s *SQLiteDB) DeleteRow(tableName string, where map[string]interface{}) error {
-	if len(where) == 0 {
-		return fmt.Errorf("no where clause provided")
+	if q.MaxColumns > 0 && len(columns) > q.MaxColumns {
+		return columns[:q.MaxColumns], true, nil
 	}
 
-	whereParts := make([]string, 0, len(where))
-	values := make([]interface{}, 0, len(where))
+	return columns, false, nil
+}
 
-	for col, val := range where {
-		whereParts = append(whereParts, fmt.Sprintf("%s = ?", col))
-		values = append(values, val)
-	}
+// sortCollations lists the collations accepted for sorting.
+var sortCollations = map[string]bool{
+	"BINARY": true,
+	"NOCASE": true,
+	"RTRIM":  true,
+}
 
-	query := fmt.Sprintf("DELETE FROM %s WHERE %s",
-		tableName,
-		strings.Join(whereParts, " AND "))
+// IsValidCollation reports whether a collation may be used for sorting.
+func IsValidCollation(collation string) bool {
+	return sortCollations[strings.ToUpper(collation)]
+}
 
-	_, err := s.db.Exec(query, values...)
-	if err != nil {
-		return s.parseConstraintError(err)
+// hasTextAffinity reports whether a declared column type gets TEXT affinity.
+func hasTextAffinity(columnType string) bool {
+	columnType = strings.ToUpper(columnType)
+	if strings.Contains(columnType, "INT") {
+		return false
 	}
-
-	return nil
+	return strings.Contains(columnType, "CHAR") ||
+		strings.Contains(columnType, "CLOB") ||
+		strings.Contains(columnType, "TEXT")
 }
 
-func (s *SQLiteDB) GetDatabaseInfo() (*models.DatabaseInfo, error) {
-	return &models.DatabaseInfo{
-		Filename: s.filename,
-	}, nil
+// SortError reports a sort on a column the table doesn't have.
+type SortError struct {
+	Column string
 }
 
-func (s *SQLiteDB) ExecuteSQL(sqlQuery string) (*models.SQLQueryResult, error) {
-	// Trim whitespace and check if query is empty
-	sqlQuery = strings.TrimSpace(sqlQuery)
-	if sqlQuery == "" {
-		return nil, fmt.Errorf("empty SQL query")
-	}
+func (e *SortError) Error() string {
+	return fmt.Sprintf("invalid sort column: %s", e.Column)
+}
 
-	// Detect if this is likely a data-returning query by checking the first word
-	normalizedQuery := strings.ToUpper(strings.TrimSpace(sqlQuery))
-	// Remove leading comments
-	for strings.HasPrefix(normalizedQuery, "--") {
-		if idx := strings.Index(normalizedQuery, "\n"); idx != -1 {
-			normalizedQuery = strings.TrimSpace(normalizedQuery[idx+1:])
-		} else {
-			break
-		}
+// sortTerms returns the columns a table query is sorted by: Sort when given,
+// otherwise the single SortColumn and SortDirection.
+func sortTerms(q models.TableQuery) []models.SortTerm {
+	if len(q.Sort) > 0 {
+		return q.Sort
 	}
-	for strings.HasPrefix(normalizedQuery, "/*") {
-		if idx := strings.Index(normalizedQuery, "*/"); idx != -1 {
-			normalizedQuery = strings.TrimSpace(normalizedQuery[idx+2:])
-		} else {
-			break
-		}
+	if q.SortColumn == "" || q.SortDirection == "" {
+		return nil
 	}
+	return []models.SortTerm{{Column: q.SortColumn, Direction: q.SortDirection}}
+}
 
-	// Check if this is likely a SELECT-type query
-	isSelectQuery := strings.HasPrefix(normalizedQuery, "SELECT") ||
-		strings.HasPrefix(normalizedQuery, "WITH") ||
-		strings.HasPrefix(normalizedQuery, "EXPLAIN") ||
-		(strings.HasPrefix(normalizedQuery, "PRAGMA") &&
-		 (strings.Contains(normalizedQuery, "TABLE_INFO") ||
-		  strings.Contains(normalizedQuery, "INDEX_LIST") ||
-		  strings.Contains(normalizedQuery, "INDEX_INFO")))
-
-	if isSelectQuery {
-		// Execute as SELECT query
-		rows, err := s.db.Query(sqlQuery)
-		if err != nil {
-			return nil, fmt.Errorf("failed to execute query: %w", err)
-		}
-		defer rows.Close()
+// hasNumericAffinity reports whether a declared column type gets INTEGER,
+// REAL or NUMERIC affinity, following SQLite's affinity rules.
+func hasNumericAffinity(columnType string) bool {
+	columnType = strings.ToUpper(columnType)
+	if strings.Contains(columnType, "INT") {
+		return true
+	}
+	return columnType != "" && !hasTextAffinity(columnType) && !strings.Contains(columnType, "BLOB")
+}
 
-		columnNames, err := rows.Columns()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get column names: %w", err)
+// orderByClause builds the ORDER BY clause for a table query, validating each
+// sort column against the table schema. A collation is only applied to text
+// columns. Without an explicit sort, DefaultSort orders by the primary key.
+func orderByClause(columns []models.Column, q models.TableQuery) (string, error) {
+	terms := sortTerms(q)
+	if len(terms) == 0 {
+		if !q.DefaultSort {
+			return "", nil
 		}
 
-		var resultRows [][]interface{}
-		for rows.Next() {
-			values := make([]interface{}, len(columnNames))
-			valuePtrs := make([]interface{}, len(columnNames))
-			for i := range values {
-				valuePtrs[i] = &values[i]
+		var primaryKeys []string
+		for _, col := range columns {
+			if col.PrimaryKey {
+				primaryKeys = append(primaryKeys, quoteIdentifier(col.Name)+" ASC")
 			}
+		}
+		if len(primaryKeys) == 0 {
+			return "", nil
+		}
+		return " ORDER BY " + strings.Join(primaryKeys, ", "), nil
+	}
 
-			if err := rows.Scan(valuePtrs...); err != nil {
-				return nil, fmt.Errorf("failed to scan row: %w", err)
-			}
+	if q.Collation != "" && !IsValidCollation(q.Collation) {
+		return "", fmt.Errorf("invalid collation: %s", q.Collation)
+	}
 
-			row := make([]interface{}, len(columnNames))
-			for i, val := range values {
-				if val != nil {
-					switch v := val.(type) {
-					case []byte:
-						row[i] = string(v)
-					default:
-						row[i] = v
-					}
-				} else {
-					row[i] = nil
-				}
+	clauses := make([]string, len(terms))
+	for i, t := range terms {
+		// Validate the column exists to prevent SQL injection
+		var sortCol *models.Column
+		for j := range columns {
+			if columns[j].Name == t.Column {
+				sortCol = &columns[j]
+				break
 			}
-			resultRows = append(resultRows, row)
+		}
+		if sortCol == nil {
+			return "", &SortError{Column: t.Column}
 		}
 
-		return &models.SQLQueryResult{
-			Columns:  columnNames,
-			Rows:     resultRows,
-			RowCount: len(resultRows),
-		}, nil
-	} else {
-		// Execute as non-SELECT query (INSERT, UPDATE, DELETE, etc.)
-		result, err := s.db.Exec(sqlQuery)
-		if err != nil {
-			return nil, s.parseConstraintError(err)
+		direction := strings.ToUpper(t.Direction)
+		if direction != "ASC" && direction != "DESC" {
+			return "", fmt.Errorf("invalid sort direction: %s", t.Direction)
 		}
 
-		rowsAffected, _ := result.RowsAffected()
-		return &models.SQLQueryResult{
-			Columns:      []string{"rows_affected"},
-			Rows:         [][]interface{}{{rowsAffected}},
-			RowCount:     1,
-			RowsAffected: int(rowsAffected),
-		}, nil
+		term := quoteIdentifier(t.Column)
+		if q.Collation != "" && hasTextAffinity(sortCol.Type) {
+			term += " COLLATE " + strings.ToUpper(q.Collation)
+		}
+		clauses[i] = term + " " + direction
 	}
+
+	return " ORDER BY " + strings.Join(clauses, ", "), nil
 }
 
+// filterCondition combines the structured filters, the raw where clause and
+// the search term into a single WHERE condition and its bound arguments.
+func filterCondition(columns []models.Column, q models.TableQuery) (string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
 
-func (s *SQLiteDB) ExportTableCSV(tableName, sortColumn, sortDirection, whereClause string, writer *csv.Writer) error {
-	columns, err := s.GetTableSchema(tableName)
-	if err != nil {
-		return err
+	if len(q.Filters) > 0 {
+		condition, filterArgs, err := filtersCondition(columns, q.Filters)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, filterArgs...)
+	}
+	if q.WhereClause != "" {
+		conditions = append(conditions, "("+q.WhereClause+")")
+	}
+	if q.Search != "" {
+		condition, searchArgs := searchCondition(columns, q.Search, q.Fold)
+		conditions = append(conditions, condition)
+		args = append(args, searchArgs...)
 	}
 
-	// Build the base query with optional WHERE clause
-	baseQuery := fmt.Sprintf("SELECT * FROM %s", tableName)
+	return strings.Join(conditions, " AND "), args, nil
+}
 
-	if whereClause != "" {
-		baseQuery += fmt.Sprintf(" WHERE %s", whereClause)
+// searchCondition matches the term as a substring of any text column. With
+// fold set, both sides are case-folded and stripped of diacritics first.
+func searchCondition(columns []models.Column, term string, fold bool) (string, []interface{}) {
+	if fold {
+		term = foldText(term)
 	}
+	pattern := "%" + likeEscaper.Replace(term) + "%"
 
-	// Build the query with optional sorting
-	query := baseQuery
-	if sortColumn != "" && sortDirection != "" {
-		// Validate sortColumn exists to prevent SQL injection
-		columnExists := false
-		for _, col := range columns {
-			if col.Name == sortColumn {
-				columnExists = true
-				break
-			}
+	var parts []string
+	var args []interface{}
+	for _, col := range columns {
+		if !hasTextAffinity(col.Type) {
+			continue
 		}
-		if !columnExists {
-			return fmt.Errorf("invalid sort column: %s", sortColumn)
+		operand := quoteIdentifier(col.Name)
+		if fold {
+			operand = foldFunctionName + "(" + operand + ")"
 		}
-		query += fmt.Sprintf(" ORDER BY %s %s", sortColumn, strings.ToUpper(sortDirection))
+		parts = append(parts, operand+` LIKE ? ESCAPE '\'`)
+		args = append(args, pattern)
 	}
 
-	rows, err := s.db.Query(query)
-	if err != nil {
-		return fmt.Errorf("failed to query table data: %w", err)
+	// A table without text columns can't match any search term
+	if len(parts) == 0 {
+		return "0", nil
 	}
-	defer rows.Close()
+
+	return "(" + strings.Join(parts, " OR ") + ")", args
+}
+
+// scopeCondition renders server-side scopes as an AND-ed equality condition
+// and its bound arguments.
+func scopeCondition(scopes []models.Scope) (string, []interface{}) {
+	conditions := make([]string, len(scopes))
+	args := make([]interface{}, len(scopes))
+	for i, scope := range scopes {
+		conditions[i] = quoteIdentifier(scope.Column) + " = ?"
+		args[i] = scope.Value
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// scopedSource returns the FROM source for a table. Scoped tables are read
+// through a subquery so a client where clause can only narrow the scoped rows,
+// never widen them.
+func scopedSource(tableName string, scopes []models.Scope) (string, []interface{}) {
+	if len(scopes) == 0 {
+		return quoteIdentifier(tableName), nil
+	}
+	condition, args := scopeCondition(scopes)
+	return fmt.Sprintf("(SELECT * FROM %s WHERE %s) AS %s",
+		quoteIdentifier(tableName), condition, quoteIdentifier(tableName)), args
+}
+
+// scopedRowidSource is scopedSource for reads that need the rowid, which the
+// scope subquery's SELECT * doesn't carry through.
+func scopedRowidSource(tableName string, scopes []models.Scope) (string, []interface{}) {
+	condition, args := scopeCondition(scopes)
+	return fmt.Sprintf("(SELECT rowid AS rowid, * FROM %s WHERE %s) AS %s",
+		quoteIdentifier(tableName), condition, quoteIdentifier(tableName)), args
+}
+
+func (s *SQLiteDB) CountRows(tableName, whereClause string, scopes ...models.Scope) (int, error) {
+	source, args := scopedSource(tableName, scopes)
+	return s.countRows(s.db, source, whereClause, args...)
+}
+
+// CountTableRows counts the rows matching a table query's filters, search
+// term and scopes.
+func (s *SQLiteDB) CountTableRows(tableName string, q models.TableQuery) (int, error) {
+	columns, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+
+	source, args := scopedSource(tableName, q.Scopes)
+	condition, filterArgs, err := filterCondition(columns, q)
+	if err != nil {
+		return 0, err
+	}
+	return s.countRows(s.db, source, condition, append(args, filterArgs...)...)
+}
+
+// estimatedRowCount returns the table's row count as recorded by ANALYZE in
+// sqlite_stat1, and whether there is one.
+func estimatedRowCount(qr queryer, tableName string) (int, bool, error) {
+	var hasStats bool
+	if err := qr.QueryRow(`SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'sqlite_stat1'`).Scan(&hasStats); err != nil {
+		return 0, false, fmt.Errorf("failed to look up statistics: %w", err)
+	}
+	if !hasStats {
+		return 0, false, nil
+	}
+
+	// The stat of the table itself, and of every index that isn't partial,
+	// starts with the table's row count; a partial index only counts the
+	// rows it covers
+	var stat string
+	query := `SELECT stat FROM sqlite_stat1 WHERE tbl = ?
+		AND (idx IS NULL OR idx IN (SELECT name FROM pragma_index_list(?) WHERE partial = 0))
+		ORDER BY idx IS NOT NULL LIMIT 1`
+	err := qr.QueryRow(query, tableName, tableName).Scan(&stat)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read statistics: %w", err)
+	}
+
+	fields := strings.Fields(stat)
+	if len(fields) == 0 {
+		return 0, false, nil
+	}
+	count, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, false, nil
+	}
+	return count, true, nil
+}
+
+// countRows counts the rows of a FROM source, as built by scopedSource.
+func (s *SQLiteDB) countRows(qr queryer, source, whereClause string, args ...interface{}) (int, error) {
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", source)
+	if whereClause != "" {
+		countQuery += fmt.Sprintf(" WHERE %s", whereClause)
+	}
+
+	var total int
+	if err := qr.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to get total row count: %w", err)
+	}
+
+	return total, nil
+}
+
+// defaultValueKey marks an insert value ({"__default__": true}) that asks for
+// the column's schema default instead of a bound value.
+const defaultValueKey = "__default__"
+
+// isDefaultSentinel reports whether an insert value is the DEFAULT sentinel.
+func isDefaultSentinel(value interface{}) bool {
+	sentinel, ok := value.(map[string]interface{})
+	return ok && len(sentinel) == 1 && sentinel[defaultValueKey] == true
+}
+
+// insertStatement builds an INSERT for the given columns. SQLite has no
+// DEFAULT keyword inside VALUES, so columns set to the DEFAULT sentinel are
+// left out of the column list, which makes SQLite apply their defaults.
+func insertStatement(tableName string, columns []string, values []interface{}) (string, []interface{}) {
+	var names, placeholders []string
+	var args []interface{}
+	for i, col := range columns {
+		if isDefaultSentinel(values[i]) {
+			continue
+		}
+		names = append(names, quoteIdentifier(col))
+		placeholders = append(placeholders, "?")
+		args = append(args, values[i])
+	}
+
+	if len(names) == 0 {
+		return fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", quoteIdentifier(tableName)), nil
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		quoteIdentifier(tableName),
+		strings.Join(names, ", "),
+		strings.Join(placeholders, ", ")), args
+}
+
+func (s *SQLiteDB) InsertRow(tableName string, data map[string]interface{}) error {
+	if len(data) == 0 {
+		return fmt.Errorf("no data provided")
+	}
+
+	columns := make([]string, 0, len(data))
+	values := make([]interface{}, 0, len(data))
+
+	for col, val := range data {
+		columns = append(columns, col)
+		values = append(values, val)
+	}
+
+	if err := s.checkLengths(tableName, columns, values); err != nil {
+		return err
+	}
+
+	query, args := insertStatement(tableName, columns, values)
+
+	_, err := s.writer.Exec(query, args...)
+	if err != nil {
+		return s.parseConstraintError(err)
+	}
+
+	return nil
+}
+
+// InsertRowValues inserts a single row from positional values, keeping the
+// column order given by the caller.
+func (s *SQLiteDB) InsertRowValues(tableName string, columns []string, values []interface{}) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("no columns provided")
+	}
+	if len(columns) != len(values) {
+		return fmt.Errorf("expected %d values, got %d", len(columns), len(values))
+	}
+
+	if err := s.checkLengths(tableName, columns, values); err != nil {
+		return err
+	}
+
+	query, args := insertStatement(tableName, columns, values)
+
+	if _, err := s.writer.Exec(query, args...); err != nil {
+		return s.parseConstraintError(err)
+	}
+
+	return nil
+}
+
+// RowError reports the row of a batch insert that failed. The whole batch
+// was rolled back.
+type RowError struct {
+	// Index is the 0-based position of the failed row.
+	Index int
+	Err   error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Index, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// InsertRows inserts many rows sharing the same column list. The INSERT is
+// prepared once and executed per row inside a single transaction, which avoids
+// re-parsing the SQL and per-row commits. Inserting 2,000 rows takes ~10ms this
+// way versus ~1.7s when calling InsertRow in a loop (BenchmarkInsertRows vs
+// BenchmarkInsertRowLoop). If any row fails the whole batch is rolled back.
+func (s *SQLiteDB) InsertRows(tableName string, columns []string, rows [][]interface{}) (int64, error) {
+	inserted, _, err := s.insertRows(tableName, columns, rows, false)
+	return inserted, err
+}
+
+// InsertRowsReturning inserts rows like InsertRows and returns every inserted
+// row as stored, including generated ids and defaults, in insertion order.
+func (s *SQLiteDB) InsertRowsReturning(tableName string, columns []string, rows [][]interface{}) ([]models.Row, error) {
+	_, returned, err := s.insertRows(tableName, columns, rows, true)
+	return returned, err
+}
+
+// BulkInsert inserts rows given as column/value maps in a single transaction,
+// reusing one prepared statement. Every row must set the same columns. If any
+// row fails the whole batch is rolled back and a RowError names the row.
+func (s *SQLiteDB) BulkInsert(tableName string, rows []map[string]interface{}) (int64, error) {
+	columns, values, err := s.bulkValues(tableName, rows)
+	if err != nil {
+		return 0, err
+	}
+	return s.InsertRows(tableName, columns, values)
+}
+
+// BulkInsertReturning inserts rows like BulkInsert and returns every inserted
+// row as stored, including generated ids and defaults, in insertion order.
+func (s *SQLiteDB) BulkInsertReturning(tableName string, rows []map[string]interface{}) ([]models.Row, error) {
+	columns, values, err := s.bulkValues(tableName, rows)
+	if err != nil {
+		return nil, err
+	}
+	return s.InsertRowsReturning(tableName, columns, values)
+}
+
+// bulkValues turns column/value maps into the columns they set, in sorted
+// order, and each row's values for them.
+func (s *SQLiteDB) bulkValues(tableName string, rows []map[string]interface{}) ([]string, [][]interface{}, error) {
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("no rows provided")
+	}
+
+	columns := make([]string, 0, len(rows[0]))
+	for col := range rows[0] {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	schema, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := requireColumns(schema, columns...); err != nil {
+		return nil, nil, err
+	}
+
+	values := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return nil, nil, &RowError{Index: i, Err: fmt.Errorf("expected the same %d columns as row 0, got %d", len(columns), len(row))}
+		}
+		values[i] = make([]interface{}, len(columns))
+		for j, col := range columns {
+			value, ok := row[col]
+			if !ok {
+				return nil, nil, &RowError{Index: i, Err: fmt.Errorf("missing column '%s' set in row 0", col)}
+			}
+			values[i][j] = value
+		}
+	}
+
+	return columns, values, nil
+}
+
+func (s *SQLiteDB) insertRows(tableName string, columns []string, rows [][]interface{}, returning bool) (int64, []models.Row, error) {
+	insert, err := s.prepareInsert(tableName, columns, returning)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer insert.close()
+
+	var inserted int64
+	var returned []models.Row
+	for i, row := range rows {
+		rowReturned, err := insert.insert(i, row)
+		if err != nil {
+			return 0, nil, err
+		}
+		returned = append(returned, rowReturned...)
+		inserted++
+	}
+
+	if err := insert.commit(); err != nil {
+		return 0, nil, err
+	}
+
+	return inserted, returned, nil
+}
+
+// preparedInsert is an INSERT of a fixed set of columns, prepared once in a
+// transaction on the writer connection. Bulk inserts and CSV imports share it
+// so they check lengths, parse constraint errors and report rows alike.
+type preparedInsert struct {
+	s         *SQLiteDB
+	tx        *sql.Tx
+	stmt      *sql.Stmt
+	columns   []string
+	limits    map[string]int
+	returning bool
+}
+
+// prepareInsert begins the transaction and prepares the INSERT. With
+// returning, each insert returns the row as stored. The caller must close it.
+func (s *SQLiteDB) prepareInsert(tableName string, columns []string, returning bool) (*preparedInsert, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns provided")
+	}
+
+	quotedColumns := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, col := range columns {
+		quotedColumns[i] = quoteIdentifier(col)
+		placeholders[i] = "?"
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		quoteIdentifier(tableName),
+		strings.Join(quotedColumns, ", "),
+		strings.Join(placeholders, ", "))
+	if returning {
+		query += " RETURNING *"
+	}
+
+	limits, err := s.columnLengthLimits(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.writer.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to prepare insert: %w", err)
+	}
+
+	return &preparedInsert{s: s, tx: tx, stmt: stmt, columns: columns, limits: limits, returning: returning}, nil
+}
+
+// insert adds one row, index being its position in the batch. A failure only
+// undoes this row, so the transaction can carry on with the next one.
+func (p *preparedInsert) insert(index int, row []interface{}) ([]models.Row, *RowError) {
+	if len(row) != len(p.columns) {
+		return nil, &RowError{Index: index, Err: fmt.Errorf("expected %d values, got %d", len(p.columns), len(row))}
+	}
+	for j, col := range p.columns {
+		if err := checkLength(p.limits, col, row[j]); err != nil {
+			return nil, &RowError{Index: index, Err: err}
+		}
+	}
+
+	if !p.returning {
+		if _, err := p.stmt.Exec(row...); err != nil {
+			return nil, &RowError{Index: index, Err: p.s.parseConstraintError(err)}
+		}
+		return nil, nil
+	}
+
+	returned, err := queryReturning(p.stmt, row)
+	if err != nil {
+		return nil, &RowError{Index: index, Err: p.s.parseConstraintError(err)}
+	}
+	return returned, nil
+}
+
+func (p *preparedInsert) commit() error {
+	if err := p.tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// close releases the statement and rolls back the transaction unless it was
+// committed.
+func (p *preparedInsert) close() {
+	p.stmt.Close()
+	p.tx.Rollback()
+}
+
+// queryReturning runs a statement with a RETURNING clause and collects the
+// rows it returns.
+func queryReturning(stmt *sql.Stmt, args []interface{}) ([]models.Row, error) {
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
 	columnNames, err := rows.Columns()
 	if err != nil {
-		return fmt.Errorf("failed to get column names: %w", err)
+		return nil, err
+	}
+
+	var returned []models.Row
+	for rows.Next() {
+		row, err := scanRow(rows, columnNames)
+		if err != nil {
+			return nil, err
+		}
+		returned = append(returned, row)
+	}
+
+	return returned, rows.Err()
+}
+
+// UpdateRow updates the rows matching where. The matching rows are read
+// first and only the submitted fields that actually differ are written; if
+// none differ the write is skipped and the result is marked as a no-op. When a
+// single row matches, the result also holds the before/after value of each
+// changed field.
+func (s *SQLiteDB) UpdateRow(tableName string, data map[string]interface{}, where map[string]interface{}) (*models.UpdateResult, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no data provided")
+	}
+	if len(where) == 0 {
+		return nil, fmt.Errorf("no where clause provided")
+	}
+
+	columns, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := lengthLimits(columns)
+	for col, val := range data {
+		if err := requireColumns(columns, col); err != nil {
+			return nil, err
+		}
+		if err := checkLength(limits, col, val); err != nil {
+			return nil, err
+		}
+		// BLOBs come back from clients as they were rendered in table data
+		if data[col], err = blobValue(val); err != nil {
+			return nil, err
+		}
+	}
+
+	whereClause, whereValues, err := whereEquals(columns, where)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.writer.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	diff, err := diffRows(tx, tableName, columns, data, whereClause, whereValues)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.UpdateResult{Changes: map[string]models.FieldChange{}}
+	if diff.matched == 0 {
+		return result, nil
+	}
+
+	// Only write the fields that differ in at least one matching row
+	setParts := make([]string, 0, len(data))
+	values := make([]interface{}, 0, len(data)+len(where))
+	for col, val := range data {
+		if !diff.changed[col] {
+			continue
+		}
+
+		setParts = append(setParts, fmt.Sprintf("%s = ?", quoteIdentifier(col)))
+		values = append(values, val)
+		if diff.matched == 1 {
+			result.Changes[col] = models.FieldChange{Before: diff.first[col], After: val}
+		}
+	}
+
+	if len(setParts) == 0 {
+		result.Noop = true
+		return result, nil
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		quoteIdentifier(tableName),
+		strings.Join(setParts, ", "),
+		whereClause)
+
+	execResult, err := tx.Exec(query, append(values, whereValues...)...)
+	if err != nil {
+		return nil, s.parseConstraintError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.RowsAffected, _ = execResult.RowsAffected()
+	return result, nil
+}
+
+// rowDiff summarizes how the rows matched by an update differ from the
+// submitted values.
+type rowDiff struct {
+	// matched is the number of matching rows read, which stops early once
+	// several rows matched and every column changed
+	matched int
+	// first holds the submitted columns of the first matching row
+	first models.Row
+	// changed marks the columns that differ in at least one matching row
+	changed map[string]bool
+}
+
+// diffRows compares the rows matching where with the submitted values. Only
+// the submitted columns are read, and rows are streamed rather than loaded,
+// stopping as soon as the outcome can't change.
+func diffRows(qr queryer, tableName string, columns []models.Column, data map[string]interface{}, whereClause string, whereValues []interface{}) (*rowDiff, error) {
+	declared := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		declared[strings.ToLower(col.Name)] = true
+	}
+
+	names := make([]string, 0, len(data))
+	for col := range data {
+		names = append(names, col)
+	}
+	sort.Strings(names)
+	selected := make([]string, len(names))
+	for i, col := range names {
+		// A rowid alias stays unquoted, so that on a WITHOUT ROWID table
+		// SQLite reports it missing instead of reading it as a string
+		expression := strings.ToLower(col)
+		if declared[expression] {
+			expression = quoteIdentifier(col)
+		}
+		selected[i] = fmt.Sprintf("%s AS %s", expression, quoteIdentifier(col))
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(selected, ", "), quoteIdentifier(tableName), whereClause)
+	rows, err := qr.Query(query, whereValues...)
+	if err != nil {
+		// requireColumns accepts the rowid aliases, which WITHOUT ROWID
+		// tables don't have
+		if _, column, ok := strings.Cut(err.Error(), "no such column: "); ok {
+			return nil, &NotFoundError{Kind: "column", Name: column}
+		}
+		return nil, fmt.Errorf("failed to read current rows: %w", err)
+	}
+	defer rows.Close()
+
+	diff := &rowDiff{changed: make(map[string]bool, len(names))}
+	for rows.Next() {
+		row, err := scanRow(rows, names)
+		if err != nil {
+			return nil, err
+		}
+		if diff.matched == 0 {
+			diff.first = row
+		}
+		diff.matched++
+		for _, col := range names {
+			if !diff.changed[col] && !valuesEqual(row[col], data[col]) {
+				diff.changed[col] = true
+			}
+		}
+		if diff.matched > 1 && len(diff.changed) == len(names) {
+			break
+		}
+	}
+
+	return diff, rows.Err()
+}
+
+// valuesEqual compares a stored value with a submitted JSON value. Numbers
+// (and booleans, stored as 0/1) compare numerically, everything else by its
+// text form, matching how SQLite's type affinity would store the value.
+func valuesEqual(stored, submitted interface{}) bool {
+	if stored == nil || submitted == nil {
+		return stored == nil && submitted == nil
+	}
+
+	// Integers are compared exactly, as float64 can't hold every int64
+	if i, ok := stored.(int64); ok {
+		if f, ok := submitted.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+			return i == int64(f)
+		}
+	}
+
+	a, aNumeric := numericValue(stored)
+	b, bNumeric := numericValue(submitted)
+	if aNumeric && bNumeric {
+		return a == b
+	}
+
+	// A BLOB only matches the same bytes; storing text instead changes the
+	// value's storage class
+	if blob, ok := stored.([]byte); ok {
+		submittedBlob, ok := submitted.([]byte)
+		return ok && bytes.Equal(blob, submittedBlob)
+	}
+
+	return fmt.Sprint(stored) == fmt.Sprint(submitted)
+}
+
+// numericValue converts numeric and boolean values to float64.
+func numericValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// DeleteRow deletes the rows matching where and returns how many were
+// deleted.
+func (s *SQLiteDB) DeleteRow(tableName string, where map[string]interface{}) (int64, error) {
+	if len(where) == 0 {
+		return 0, fmt.Errorf("no where clause provided")
+	}
+
+	columns, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return 0, err
+	}
+
+	whereClause, values, err := whereEquals(columns, where)
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", quoteIdentifier(tableName), whereClause)
+
+	result, err := s.writer.Exec(query, values...)
+	if err != nil {
+		return 0, s.parseConstraintError(err)
+	}
+
+	return result.RowsAffected()
+}
+
+// whereEquals builds a WHERE condition matching every column/value pair, after
+// checking the columns exist.
+func whereEquals(columns []models.Column, where map[string]interface{}) (string, []interface{}, error) {
+	parts := make([]string, 0, len(where))
+	values := make([]interface{}, 0, len(where))
+	for col, val := range where {
+		if err := requireColumns(columns, col); err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, fmt.Sprintf("%s = ?", quoteIdentifier(col)))
+		values = append(values, val)
+	}
+	return strings.Join(parts, " AND "), values, nil
+}
+
+func (s *SQLiteDB) GetDatabaseInfo() (*models.DatabaseInfo, error) {
+	info := &models.DatabaseInfo{
+		Filename: s.filename,
+		ReadOnly: s.readOnly,
+	}
+	if err := s.db.QueryRow("PRAGMA journal_mode").Scan(&info.JournalMode); err != nil {
+		return nil, fmt.Errorf("failed to get journal mode: %w", err)
+	}
+	return info, nil
+}
+
+// IsSelectQuery detects whether a query returns rows by its first keyword,
+// skipping leading whitespace and comments. SELECT, VALUES, EXPLAIN and
+// PRAGMAs that read a value return rows; for WITH, the statement following the
+// common table expressions decides, so WITH ... DELETE is not a query.
+func IsSelectQuery(sqlQuery string) bool {
+	switch statementKind(sqlQuery) {
+	case "SELECT", "VALUES", "EXPLAIN":
+		return true
+	case "PRAGMA":
+		return !pragmaWrites(sqlQuery)
+	}
+	return false
+}
+
+// pragmaQueries are the pragmas that take an argument in parentheses only to
+// report on it.
+var pragmaQueries = map[string]bool{
+	"TABLE_INFO":        true,
+	"TABLE_XINFO":       true,
+	"TABLE_LIST":        true,
+	"INDEX_INFO":        true,
+	"INDEX_XINFO":       true,
+	"INDEX_LIST":        true,
+	"FOREIGN_KEY_LIST":  true,
+	"FOREIGN_KEY_CHECK": true,
+	"INTEGRITY_CHECK":   true,
+	"QUICK_CHECK":       true,
+}
+
+// pragmaWrites reports whether a PRAGMA sets a value, as PRAGMA x = v and
+// PRAGMA x(v) do, so it has to run on the writer and may change the schema.
+func pragmaWrites(sqlQuery string) bool {
+	name := ""
+	writes := false
+	scanWords(sqlQuery, func(word string, depth int) bool {
+		switch word {
+		case "=":
+			writes = true
+			return false
+		case "(":
+			writes = !pragmaQueries[name]
+			return false
+		}
+		name = word
+		return true
+	})
+	return writes
+}
+
+// statementKind returns the upper-cased keyword that decides what a statement
+// does: its first keyword, or for WITH the statement following the common
+// table expressions. A WITH without one is returned as WITH.
+func statementKind(sqlQuery string) string {
+	kind := ""
+	scanWords(sqlQuery, func(word string, depth int) bool {
+		if kind == "" {
+			kind = word
+			return word == "WITH"
+		}
+
+		// Skip the bodies of the common table expressions
+		if depth > 0 {
+			return true
+		}
+		switch word {
+		case "SELECT", "VALUES", "INSERT", "REPLACE", "UPDATE", "DELETE":
+			kind = word
+			return false
+		}
+		return true
+	})
+	return kind
+}
+
+// scanWords calls visit with each upper-cased word of a SQL statement and its
+// parenthesis nesting depth, skipping string literals, quoted identifiers and
+// comments. "=" and "(" are visited as words too, at the depth outside the
+// parenthesis. Scanning stops when visit returns false.
+func scanWords(sqlQuery string, visit func(word string, depth int) bool) {
+	depth := 0
+	for i := 0; i < len(sqlQuery); {
+		c := sqlQuery[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			// Doubled quotes inside a literal are escapes and simply reopen it
+			end := strings.IndexByte(sqlQuery[i+1:], closing)
+			if end < 0 {
+				return
+			}
+			i += end + 2
+		case c == '-' && strings.HasPrefix(sqlQuery[i:], "--"):
+			end := strings.IndexByte(sqlQuery[i:], '\n')
+			if end < 0 {
+				return
+			}
+			i += end + 1
+		case c == '/' && strings.HasPrefix(sqlQuery[i:], "/*"):
+			end := strings.Index(sqlQuery[i+2:], "*/")
+			if end < 0 {
+				return
+			}
+			i += end + 4
+		case c == '(':
+			if !visit("(", depth) {
+				return
+			}
+			depth++
+			i++
+		case c == '=':
+			if !visit("=", depth) {
+				return
+			}
+			i++
+		case c == ')':
+			depth--
+			i++
+		case isIdentifierByte(c):
+			start := i
+			for i < len(sqlQuery) && isIdentifierByte(sqlQuery[i]) {
+				i++
+			}
+			if !visit(strings.ToUpper(sqlQuery[start:i]), depth) {
+				return
+			}
+		default:
+			i++
+		}
+	}
+}
+
+// uniqueColumnNames disambiguates duplicate result column names (e.g. two
+// "id" columns from a join) by appending a numeric suffix to later duplicates.
+func uniqueColumnNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		seen[name] = true
+	}
+
+	result := make([]string, len(names))
+	used := make(map[string]bool, len(names))
+	for i, name := range names {
+		// Skip suffixed names that are already taken or belong to another column
+		candidate := name
+		for suffix := 1; used[candidate] || (candidate != name && seen[candidate]); suffix++ {
+			candidate = fmt.Sprintf("%s_%d", name, suffix)
+		}
+		used[candidate] = true
+		result[i] = candidate
+	}
+
+	return result
+}
+
+// nearTokenPattern extracts the token SQLite quotes in syntax errors such as
+// `near "SELEC": syntax error`.
+var nearTokenPattern = regexp.MustCompile(`near "((?:[^"]|"")*)"`)
+
+// ValidateSQL compiles every statement of a script without executing it, so
+// write statements have no side effects. Statements are compiled on a read
+// connection; when one fails after earlier statements changed the schema, the
+// script is compiled again against an in-memory copy of the schema in which
+// that DDL is run, so later statements can use the tables earlier ones create.
+func (s *SQLiteDB) ValidateSQL(sqlQuery string) *models.SQLValidation {
+	ctx := context.Background()
+	conn, err := s.attachedConn(ctx, s.db)
+	if err != nil {
+		return &models.SQLValidation{Error: err.Error()}
+	}
+	defer conn.Close()
+
+	statements := splitStatements(sqlQuery)
+	result := compileStatements(ctx, conn, sqlQuery, statements, false)
+	if result.Valid || result.Index == nil || !changesSchema(statements[:*result.Index]) {
+		return result
+	}
+
+	schema, err := s.schemaCopy(ctx)
+	if err != nil {
+		return &models.SQLValidation{Error: err.Error()}
+	}
+	defer schema.Close()
+	schemaConn, err := schema.Conn(ctx)
+	if err != nil {
+		return &models.SQLValidation{Error: err.Error()}
+	}
+	defer schemaConn.Close()
+	return compileStatements(ctx, schemaConn, sqlQuery, statements, true)
+}
+
+// compileStatements prepares each statement of sqlQuery on conn and reports
+// the first that fails. With runDDL, schema changes are executed as well, so
+// conn must be a throwaway database.
+func compileStatements(ctx context.Context, conn *sql.Conn, sqlQuery string, statements []string, runDDL bool) *models.SQLValidation {
+	end := 0
+	for i, statement := range statements {
+		// Statements are trimmed slices of the script, so they are found in order
+		start := end + strings.Index(sqlQuery[end:], statement)
+		end = start + len(statement)
+
+		stmt, err := conn.PrepareContext(ctx, statement)
+		if err == nil {
+			stmt.Close()
+			if runDDL && changesSchema(statements[i:i+1]) {
+				_, err = conn.ExecContext(ctx, statement)
+			}
+		}
+		if err != nil {
+			return invalidSQL(sqlQuery, i, start, statement, err)
+		}
+	}
+	return &models.SQLValidation{Valid: true}
+}
+
+// changesSchema reports whether any of the statements is DDL.
+func changesSchema(statements []string) bool {
+	for _, statement := range statements {
+		switch statementKind(statement) {
+		case "CREATE", "ALTER", "DROP":
+			return true
+		}
+	}
+	return false
+}
+
+// schemaCopy opens an in-memory database holding the main schema of this one
+// and none of its rows. Objects the copy can't recreate, such as the shadow
+// tables a virtual table creates itself, are skipped.
+func (s *SQLiteDB) schemaCopy(ctx context.Context) (*sql.DB, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' ORDER BY type != 'table', rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	var schema []string
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan schema: %w", err)
+		}
+		schema = append(schema, stmt)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	copyDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open schema copy: %w", err)
+	}
+	// Every connection to :memory: is a database of its own
+	copyDB.SetMaxOpenConns(1)
+	for _, stmt := range schema {
+		copyDB.ExecContext(ctx, stmt)
+	}
+	return copyDB, nil
+}
+
+// invalidSQL describes the error of the index'th statement of a script, which
+// starts at offset start.
+func invalidSQL(sqlQuery string, index, start int, statement string, err error) *models.SQLValidation {
+	result := &models.SQLValidation{Error: err.Error(), Index: &index}
+	if match := nearTokenPattern.FindStringSubmatch(err.Error()); match != nil {
+		result.Near = strings.ReplaceAll(match[1], `""`, `"`)
+		if offset := strings.Index(statement, result.Near); offset >= 0 {
+			offset += start
+			result.Offset = &offset
+		}
+	}
+	return result
+}
+
+// ExplainQuery returns SQLite's query plan for a single statement, with one
+// row per plan step (id, parent, notused, detail). The statement itself is
+// not executed, so write statements are safe to explain.
+func (s *SQLiteDB) ExplainQuery(sqlQuery string) (*models.SQLQueryResult, error) {
+	sqlQuery = strings.TrimSpace(sqlQuery)
+	if sqlQuery == "" {
+		return nil, fmt.Errorf("empty SQL query")
+	}
+
+	// The driver runs any statements after the first, so only one is accepted
+	statements := splitStatements(sqlQuery)
+	if len(statements) != 1 {
+		return nil, fmt.Errorf("only a single statement can be explained, got %d", len(statements))
+	}
+
+	ctx := context.Background()
+	conn, err := s.attachedConn(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, "EXPLAIN QUERY PLAN "+statements[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", s.noSuchTableError(err))
+	}
+	defer rows.Close()
+
+	return readQueryResult(rows, 0, 0)
+}
+
+// PreviewError reports SQL that can't be previewed: anything but a single
+// DELETE or UPDATE, or a statement that fails. Err may be a ConstraintError
+// or a NoSuchTableError.
+type PreviewError struct {
+	Err error
+}
+
+func (e *PreviewError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PreviewError) Unwrap() error {
+	return e.Err
+}
+
+// PreviewSQL runs a single DELETE or UPDATE in a transaction and rolls it
+// back, returning how many rows it would affect without changing anything.
+func (s *SQLiteDB) PreviewSQL(sqlQuery string) (*models.SQLQueryResult, error) {
+	sqlQuery = strings.TrimSpace(sqlQuery)
+	if sqlQuery == "" {
+		return nil, &PreviewError{Err: errors.New("empty SQL query")}
+	}
+
+	// The driver runs any statements after the first, so only one is accepted
+	statements := splitStatements(sqlQuery)
+	if len(statements) != 1 {
+		return nil, &PreviewError{Err: fmt.Errorf("only a single statement can be previewed, got %d", len(statements))}
+	}
+	if kind := statementKind(statements[0]); kind != "DELETE" && kind != "UPDATE" {
+		return nil, &PreviewError{Err: errors.New("only DELETE and UPDATE statements can be previewed")}
+	}
+
+	ctx := context.Background()
+	conn, err := s.attachedConn(ctx, s.writer)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	start := time.Now()
+	result, err := tx.Exec(statements[0])
+	if err != nil {
+		return nil, &PreviewError{Err: s.noSuchTableError(s.parseConstraintError(err))}
+	}
+	elapsed := elapsedMs(start)
+
+	rowsAffected, _ := result.RowsAffected()
+	return &models.SQLQueryResult{
+		Columns:      []string{"rows_affected"},
+		Rows:         [][]interface{}{{rowsAffected}},
+		RowCount:     1,
+		RowsAffected: int(rowsAffected),
+		ElapsedMs:    elapsed,
+	}, nil
+}
+
+// ExecuteSQL runs a statement with args bound to its placeholders.
+func (s *SQLiteDB) ExecuteSQL(sqlQuery string, args ...interface{}) (*models.SQLQueryResult, error) {
+	return s.ExecuteSQLContext(context.Background(), sqlQuery, 0, 0, args...)
+}
+
+// ErrQueryTimeout is returned when a query is interrupted by its context's
+// deadline.
+var ErrQueryTimeout = errors.New("query timed out")
+
+// ErrReadOnly is returned when a statement tries to write to a database that
+// was opened read-only.
+var ErrReadOnly = errors.New("database is open read-only")
+
+// ExecuteSQLContext is ExecuteSQL bound to ctx: the query is interrupted when
+// ctx is done, and a passed deadline is reported as ErrQueryTimeout. SELECT
+// results are cut off after maxRows rows or once their rows would exceed
+// maxResponseBytes of JSON; 0 means no limit. args are bound to the
+// statement's placeholders and must match them in number.
+func (s *SQLiteDB) ExecuteSQLContext(ctx context.Context, sqlQuery string, maxRows, maxResponseBytes int, args ...interface{}) (*models.SQLQueryResult, error) {
+	// Trim whitespace and check if query is empty
+	sqlQuery = strings.TrimSpace(sqlQuery)
+	if sqlQuery == "" {
+		return nil, fmt.Errorf("empty SQL query")
+	}
+
+	start := time.Now()
+	isSelect := IsSelectQuery(sqlQuery)
+	var result *models.SQLQueryResult
+	var err error
+	if isSelect {
+		result, err = s.executeSelectQuery(ctx, sqlQuery, maxRows, maxResponseBytes, args)
+	} else {
+		result, err = s.executeNonSelectQuery(ctx, sqlQuery, args)
+	}
+	var sqliteErr sqlite3.Error
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result, err = nil, ErrQueryTimeout
+	} else if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrReadonly {
+		result, err = nil, ErrReadOnly
+	}
+
+	rowCount := 0
+	if result != nil {
+		rowCount = result.RowCount
+		if !isSelect {
+			rowCount = result.RowsAffected
+		}
+	}
+	s.recordQuery(sqlQuery, start, rowCount, err)
+
+	return result, err
+}
+
+func (s *SQLiteDB) executeSelectQuery(ctx context.Context, sqlQuery string, maxRows, maxResponseBytes int, args []interface{}) (*models.SQLQueryResult, error) {
+	conn, err := s.attachedConn(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := checkArgs(conn, sqlQuery, args); err != nil {
+		return nil, err
+	}
+
+	// SQLite produces rows as they are stepped through, so the time covers
+	// reading them, but not encoding the response
+	start := time.Now()
+	rows, err := conn.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", s.noSuchTableError(err))
+	}
+	defer rows.Close()
+
+	result, err := readQueryResult(rows, maxRows, maxResponseBytes)
+	if err != nil {
+		return nil, err
+	}
+	result.ElapsedMs = elapsedMs(start)
+	return result, nil
+}
+
+// elapsedMs returns the time since start in fractional milliseconds.
+func elapsedMs(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
+
+// executeNonSelectQuery runs INSERT, UPDATE, DELETE and other statements that
+// don't return rows.
+func (s *SQLiteDB) executeNonSelectQuery(ctx context.Context, sqlQuery string, args []interface{}) (*models.SQLQueryResult, error) {
+	versionBefore, err := s.schemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.attachedConn(ctx, s.writer)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkArgs(conn, sqlQuery, args); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	start := time.Now()
+	result, err := conn.ExecContext(ctx, sqlQuery, args...)
+	elapsed := elapsedMs(start)
+	conn.Close()
+	if err != nil {
+		return nil, s.noSuchTableError(s.parseConstraintError(err))
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	queryResult := &models.SQLQueryResult{
+		Columns:      []string{"rows_affected"},
+		Rows:         [][]interface{}{{rowsAffected}},
+		RowCount:     1,
+		RowsAffected: int(rowsAffected),
+		ElapsedMs:    elapsed,
+	}
+
+	// Report DDL so clients can refresh their table list
+	versionAfter, err := s.schemaVersion()
+	if err != nil {
+		return nil, err
 	}
+	if versionAfter != versionBefore {
+		tables, err := s.GetTables()
+		if err != nil {
+			return nil, err
+		}
+		queryResult.SchemaChanged = true
+		queryResult.Tables = tables
+	}
+
+	return queryResult, nil
+}
+
+// ParamError reports query params that don't fit the statement: a param of
+// an unsupported type, or the wrong number of them.
+type ParamError struct {
+	Reason string
+}
+
+func (e *ParamError) Error() string {
+	return e.Reason
+}
+
+// checkArgs makes sure args match the placeholders of the statements in
+// number and are plain values. The driver only complains about missing
+// arguments and silently ignores extra ones. Without args, a statement that
+// doesn't compile is left for the caller to report; with them, it's a
+// ParamError, since its placeholders can't be counted before it runs, e.g.
+// when it uses a table that an earlier statement creates.
+func checkArgs(conn *sql.Conn, sqlQuery string, args []interface{}) error {
+	for i, arg := range args {
+		switch arg.(type) {
+		case nil, bool, float64, int64, string:
+		default:
+			return &ParamError{Reason: fmt.Sprintf("param %d must be a string, number, boolean or null", i+1)}
+		}
+	}
+
+	return conn.Raw(func(driverConn interface{}) error {
+		sqliteConn, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return nil
+		}
+		// The driver binds args to the statements of a script in turn
+		placeholders := 0
+		for i, statement := range splitStatements(sqlQuery) {
+			stmt, err := sqliteConn.Prepare(statement)
+			if err != nil {
+				if len(args) == 0 {
+					return nil
+				}
+				return &ParamError{Reason: fmt.Sprintf("can't match params to statement %d: %v", i+1, err)}
+			}
+			placeholders += stmt.NumInput()
+			stmt.Close()
+		}
+		if placeholders != len(args) {
+			return &ParamError{Reason: fmt.Sprintf("statement has %d placeholders but %d params were given", placeholders, len(args))}
+		}
+		return nil
+	})
+}
 
-	// Write header
-	if err := writer.Write(columnNames); err != nil {
-		return fmt.Errorf("failed to write CSV header: %w", err)
+// readQueryResult reads the rows of a result set into a SQLQueryResult, with
+// duplicate column names disambiguated. Reading stops after maxRows rows or
+// once the rows would exceed maxResponseBytes of JSON; 0 means no limit.
+func readQueryResult(rows *sql.Rows, maxRows, maxResponseBytes int) (*models.SQLQueryResult, error) {
+	return readQueryResultWithin(rows, maxRows, &responseBudget{max: maxResponseBytes})
+}
+
+// readQueryResultWithin reads rows like readQueryResult, counting them against
+// a budget that may be shared with other results.
+func readQueryResultWithin(rows *sql.Rows, maxRows int, budget *responseBudget) (*models.SQLQueryResult, error) {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column names: %w", err)
 	}
+	columnNames = uniqueColumnNames(columnNames)
 
-	// Write data rows
+	var resultRows [][]interface{}
+	truncated := false
 	for rows.Next() {
+		// Stop without buffering the rest, which may be millions of rows
+		if maxRows > 0 && len(resultRows) == maxRows {
+			truncated = true
+			break
+		}
+
 		values := make([]interface{}, len(columnNames))
 		valuePtrs := make([]interface{}, len(columnNames))
 		for i := range values {
@@ -538,29 +2127,141 @@ func (s *SQLiteDB) ExportTableCSV(tableName, sortColumn, sortDirection, whereCla
 		}
 
 		if err := rows.Scan(valuePtrs...); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
-		// Convert values to strings for CSV
-		csvRow := make([]string, len(columnNames))
+		row := make([]interface{}, len(columnNames))
 		for i, val := range values {
 			if val != nil {
 				switch v := val.(type) {
 				case []byte:
-					csvRow[i] = string(v)
+					row[i] = models.JSONValue(v)
+				case int64:
+					row[i] = models.ExactInteger(v)
 				default:
-					csvRow[i] = fmt.Sprintf("%v", v)
+					row[i] = v
 				}
 			} else {
-				csvRow[i] = ""
+				row[i] = nil
 			}
 		}
-
-		if err := writer.Write(csvRow); err != nil {
-			return fmt.Errorf("failed to write CSV row: %w", err)
+		if !budget.fits(row) {
+			break
 		}
+		resultRows = append(resultRows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
 	}
 
-	writer.Flush()
-	return writer.Error()
-}
\ No newline at end of file
+	return &models.SQLQueryResult{
+		Columns:         columnNames,
+		Rows:            resultRows,
+		RowCount:        len(resultRows),
+		Truncated:       truncated,
+		TruncatedBySize: budget.exceeded,
+	}, nil
+}
+
+// schemaVersion returns the schema cookie, which SQLite increments on every
+// schema change.
+func (s *SQLiteDB) schemaVersion() (int, error) {
+	var version int
+	if err := s.db.QueryRow("PRAGMA schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+func (s *SQLiteDB) ExportQueryCSV(sqlQuery string, opts models.CSVOptions, writer *csv.Writer) error {
+	sqlQuery = strings.TrimSpace(sqlQuery)
+	if sqlQuery == "" {
+		return fmt.Errorf("empty SQL query")
+	}
+	if !IsSelectQuery(sqlQuery) {
+		return fmt.Errorf("only SELECT queries can be exported")
+	}
+
+	ctx := context.Background()
+	conn, err := s.attachedConn(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, sqlQuery)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get column names: %w", err)
+	}
+
+	return writeCSVRows(rows, uniqueColumnNames(columnNames), opts, writer)
+}
+
+// queryTableExport queries every row of a table for an export, applying the
+// table query's filters, scopes and sorting.
+func (s *SQLiteDB) queryTableExport(qr queryer, tableName string, q models.TableQuery) (*sql.Rows, error) {
+	columns, err := s.getTableSchema(qr, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the base query with optional WHERE clause
+	source, args := scopedSource(tableName, q.Scopes)
+	baseQuery := fmt.Sprintf("SELECT * FROM %s", source)
+
+	condition, filterArgs, err := filterCondition(columns, q)
+	if err != nil {
+		return nil, err
+	}
+	if condition != "" {
+		baseQuery += fmt.Sprintf(" WHERE %s", condition)
+	}
+	args = append(args, filterArgs...)
+
+	// Build the query with optional sorting
+	orderBy, err := orderByClause(columns, q)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := qr.Query(baseQuery+orderBy, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query table data: %w", err)
+	}
+	return rows, nil
+}
+
+// ExportTableCSV writes the table as CSV. The schema lookup and the row scan
+// run in one read transaction, so the export reflects a single consistent
+// state of the database even while other connections write to it.
+func (s *SQLiteDB) ExportTableCSV(tableName string, q models.TableQuery, opts models.CSVOptions, writer *csv.Writer) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin export transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := s.queryTableExport(tx, tableName, q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get column names: %w", err)
+	}
+
+	if err := writeCSVRows(rows, columnNames, opts, writer); err != nil {
+		return err
+	}
+	rows.Close()
+
+	return tx.Commit()
+}
@@ -0,0 +1,507 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sqliter/internal/models"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func createItemsTable(t testing.TB, database *SQLiteDB) {
+	columns := []models.Column{
+		{Name: "id", Type: "int", PrimaryKey: true},
+		{Name: "name", Type: "string", NotNull: true, Unique: true},
+		{Name: "qty", Type: "int"},
+	}
+	if err := database.CreateTable("items", columns); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func itemRows(n int) [][]interface{} {
+	rows := make([][]interface{}, n)
+	for i := range rows {
+		rows[i] = []interface{}{fmt.Sprintf("item-%d", i), i}
+	}
+	return rows
+}
+
+func TestInsertRows(t *testing.T) {
+	database := setupEmptyDB(t)
+	createItemsTable(t, database)
+
+	inserted, err := database.InsertRows("items", []string{"name", "qty"}, itemRows(5000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inserted != 5000 {
+		t.Errorf("Expected 5000 rows inserted, got %d", inserted)
+	}
+
+	total, err := database.CountRows("items", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 5000 {
+		t.Errorf("Expected 5000 rows in table, got %d", total)
+	}
+
+	var sum int
+	if err := database.db.QueryRow("SELECT SUM(qty) FROM items").Scan(&sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 4999*5000/2 {
+		t.Errorf("Expected qty sum %d, got %d", 4999*5000/2, sum)
+	}
+}
+
+func TestInsertRowsRollsBackOnError(t *testing.T) {
+	database := setupEmptyDB(t)
+	createItemsTable(t, database)
+
+	rows := itemRows(10)
+	rows[7][0] = "item-2" // duplicate of a unique value
+
+	if _, err := database.InsertRows("items", []string{"name", "qty"}, rows); err == nil {
+		t.Fatal("Expected an error for a duplicate row")
+	}
+
+	total, err := database.CountRows("items", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 0 {
+		t.Errorf("Expected the batch to be rolled back, found %d rows", total)
+	}
+}
+
+func BenchmarkInsertRows(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		database := setupEmptyDB(b)
+		createItemsTable(b, database)
+		rows := itemRows(2000)
+		b.StartTimer()
+
+		if _, err := database.InsertRows("items", []string{"name", "qty"}, rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInsertRowLoop(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		database := setupEmptyDB(b)
+		createItemsTable(b, database)
+		rows := itemRows(2000)
+		b.StartTimer()
+
+		for _, row := range rows {
+			if err := database.InsertRow("items", map[string]interface{}{"name": row[0], "qty": row[1]}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestAnalyzeTableAfterInsertRows(t *testing.T) {
+	database := setupEmptyDB(t)
+	createItemsTable(t, database)
+
+	if _, err := database.InsertRows("items", []string{"name", "qty"}, itemRows(500)); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AnalyzeTable("items"); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := database.db.QueryRow("SELECT COUNT(*) FROM sqlite_stat1 WHERE tbl = 'items'").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 {
+		t.Error("Expected sqlite_stat1 to have an entry for 'items'")
+	}
+}
+
+func TestGetTableDataPageNumbers(t *testing.T) {
+	database := setupEmptyDB(t)
+	createItemsTable(t, database)
+	if _, err := database.InsertRows("items", []string{"name", "qty"}, itemRows(45)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := database.GetTableData("items", models.TableQuery{Limit: 10, Offset: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Page != 3 || data.TotalPages != 5 {
+		t.Errorf("Expected page 3 of 5, got page %d of %d", data.Page, data.TotalPages)
+	}
+
+	data, err = database.GetTableData("items", models.TableQuery{Limit: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Page != 0 || data.TotalPages != 0 {
+		t.Errorf("Expected no page numbers without a limit, got page %d of %d", data.Page, data.TotalPages)
+	}
+}
+
+// writeHook calls onWrite before the first write that reaches it.
+type writeHook struct {
+	bytes.Buffer
+	onWrite func()
+}
+
+func (w *writeHook) Write(p []byte) (int, error) {
+	if w.onWrite != nil {
+		w.onWrite()
+		w.onWrite = nil
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestExportTableCSVConsistentSnapshot(t *testing.T) {
+	database := setupEmptyDB(t)
+	if _, err := database.db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		t.Fatal(err)
+	}
+	createItemsTable(t, database)
+	if _, err := database.InsertRows("items", []string{"name", "qty"}, itemRows(2000)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Modify the table once the export has started writing output
+	out := &writeHook{onWrite: func() {
+		if _, err := database.db.Exec("DELETE FROM items WHERE id % 2 = 0"); err != nil {
+			t.Error(err)
+		}
+		if _, err := database.db.Exec("INSERT INTO items (name, qty) VALUES ('late', 0)"); err != nil {
+			t.Error(err)
+		}
+	}}
+
+	writer := csv.NewWriter(out)
+	if err := database.ExportTableCSV("items", models.TableQuery{DefaultSort: true}, models.CSVOptions{}, writer); err != nil {
+		t.Fatal(err)
+	}
+	writer.Flush()
+
+	if out.onWrite != nil {
+		t.Fatal("Expected the table to be modified during the export")
+	}
+
+	records, err := csv.NewReader(&out.Buffer).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2001 {
+		t.Fatalf("Expected a header and the 2000 pre-export rows, got %d records", len(records))
+	}
+	for i, record := range records[1:] {
+		if record[0] != fmt.Sprint(i+1) {
+			t.Fatalf("Expected row %d to have id %d, got %s", i, i+1, record[0])
+		}
+	}
+
+	total, err := database.CountRows("items", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1001 {
+		t.Errorf("Expected 1001 rows after the concurrent writes, got %d", total)
+	}
+}
+
+func TestExportTableCSVCellRendering(t *testing.T) {
+	database := setupEmptyDB(t)
+	setup := []string{
+		`CREATE TABLE cells (id INTEGER PRIMARY KEY, note TEXT, data BLOB, amount REAL, big REAL)`,
+		`INSERT INTO cells VALUES (1, NULL, X'00FF10', 2.5, 1e21)`,
+		`INSERT INTO cells VALUES (2, '', NULL, 3, 0.000001)`,
+		`INSERT INTO cells VALUES (3, 'text', '', NULL, -7)`,
+	}
+	for _, stmt := range setup {
+		if _, err := database.db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	export := func(opts models.CSVOptions) string {
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		if err := database.ExportTableCSV("cells", models.TableQuery{DefaultSort: true}, opts, writer); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	want := "id,note,data,amount,big\n" +
+		"1,,AP8Q,2.5,1000000000000000000000\n" +
+		"2,,,3,0.000001\n" +
+		"3,text,,,-7\n"
+	if got := export(models.CSVOptions{}); got != want {
+		t.Errorf("Unexpected default CSV:\n%s\nwant:\n%s", got, want)
+	}
+
+	want = "id,note,data,amount,big\n" +
+		"1,NULL,<binary 3 bytes>,2.5,1000000000000000000000\n" +
+		"2,,NULL,3,0.000001\n" +
+		"3,text,,NULL,-7\n"
+	if got := export(models.CSVOptions{Null: "NULL", Binary: models.CSVBinaryPlaceholder}); got != want {
+		t.Errorf("Unexpected CSV with a null string and binary placeholders:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestInitPragmasRunOnEveryConnection(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpfile.Close()
+	defer os.Remove(tmpfile.Name())
+
+	database, err := NewSQLiteDB(tmpfile.Name(), "cache_size = -4321", "PRAGMA foreign_keys=ON;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	// Hold one connection open so the pool has to open a fresh one
+	ctx := context.Background()
+	held, err := database.db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Close()
+
+	fresh, err := database.db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fresh.Close()
+
+	for _, conn := range []*sql.Conn{held, fresh} {
+		var cacheSize, foreignKeys int
+		if err := conn.QueryRowContext(ctx, "PRAGMA cache_size").Scan(&cacheSize); err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+			t.Fatal(err)
+		}
+		if cacheSize != -4321 || foreignKeys != 1 {
+			t.Errorf("Expected cache_size -4321 and foreign_keys 1, got %d and %d", cacheSize, foreignKeys)
+		}
+	}
+
+	if _, err := NewSQLiteDB(tmpfile.Name(), "foreign_keys=ON; DROP TABLE x"); err == nil {
+		t.Error("Expected an error for an init pragma with several statements")
+	}
+}
+
+func TestInsertRowsReturning(t *testing.T) {
+	database := setupEmptyDB(t)
+	createItemsTable(t, database)
+
+	returned, err := database.InsertRowsReturning("items", []string{"name", "qty"}, itemRows(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(returned) != 3 {
+		t.Fatalf("Expected 3 returned rows, got %d", len(returned))
+	}
+	for i, row := range returned {
+		if row["id"] != int64(i+1) || row["name"] != fmt.Sprintf("item-%d", i) {
+			t.Errorf("Expected row %d to have generated id %d and name item-%d, got %v", i, i+1, i, row)
+		}
+	}
+
+	// A failing row rolls back the batch and returns nothing
+	rows := itemRows(3)
+	rows[2][0] = "item-0"
+	if returned, err := database.InsertRowsReturning("items", []string{"name", "qty"}, rows); err == nil || returned != nil {
+		t.Errorf("Expected an error and no rows for a duplicate, got %v and %v", returned, err)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `
+		-- create; the table
+		CREATE TABLE log (id INTEGER PRIMARY KEY, msg TEXT DEFAULT 'a;b');
+		/* a ; comment */
+		INSERT INTO log (msg) VALUES ('it''s; fine'), ("quoted;ident");
+		CREATE TEMP TRIGGER log_end AFTER INSERT ON log BEGIN
+			UPDATE log SET msg = CASE WHEN NEW.msg = '' THEN 'empty' ELSE NEW.msg END WHERE id = NEW.id;
+			DELETE FROM log WHERE id < 0;
+		END;
+		;
+		SELECT [odd;name] FROM log`
+
+	statements := splitStatements(script)
+	if len(statements) != 4 {
+		t.Fatalf("Expected 4 statements, got %d: %q", len(statements), statements)
+	}
+	if !strings.HasPrefix(statements[0], "-- create; the table\n") || !strings.HasSuffix(statements[0], "DEFAULT 'a;b')") {
+		t.Errorf("Unexpected first statement: %q", statements[0])
+	}
+	if !strings.HasSuffix(statements[1], `("quoted;ident")`) {
+		t.Errorf("Unexpected second statement: %q", statements[1])
+	}
+	if !strings.HasPrefix(statements[2], "CREATE TEMP TRIGGER") || !strings.HasSuffix(statements[2], "END") {
+		t.Errorf("Expected the whole trigger as one statement, got %q", statements[2])
+	}
+	if statements[3] != "SELECT [odd;name] FROM log" {
+		t.Errorf("Unexpected last statement: %q", statements[3])
+	}
+
+	for script, want := range map[string][]string{
+		"SELECT 'a;b'":  {"SELECT 'a;b'"},
+		"SELECT 'a;b';": {"SELECT 'a;b'"},
+		"CREATE TRIGGER t AFTER INSERT ON log BEGIN SELECT 1; SELECT 2; END; SELECT 3": {
+			"CREATE TRIGGER t AFTER INSERT ON log BEGIN SELECT 1; SELECT 2; END",
+			"SELECT 3",
+		},
+	} {
+		if got := splitStatements(script); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitStatements(%q) = %q, want %q", script, got, want)
+		}
+	}
+}
+
+func TestIsSelectQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"SELECT 1", true},
+		{"  select * FROM users", true},
+		{"-- a comment\n/* another */ -- and one more\nSELECT 1", true},
+		{"VALUES (1, 2)", true},
+		{"EXPLAIN QUERY PLAN SELECT 1", true},
+		{"PRAGMA journal_mode", true},
+		{"PRAGMA main.table_info(users)", true},
+		{"PRAGMA journal_mode = WAL", false},
+		{"PRAGMA main.user_version=3", false},
+		{"PRAGMA wal_checkpoint(TRUNCATE)", false},
+		{"PRAGMA /* ( */ foreign_keys", true},
+		{"WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 3) SELECT i FROM n", true},
+		{"WITH old AS (SELECT id FROM users WHERE age > 60) DELETE FROM users WHERE id IN (SELECT id FROM old)", false},
+		{"WITH \"delete\" AS (SELECT 1) SELECT * FROM \"delete\"", true},
+		{"SELECTED_ROWS", false},
+		{"INSERT INTO users (name) VALUES ('SELECT')", false},
+		{"/* SELECT */ UPDATE users SET name = 'x'", false},
+		{"-- SELECT", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsSelectQuery(tt.query); got != tt.want {
+			t.Errorf("IsSelectQuery(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestConcurrentWritesDontLock(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpfile.Close()
+	defer os.Remove(tmpfile.Name())
+
+	// Without a busy timeout any lock contention would fail immediately. In
+	// WAL mode readers never wait, so only the writes could collide
+	database, err := NewSQLiteDB(tmpfile.Name(), "busy_timeout=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	if _, err := database.SetJournalMode("wal"); err != nil {
+		t.Fatal(err)
+	}
+	createItemsTable(t, database)
+
+	const writers = 20
+	errs := make(chan error, writers*12)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("item-%d", i)
+			errs <- database.InsertRow("items", map[string]interface{}{"name": name, "qty": i})
+			// Updates read the current row before writing in one transaction
+			for n := 1; n <= 10; n++ {
+				_, err := database.UpdateRow("items", map[string]interface{}{"qty": i + n}, map[string]interface{}{"name": name})
+				errs <- err
+			}
+			if i%2 == 0 {
+				_, err := database.DeleteRow("items", map[string]interface{}{"name": name})
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected concurrent writes to succeed, got %v", err)
+		}
+	}
+	total, err := database.CountRows("items", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != writers/2 {
+		t.Errorf("Expected %d rows left, got %d", writers/2, total)
+	}
+}
+
+func TestGetTableDataJSONPathErrors(t *testing.T) {
+	database := setupEmptyDB(t)
+	createItemsTable(t, database)
+
+	_, err := database.GetTableData("items", models.TableQuery{Limit: 10, JSONPaths: []models.JSONPath{{Column: "missing", Path: "$.a"}}})
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) || notFound.Kind != "column" {
+		t.Errorf("Expected a NotFoundError for an unknown column, got %v", err)
+	}
+
+	_, err = database.GetTableData("items", models.TableQuery{Limit: 10, JSONPaths: []models.JSONPath{{Column: "name", Path: "address"}}})
+	var badFilter *FilterError
+	if !errors.As(err, &badFilter) || badFilter.Column != "name" {
+		t.Errorf("Expected a FilterError for an invalid path, got %v", err)
+	}
+}
+
+func TestConnectionJournalMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	database, err := NewSQLiteDB(path, "journal_mode=persist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	// Every connection gets the mode, the writer included
+	for name, pool := range map[string]*sql.DB{"reader": database.db, "writer": database.writer} {
+		var mode string
+		if err := pool.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+			t.Fatal(err)
+		}
+		if mode != "persist" {
+			t.Errorf("Expected the %s to use persist, got %s", name, mode)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sqliter/internal/models"
+	"strings"
+)
+
+// GetTableStats returns every table's row count and, when SQLite was built
+// with the dbstat virtual table, its size on disk including its indexes.
+// Scoped tables are counted within their scopes and get no size, since that
+// would reveal how much data lies outside them.
+func (s *SQLiteDB) GetTableStats(scopes map[string][]models.Scope) ([]models.TableStat, error) {
+	tables, err := s.GetTables()
+	if err != nil {
+		return nil, err
+	}
+
+	sizes, err := s.tableSizes()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]models.TableStat, 0, len(tables))
+	for _, table := range tables {
+		count, err := s.CountRows(table.Name, "", scopes[table.Name]...)
+		if err != nil {
+			return nil, err
+		}
+		stat := models.TableStat{Name: table.Name, Rows: count}
+		if size, ok := sizes[table.Name]; ok && len(scopes[table.Name]) == 0 {
+			stat.SizeBytes = &size
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
+
+// tableSizes sums the pages used by each table and its indexes. It returns
+// nil when the dbstat virtual table isn't compiled in.
+func (s *SQLiteDB) tableSizes() (map[string]int64, error) {
+	query := `SELECT m.tbl_name, SUM(d.pgsize)
+		FROM dbstat d JOIN sqlite_master m ON m.name = d.name
+		WHERE d.aggregate = TRUE
+		GROUP BY m.tbl_name`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table: dbstat") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read table sizes: %w", err)
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]int64)
+	for rows.Next() {
+		var name string
+		var size sql.NullInt64
+		if err := rows.Scan(&name, &size); err != nil {
+			return nil, fmt.Errorf("failed to scan table size: %w", err)
+		}
+		sizes[name] = size.Int64
+	}
+
+	return sizes, rows.Err()
+}
+
+// DatabaseSize returns the size of the database file from its page count
+// and page size, which works without dbstat.
+func (s *SQLiteDB) DatabaseSize() (int64, error) {
+	var size int64
+	if err := s.db.QueryRow(`SELECT page_count * page_size FROM pragma_page_count, pragma_page_size`).Scan(&size); err != nil {
+		return 0, fmt.Errorf("failed to read database size: %w", err)
+	}
+	return size, nil
+}
+
+// GetColumnStats returns a column's row, null and distinct value counts and,
+// for a column with a numeric declared type, its min, max, average and sum.
+// The column is checked against the schema before it's put in the query, and
+// scopes restrict the rows that are summarized. A sum of integers that
+// overflows 64 bits is returned as a float.
+func (s *SQLiteDB) GetColumnStats(tableName, column string, scopes ...models.Scope) (*models.ColumnStats, error) {
+	columns, err := s.GetTableSchema(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireColumns(columns, column); err != nil {
+		return nil, err
+	}
+
+	// rowid and its aliases aren't in the schema, but are integers
+	col := models.Column{Name: column, Type: "INTEGER"}
+	for _, c := range columns {
+		if strings.EqualFold(c.Name, column) {
+			col = c
+			break
+		}
+	}
+
+	stats := &models.ColumnStats{Column: col.Name, Type: col.Type, Numeric: hasNumericAffinity(col.Type)}
+	err = s.scanColumnStats(stats, tableName, "SUM", scopes)
+	if err != nil && stats.Numeric && strings.Contains(err.Error(), "integer overflow") {
+		// TOTAL sums as a float, so it can't overflow
+		err = s.scanColumnStats(stats, tableName, "TOTAL", scopes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute column stats: %w", err)
+	}
+	for _, value := range []*interface{}{&stats.Min, &stats.Max, &stats.Sum} {
+		switch v := (*value).(type) {
+		case int64:
+			*value = models.ExactInteger(v)
+		case []byte:
+			*value = string(v)
+		}
+	}
+
+	return stats, nil
+}
+
+// scanColumnStats runs the aggregates of GetColumnStats into stats, summing
+// with sum, which is SUM or TOTAL.
+func (s *SQLiteDB) scanColumnStats(stats *models.ColumnStats, tableName, sum string, scopes []models.Scope) error {
+	quoted := quoteIdentifier(stats.Column)
+	aggregates := fmt.Sprintf("COUNT(*), COUNT(*) - COUNT(%s), COUNT(DISTINCT %s)", quoted, quoted)
+	if stats.Numeric {
+		aggregates += fmt.Sprintf(", MIN(%s), MAX(%s), AVG(%s), %s(%s)", quoted, quoted, quoted, sum, quoted)
+	}
+
+	source, args := scopedSource(tableName, scopes)
+	row := s.db.QueryRow(fmt.Sprintf("SELECT %s FROM %s", aggregates, source), args...)
+
+	targets := []interface{}{&stats.Count, &stats.NullCount, &stats.DistinctCount}
+	var avg sql.NullFloat64
+	if stats.Numeric {
+		targets = append(targets, &stats.Min, &stats.Max, &avg, &stats.Sum)
+	}
+	if err := row.Scan(targets...); err != nil {
+		return err
+	}
+	if avg.Valid {
+		stats.Avg = &avg.Float64
+	}
+	return nil
+}
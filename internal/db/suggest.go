@@ -0,0 +1,106 @@
+package db
+
+import (
+	"sort"
+	"strings"
+)
+
+// noSuchTablePrefix starts SQLite's error message for a missing table.
+const noSuchTablePrefix = "no such table: "
+
+// maxSuggestionDistance is the largest edit distance at which an existing
+// table is suggested for a misspelled name.
+const maxSuggestionDistance = 2
+
+// NoSuchTableError reports a query referencing a missing table, along with
+// existing tables whose names are close to it.
+type NoSuchTableError struct {
+	Name        string
+	Suggestions []string
+}
+
+func (e *NoSuchTableError) Error() string {
+	return noSuchTablePrefix + e.Name
+}
+
+// noSuchTableError turns SQLite's "no such table" error into a
+// NoSuchTableError with suggestions, and returns any other error unchanged.
+func (s *SQLiteDB) noSuchTableError(err error) error {
+	msg := err.Error()
+	i := strings.Index(msg, noSuchTablePrefix)
+	if i < 0 {
+		return err
+	}
+
+	name := strings.TrimSpace(msg[i+len(noSuchTablePrefix):])
+	tables, lookupErr := s.GetTables()
+	if lookupErr != nil {
+		return err
+	}
+
+	// Tables are suggested by their bare name, without a schema qualifier
+	bareName := name
+	if _, table, ok := strings.Cut(name, "."); ok {
+		bareName = table
+	}
+
+	names := make([]string, len(tables))
+	for i, table := range tables {
+		names[i] = table.Name
+	}
+
+	return &NoSuchTableError{Name: name, Suggestions: similarNames(bareName, names)}
+}
+
+// similarNames returns the candidates within maxSuggestionDistance of name,
+// compared case-insensitively, closest first.
+func similarNames(name string, candidates []string) []string {
+	distances := make(map[string]int)
+	similar := []string{}
+	for _, candidate := range candidates {
+		distance := levenshtein(strings.ToLower(name), strings.ToLower(candidate))
+		if distance <= maxSuggestionDistance {
+			distances[candidate] = distance
+			similar = append(similar, candidate)
+		}
+	}
+
+	sort.SliceStable(similar, func(i, j int) bool {
+		return distances[similar[i]] < distances[similar[j]]
+	})
+	return similar
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
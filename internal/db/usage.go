@@ -0,0 +1,63 @@
+package db
+
+import (
+	"fmt"
+	"sqliter/internal/models"
+	"time"
+)
+
+const usageTable = internalTablePrefix + "usage"
+
+func (s *SQLiteDB) ensureUsageTable() error {
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (table_name TEXT PRIMARY KEY, access_count INTEGER NOT NULL, last_accessed INTEGER NOT NULL)", quoteIdentifier(usageTable))
+	if _, err := s.writer.Exec(query); err != nil {
+		return fmt.Errorf("failed to create usage table: %w", err)
+	}
+	return nil
+}
+
+// RecordTableAccess bumps the access count and timestamp of a table.
+func (s *SQLiteDB) RecordTableAccess(tableName string) error {
+	if err := s.ensureUsageTable(); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (table_name, access_count, last_accessed) VALUES (?, 1, ?)
+		ON CONFLICT(table_name) DO UPDATE SET access_count = access_count + 1, last_accessed = excluded.last_accessed`, quoteIdentifier(usageTable))
+	if _, err := s.writer.Exec(query, tableName, time.Now().UnixNano()); err != nil {
+		return fmt.Errorf("failed to record table access: %w", err)
+	}
+
+	return nil
+}
+
+// GetRecentTables returns the most recently accessed tables that still exist.
+func (s *SQLiteDB) GetRecentTables(limit int) ([]models.TableUsage, error) {
+	recent := []models.TableUsage{}
+
+	exists, err := s.schemaObjectExists("table", usageTable)
+	if err != nil || !exists {
+		return recent, err
+	}
+
+	query := fmt.Sprintf(`SELECT u.table_name, u.access_count, u.last_accessed FROM %s u
+		JOIN sqlite_master m ON m.type = 'table' AND m.name = u.table_name
+		ORDER BY u.last_accessed DESC LIMIT ?`, quoteIdentifier(usageTable))
+	rows, err := s.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent tables: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var usage models.TableUsage
+		var lastAccessed int64
+		if err := rows.Scan(&usage.Name, &usage.AccessCount, &lastAccessed); err != nil {
+			return nil, fmt.Errorf("failed to scan usage row: %w", err)
+		}
+		usage.LastAccessed = time.Unix(0, lastAccessed).UTC()
+		recent = append(recent, usage)
+	}
+
+	return recent, rows.Err()
+}
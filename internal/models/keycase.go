@@ -0,0 +1,132 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Key cases for the keys of returned rows.
+const (
+	KeyCaseOriginal = "original"
+	KeyCaseCamel    = "camel"
+	KeyCaseSnake    = "snake"
+)
+
+// IsValidKeyCase reports whether keyCase is a supported key case; empty means original.
+func IsValidKeyCase(keyCase string) bool {
+	switch keyCase {
+	case "", KeyCaseOriginal, KeyCaseCamel, KeyCaseSnake:
+		return true
+	}
+	return false
+}
+
+// splitWords splits a column name into lowercase words on underscores,
+// hyphens, spaces and case changes: "createdAt" and "HTTPStatus_code" give
+// [created at] and [http status code].
+func splitWords(name string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	for i, r := range runes {
+		if r == '_' || r == '-' || unicode.IsSpace(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// Start a word at "aB", and at the last capital of "HTTPStatus"
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+
+	return words
+}
+
+// ConvertKeyCase renders a column name in the given key case. Names without
+// any letters or digits are returned unchanged.
+func ConvertKeyCase(name, keyCase string) string {
+	if keyCase != KeyCaseCamel && keyCase != KeyCaseSnake {
+		return name
+	}
+
+	words := splitWords(name)
+	if len(words) == 0 {
+		return name
+	}
+	if keyCase == KeyCaseSnake {
+		return strings.Join(words, "_")
+	}
+
+	var b strings.Builder
+	b.WriteString(words[0])
+	for _, word := range words[1:] {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	return b.String()
+}
+
+// convertKeys maps each name to its key-cased form. A name that would collide
+// with an earlier converted name keeps its original spelling, as does the row
+// key field.
+func convertKeys(names []string, keyCase string) []string {
+	converted := make([]string, len(names))
+	taken := make(map[string]bool, len(names))
+	for i, name := range names {
+		key := ConvertKeyCase(name, keyCase)
+		if name == RowKeyField || taken[key] {
+			key = name
+		}
+		taken[key] = true
+		converted[i] = key
+	}
+	return converted
+}
+
+// ApplyKeyCase renames the keys of every row. The column schema keeps the
+// table's real column names, which are still the ones used in queries.
+func (d *TableData) ApplyKeyCase(keyCase string) {
+	if keyCase != KeyCaseCamel && keyCase != KeyCaseSnake {
+		return
+	}
+
+	for i, row := range d.Rows {
+		names := make([]string, 0, len(row))
+		for name := range row {
+			names = append(names, name)
+		}
+		// Sort so collisions resolve the same way for every row
+		sort.Strings(names)
+
+		renamed := make(Row, len(row))
+		for j, key := range convertKeys(names, keyCase) {
+			renamed[key] = row[names[j]]
+		}
+		d.Rows[i] = renamed
+	}
+}
+
+// ApplyKeyCase renames the result columns.
+func (r *SQLQueryResult) ApplyKeyCase(keyCase string) {
+	r.Columns = convertKeys(r.Columns, keyCase)
+}
+
+// ApplyKeyCase renames the columns of a columnar result.
+func (c *ColumnarData) ApplyKeyCase(keyCase string) {
+	c.Columns = convertKeys(c.Columns, keyCase)
+}
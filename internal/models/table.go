@@ -1,5 +1,15 @@
 package models
 
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
 type Table struct {
 	Name string `json:"name"`
 	Type string `json:"type"`
@@ -15,29 +25,314 @@ type Column struct {
 	Unique       bool   `json:"unique"`
 }
 
+// UniqueConstraint is a unique constraint spanning several columns.
+type UniqueConstraint struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+// Row holds a row's values as read from the database, so they can be bound
+// back as they are; they are only rendered for clients when the row is
+// encoded as JSON.
 type Row map[string]interface{}
 
+// MarshalJSON encodes the row with its values rendered by JSONValue.
+func (r Row) MarshalJSON() ([]byte, error) {
+	if r == nil {
+		return []byte("null"), nil
+	}
+	rendered := make(map[string]interface{}, len(r))
+	for name, value := range r {
+		rendered[name] = JSONValue(value)
+	}
+	return json.Marshal(rendered)
+}
+
+// maxSafeInteger is the largest integer a JSON number can carry without
+// losing precision in JavaScript (2^53).
+const maxSafeInteger = 1 << 53
+
+// ExactInteger returns integers beyond ±2^53 as decimal strings, since
+// clients decoding JSON numbers as doubles would silently round them.
+func ExactInteger(v int64) interface{} {
+	if v > maxSafeInteger || v < -maxSafeInteger {
+		return strconv.FormatInt(v, 10)
+	}
+	return v
+}
+
+// Blob is a BLOB value in table data. It is sent as {"__blob__": "<base64>"}
+// so clients can tell binary data from text.
+type Blob struct {
+	Data string `json:"__blob__"`
+}
+
+// JSONValue renders a database value for a JSON response: BLOBs become Blob,
+// so binary data isn't mangled into invalid UTF-8, and integers go through
+// ExactInteger. Key objects nested in a row are rendered the same way.
+func JSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return Blob{Data: base64.StdEncoding.EncodeToString(v)}
+	case int64:
+		return ExactInteger(v)
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(v))
+		for name, nested := range v {
+			rendered[name] = JSONValue(nested)
+		}
+		return rendered
+	}
+	return value
+}
+
 type TableData struct {
-	Columns []Column `json:"columns"`
-	Rows    []Row    `json:"rows"`
-	Total   int      `json:"total"`
+	Columns          []Column `json:"columns"`
+	Rows             []Row    `json:"rows"`
+	Total            int      `json:"total"`
+	ColumnsTruncated bool     `json:"columns_truncated,omitempty"`
+	// Page is the 1-based page the offset falls on; Page and TotalPages are
+	// zero when the query has no positive limit.
+	Page       int `json:"page"`
+	TotalPages int `json:"total_pages"`
+	// TruncatedBySize is set when rows were left out to stay within the
+	// response size limit.
+	TruncatedBySize bool `json:"truncated_by_size,omitempty"`
+	// NextCursor continues after the last row when rows are in key order and
+	// more rows follow.
+	NextCursor string `json:"next_cursor,omitempty"`
+	// TotalEstimated is set when Total comes from the table statistics rather
+	// than a count. Total is -1 when it wasn't computed at all.
+	TotalEstimated bool `json:"total_estimated,omitempty"`
+	// ViewSQL is the view's CREATE VIEW statement when browsing a view.
+	ViewSQL string `json:"view_sql,omitempty"`
+}
+
+// Scope is a server-side filter restricting a table to rows where Column
+// equals Value.
+type Scope struct {
+	Column string
+	Value  string
+}
+
+// JSONPath selects a value inside a JSON column with json_extract.
+type JSONPath struct {
+	Column string
+	Path   string
 }
 
+// Filter is a structured row filter: a column compared to a bound value with
+// one of the allowed operators (=, !=, <, <=, >, >=, LIKE, IN, IS NULL).
+type Filter struct {
+	Column string      `json:"column"`
+	Op     string      `json:"op"`
+	Value  interface{} `json:"value"`
+}
+
+// SortTerm is one column of a multi-column sort with its direction, "asc" or
+// "desc".
+type SortTerm struct {
+	Column    string
+	Direction string
+}
+
+// TableQuery holds the paging, sorting and filtering options for reading table rows.
+type TableQuery struct {
+	Limit         int
+	Offset        int
+	SortColumn    string
+	SortDirection string
+	Collation     string
+	WhereClause   string
+	DefaultSort   bool
+	// Sort orders by several columns in turn and takes precedence over
+	// SortColumn and SortDirection.
+	Sort []SortTerm
+	// Filters are structured conditions with their values bound as parameters.
+	Filters []Filter
+	// Columns projects the result onto the named columns.
+	Columns []string
+	// MaxColumns caps the number of returned columns when no projection is given.
+	MaxColumns int
+	// Snapshot reads through the consistent view of an open snapshot session.
+	Snapshot string
+	// Search matches rows containing the term in any text column.
+	Search string
+	// Fold makes Search case- and accent-insensitive.
+	Fold bool
+	// Scopes are server-configured filters that are always applied.
+	Scopes []Scope
+	// JSONPaths adds a computed column per path with the extracted JSON value.
+	JSONPaths []JSONPath
+	// RowKeyFormat adds each row's key in the given format; empty adds none.
+	RowKeyFormat string
+	// MaxResponseBytes stops adding rows once their JSON encoding would exceed
+	// this many bytes; 0 means no limit.
+	MaxResponseBytes int
+	// After is a cursor from a previous page: rows continue after its key and
+	// the total isn't counted.
+	After string
+	// Count is how the total is computed: exactly (empty), or CountEstimate
+	// or CountSkip.
+	Count string
+}
+
+// Ways of computing the total of table data besides an exact COUNT(*).
+const (
+	// CountEstimate uses the row count recorded by ANALYZE when the query has
+	// no filters, falling back to an exact count.
+	CountEstimate = "estimate"
+	// CountSkip leaves the total unknown (-1).
+	CountSkip = "skip"
+)
+
+// Row key formats for identifying the rows of table data in later edits.
+const (
+	// RowKeyObject adds the key columns as an object under RowKeyField.
+	RowKeyObject = "object"
+	// RowKeyEmbedded adds the key columns to the row itself.
+	RowKeyEmbedded = "embedded"
+	// RowKeyToken adds an opaque token encoding the key under RowKeyField.
+	RowKeyToken = "token"
+
+	RowKeyField = "_key"
+)
+
+// IsValidRowKeyFormat reports whether format is a supported row key format;
+// empty means rows carry no separate key.
+func IsValidRowKeyFormat(format string) bool {
+	switch format {
+	case "", RowKeyObject, RowKeyEmbedded, RowKeyToken:
+		return true
+	}
+	return false
+}
+
+// InsertRequest carries a row either as a column/value map in Data, or as
+// parallel Columns and Values slices when the column order matters.
 type InsertRequest struct {
-	Data map[string]interface{} `json:"data"`
+	Data    map[string]interface{} `json:"data"`
+	Columns []string               `json:"columns"`
+	Values  []interface{}          `json:"values"`
+}
+
+// BulkInsertRequest carries rows as column/value maps that all set the same
+// columns.
+type BulkInsertRequest struct {
+	Rows []map[string]interface{} `json:"rows"`
 }
 
+// UpdateRequest identifies the row either by Where or by a Key taken from
+// table data, as an object or a token.
 type UpdateRequest struct {
 	Data  map[string]interface{} `json:"data"`
 	Where map[string]interface{} `json:"where"`
+	Key   interface{}            `json:"key,omitempty"`
+}
+
+// FieldChange holds a field's value before and after an update.
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// MarshalJSON encodes the change with both values rendered by JSONValue.
+func (c FieldChange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Before interface{} `json:"before"`
+		After  interface{} `json:"after"`
+	}{JSONValue(c.Before), JSONValue(c.After)})
+}
+
+// UpdateResult describes the outcome of an update. Noop is set when every
+// submitted value already matched, in which case nothing was written.
+type UpdateResult struct {
+	RowsAffected int64                  `json:"rows_affected"`
+	Noop         bool                   `json:"noop"`
+	Changes      map[string]FieldChange `json:"changes"`
 }
 
 type DeleteRequest struct {
 	Where map[string]interface{} `json:"where"`
+	Key   interface{}            `json:"key,omitempty"`
 }
 
 type DatabaseInfo struct {
 	Filename string `json:"filename"`
+	ReadOnly bool   `json:"read_only"`
+	// JournalMode is the journal mode in effect, e.g. "wal" or "delete".
+	JournalMode string `json:"journal_mode"`
+	// Databases lists the names of all databases the server has open.
+	Databases []string `json:"databases,omitempty"`
+}
+
+// AttachedDatabase is a database file attached to the SQL console under
+// Alias.
+type AttachedDatabase struct {
+	Alias string `json:"alias"`
+	Path  string `json:"path"`
+}
+
+// AttachRequest names a database file to attach and its alias.
+type AttachRequest struct {
+	Alias string `json:"alias"`
+	Path  string `json:"path"`
+}
+
+// DatabaseEntry describes one of the databases the server has open. Name is
+// what clients pass as the db query parameter.
+type DatabaseEntry struct {
+	Name     string `json:"name"`
+	Filename string `json:"filename"`
+	ReadOnly bool   `json:"read_only"`
+	Default  bool   `json:"default"`
+}
+
+// ForeignKeyViolation is a row whose foreign key has no matching parent row.
+// RowID is nil for WITHOUT ROWID tables. ForeignKeyID matches the id of the
+// table's foreign keys.
+type ForeignKeyViolation struct {
+	Table        string `json:"table"`
+	RowID        *int64 `json:"rowid"`
+	Parent       string `json:"parent"`
+	ForeignKeyID int    `json:"fk_id"`
+}
+
+// TableStat is a table's row count and size on disk. SizeBytes is nil when
+// the size is unavailable.
+type TableStat struct {
+	Name      string `json:"name"`
+	Rows      int    `json:"rows"`
+	SizeBytes *int64 `json:"size_bytes"`
+}
+
+// ColumnStats summarizes a column's values. Min, Max, Avg and Sum are only
+// computed for columns with a numeric declared type, and are left out when
+// the column has no values.
+type ColumnStats struct {
+	Column        string      `json:"column"`
+	Type          string      `json:"type"`
+	Numeric       bool        `json:"numeric"`
+	Count         int         `json:"count"`
+	NullCount     int         `json:"null_count"`
+	DistinctCount int         `json:"distinct_count"`
+	Min           interface{} `json:"min,omitempty"`
+	Max           interface{} `json:"max,omitempty"`
+	Avg           *float64    `json:"avg,omitempty"`
+	Sum           interface{} `json:"sum,omitempty"`
+}
+
+type CheckpointResult struct {
+	Busy               int `json:"busy"`
+	LogFrames          int `json:"log_frames"`
+	CheckpointedFrames int `json:"checkpointed_frames"`
+}
+
+type WALStatus struct {
+	JournalMode    string            `json:"journal_mode"`
+	WALFileExists  bool              `json:"wal_file_exists"`
+	WALFileSize    int64             `json:"wal_file_size"`
+	LastCheckpoint *CheckpointResult `json:"last_checkpoint"`
 }
 
 type SQLQueryResult struct {
@@ -45,8 +340,378 @@ type SQLQueryResult struct {
 	Rows         [][]interface{} `json:"rows"`
 	RowCount     int            `json:"rowCount"`
 	RowsAffected int            `json:"rowsAffected,omitempty"`
+	// SchemaChanged is set when the statement altered the schema, in which
+	// case Tables holds the refreshed table list.
+	SchemaChanged bool    `json:"schema_changed,omitempty"`
+	Tables        []Table `json:"tables,omitempty"`
+	// Truncated is set when rows were left out to stay within the row limit.
+	Truncated bool `json:"truncated,omitempty"`
+	// TruncatedBySize is set when rows were left out to stay within the
+	// response size limit.
+	TruncatedBySize bool `json:"truncated_by_size,omitempty"`
+	// ElapsedMs is how long the database took to run the statement and
+	// produce its rows, in milliseconds.
+	ElapsedMs float64 `json:"elapsed_ms,omitempty"`
+}
+
+// QueryHistoryEntry records a statement run in the SQL console and how it
+// went. Results aren't kept.
+type QueryHistoryEntry struct {
+	SQL        string    `json:"sql"`
+	ExecutedAt time.Time `json:"executed_at"`
+	ElapsedMs  float64   `json:"elapsed_ms"`
+	// RowCount is the number of rows returned, or affected by a statement
+	// that returns none.
+	RowCount int    `json:"row_count"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SQLStatementResult is the result of one statement of a SQL script.
+type SQLStatementResult struct {
+	SQL string `json:"sql"`
+	SQLQueryResult
+}
+
+// SQLScriptResult holds the per-statement results of a SQL script run in a
+// single transaction, and the rows affected by all of them.
+type SQLScriptResult struct {
+	Statements    []SQLStatementResult `json:"statements"`
+	RowsAffected  int                  `json:"rowsAffected"`
+	SchemaChanged bool                 `json:"schema_changed,omitempty"`
+	Tables        []Table              `json:"tables,omitempty"`
+}
+
+// CSV binary formats for BLOB cells.
+const (
+	CSVBinaryBase64      = "base64"
+	CSVBinaryPlaceholder = "placeholder"
+)
+
+// CSVOptions controls how cells are rendered in CSV exports.
+type CSVOptions struct {
+	// Null is written for NULL cells (empty by default).
+	Null string
+	// Binary is CSVBinaryBase64 (the default) or CSVBinaryPlaceholder.
+	Binary string
+	// Delimiter separates fields; zero means a comma.
+	Delimiter rune
+	// CRLF ends lines with \r\n instead of \n.
+	CRLF bool
 }
 
 type ExecuteSQLRequest struct {
 	SQL string `json:"sql"`
-}
\ No newline at end of file
+	// Params are bound to the statement's placeholders in order.
+	Params []interface{} `json:"params,omitempty"`
+	// MaxRows overrides the server's row limit for this query; 0 means no
+	// limit.
+	MaxRows *int `json:"maxRows,omitempty"`
+}
+
+// UnmarshalJSON decodes Params with integers as int64, since float64 would
+// round those beyond ±2^53 before they are bound.
+func (r *ExecuteSQLRequest) UnmarshalJSON(data []byte) error {
+	type request ExecuteSQLRequest
+	var raw struct {
+		request
+		Params []json.RawMessage `json:"params,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*r = ExecuteSQLRequest(raw.request)
+	if raw.Params == nil {
+		return nil
+	}
+
+	r.Params = make([]interface{}, len(raw.Params))
+	for i, param := range raw.Params {
+		decoder := json.NewDecoder(bytes.NewReader(param))
+		decoder.UseNumber()
+		if err := decoder.Decode(&r.Params[i]); err != nil {
+			return err
+		}
+		if number, ok := r.Params[i].(json.Number); ok {
+			if v, err := number.Int64(); err == nil {
+				r.Params[i] = v
+			} else if v, err := number.Float64(); err == nil {
+				r.Params[i] = v
+			} else {
+				return fmt.Errorf("param %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// SQLValidation reports whether every statement of a script compiles. When
+// SQLite names the offending token, Near holds it and Offset its byte position
+// in the whole SQL.
+type SQLValidation struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+	// Index is the 0-based position of the failing statement in a script
+	Index  *int   `json:"index,omitempty"`
+	Near   string `json:"near,omitempty"`
+	Offset *int   `json:"offset,omitempty"`
+}
+
+// GenerateSQLRequest selects the rows to render as INSERT statements, by
+// primary key (or rowid) and/or structured filters.
+type GenerateSQLRequest struct {
+	IDs     []interface{} `json:"ids"`
+	Filters []Filter      `json:"filters"`
+}
+
+// RenameRequest gives the new name of a renamed table or column.
+type RenameRequest struct {
+	NewName string `json:"new_name"`
+}
+
+// CreateTableRequest defines a new table by its name and columns.
+type CreateTableRequest struct {
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+}
+
+// Index describes a table index. Columns lists the key columns in order,
+// with "<expression>" for indexed expressions.
+type Index struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+	// Origin is "c" for CREATE INDEX, "u" for a UNIQUE constraint and "pk"
+	// for a PRIMARY KEY.
+	Origin string `json:"origin"`
+	// Partial is set for indexes with a WHERE clause, whose predicate is Where.
+	Partial bool   `json:"partial"`
+	Where   string `json:"where,omitempty"`
+}
+
+// CreateIndexRequest defines an index on a table's columns. Where is an
+// optional SQL predicate that makes the index partial.
+type CreateIndexRequest struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+	Where   string   `json:"where"`
+}
+
+// View is a view and the CREATE VIEW statement defining it.
+type View struct {
+	Name string `json:"name"`
+	SQL  string `json:"sql"`
+}
+
+// Trigger is a trigger, the table or view it fires on and its CREATE TRIGGER
+// statement.
+type Trigger struct {
+	Name  string `json:"name"`
+	Table string `json:"table"`
+	SQL   string `json:"sql"`
+}
+
+type CreateViewRequest struct {
+	Name   string `json:"name"`
+	Select string `json:"select"`
+}
+
+// StringifyNumbers converts every numeric value in the result rows to its
+// decimal string form so that large integers survive JSON decoding in clients
+// that parse numbers as IEEE 754 doubles.
+func (r *SQLQueryResult) StringifyNumbers() {
+	for _, row := range r.Rows {
+		for i, val := range row {
+			switch v := val.(type) {
+			case int64:
+				row[i] = strconv.FormatInt(v, 10)
+			case int:
+				row[i] = strconv.Itoa(v)
+			case float64:
+				row[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+	}
+}
+
+// ForeignKey is one column of a foreign key constraint. The columns of a
+// composite foreign key share an ID and are ordered by Seq.
+type ForeignKey struct {
+	ID               int    `json:"id"`
+	Seq              int    `json:"seq"`
+	Column           string `json:"column"`
+	ReferencedTable  string `json:"referenced_table"`
+	ReferencedColumn string `json:"referenced_column"`
+	OnUpdate         string `json:"on_update"`
+	OnDelete         string `json:"on_delete"`
+}
+
+// ColumnarData is a column-major (struct-of-arrays) rendering of a result set:
+// Values[i] holds every value of Columns[i], in row order.
+type ColumnarData struct {
+	Columns    []string        `json:"columns"`
+	Values     [][]interface{} `json:"values"`
+	Total      int             `json:"total,omitempty"`
+	RowCount   int             `json:"rowCount"`
+	Page       int             `json:"page,omitempty"`
+	TotalPages int             `json:"total_pages,omitempty"`
+
+	TruncatedBySize bool    `json:"truncated_by_size,omitempty"`
+	NextCursor      string  `json:"next_cursor,omitempty"`
+	TotalEstimated  bool    `json:"total_estimated,omitempty"`
+	Truncated       bool    `json:"truncated,omitempty"`
+	ElapsedMs       float64 `json:"elapsed_ms,omitempty"`
+}
+
+// Columnar converts the row-major table data into column-major form. Schema
+// columns come first, followed by any extra row fields (such as expanded labels).
+func (d *TableData) Columnar() *ColumnarData {
+	names := make([]string, 0, len(d.Columns))
+	known := make(map[string]bool, len(d.Columns))
+	for _, col := range d.Columns {
+		names = append(names, col.Name)
+		known[col.Name] = true
+	}
+
+	var extra []string
+	for _, row := range d.Rows {
+		for name := range row {
+			if !known[name] {
+				known[name] = true
+				extra = append(extra, name)
+			}
+		}
+	}
+	sort.Strings(extra)
+	names = append(names, extra...)
+
+	values := make([][]interface{}, len(names))
+	for i, name := range names {
+		values[i] = make([]interface{}, len(d.Rows))
+		for j, row := range d.Rows {
+			values[i][j] = JSONValue(row[name])
+		}
+	}
+
+	return &ColumnarData{
+		Columns:    names,
+		Values:     values,
+		Total:      d.Total,
+		RowCount:   len(d.Rows),
+		Page:       d.Page,
+		TotalPages: d.TotalPages,
+
+		TruncatedBySize: d.TruncatedBySize,
+		NextCursor:      d.NextCursor,
+		TotalEstimated:  d.TotalEstimated,
+	}
+}
+
+// Columnar converts the row-major query result into column-major form.
+func (r *SQLQueryResult) Columnar() *ColumnarData {
+	values := make([][]interface{}, len(r.Columns))
+	for i := range r.Columns {
+		values[i] = make([]interface{}, len(r.Rows))
+		for j, row := range r.Rows {
+			values[i][j] = row[i]
+		}
+	}
+
+	return &ColumnarData{Columns: r.Columns, Values: values, RowCount: r.RowCount, Truncated: r.Truncated, TruncatedBySize: r.TruncatedBySize, ElapsedMs: r.ElapsedMs}
+}
+
+type SchemaDiffRequest struct {
+	Path string `json:"path"`
+}
+
+type ColumnChange struct {
+	Column  string `json:"column"`
+	Current Column `json:"current"`
+	Other   Column `json:"other"`
+}
+
+type TableDiff struct {
+	Table                string         `json:"table"`
+	ColumnsOnlyInCurrent []string       `json:"columns_only_in_current"`
+	ColumnsOnlyInOther   []string       `json:"columns_only_in_other"`
+	ChangedColumns       []ColumnChange `json:"changed_columns"`
+}
+
+// SchemaDiff describes how the current database schema differs from another database.
+type SchemaDiff struct {
+	TablesOnlyInCurrent []string    `json:"tables_only_in_current"`
+	TablesOnlyInOther   []string    `json:"tables_only_in_other"`
+	Tables              []TableDiff `json:"tables"`
+}
+
+// Snapshot identifies a read transaction that keeps a consistent view of the
+// database across several paged requests.
+type Snapshot struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type TableUsage struct {
+	Name         string    `json:"name"`
+	AccessCount  int       `json:"access_count"`
+	LastAccessed time.Time `json:"last_accessed"`
+}
+
+// SavedQuery is a named SQL snippet kept in the database.
+type SavedQuery struct {
+	Name    string    `json:"name"`
+	SQL     string    `json:"sql"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+type SaveQueryRequest struct {
+	Name string `json:"name"`
+	SQL  string `json:"sql"`
+}
+
+type SaveAsRequest struct {
+	Path string `json:"path"`
+	// Overwrite confirms replacing an existing file at Path.
+	Overwrite bool `json:"overwrite"`
+	// Switch makes the server continue with the saved copy.
+	Switch bool `json:"switch"`
+}
+
+type Diagnostics struct {
+	SQLiteVersion string                 `json:"sqlite_version"`
+	DriverVersion string                 `json:"driver_version"`
+	GoVersion     string                 `json:"go_version"`
+	OS            string                 `json:"os"`
+	Arch          string                 `json:"arch"`
+	JournalMode   string                 `json:"journal_mode"`
+	Config        map[string]interface{} `json:"config"`
+}
+
+// FTSCandidates lists a table's text columns that could be added to a
+// full-text index, and those an external-content FTS table already covers.
+type FTSCandidates struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	Indexed []string `json:"indexed"`
+}
+
+// CreateFTSRequest lists the columns to add to a new full-text index.
+type CreateFTSRequest struct {
+	Columns []string `json:"columns"`
+}
+
+// FTSSearchResult holds the rows matching a full-text search, each with its
+// _rank, and the total number of matches.
+type FTSSearchResult struct {
+	FTSTable string `json:"fts_table"`
+	Rows     []Row  `json:"rows"`
+	Total    int    `json:"total"`
+}
+
+// RowidChunk is an inclusive rowid range covering Count rows of a table.
+type RowidChunk struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Count int   `json:"count"`
+}
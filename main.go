@@ -6,29 +6,118 @@ import (
 	"fmt"
 	"io/fs"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"sqliter/internal/api"
 	"sqliter/internal/db"
+	"sqliter/internal/models"
+	"strings"
+	"time"
 )
 
+// waitForDBInterval is how often --wait-for-db checks for the database file.
+const waitForDBInterval = 500 * time.Millisecond
+
 //go:embed all:web/dist
 var staticFiles embed.FS
 
 func main() {
 	var (
-		port   = flag.String("port", "2826", "Port to run the server on")
-		dbPath = flag.String("db", "", "Path to SQLite database file")
+		host = flag.String("host", "127.0.0.1", "Host/interface to bind the server to (use 0.0.0.0 for all interfaces)")
+		port = flag.String("port", "2826", "Port to run the server on")
+
+		defaultSort = flag.Bool("default-sort", true, "Order table rows by primary key when no sort is requested")
+		maxColumns  = flag.Int("max-columns", 0, "Maximum number of columns returned for a table when no projection is requested (0 = unlimited)")
+		trackUsage  = flag.Bool("track-usage", false, "Record which tables are browsed to power the recent tables list (writes to the database on every table read)")
+		readOnly    = flag.Bool("read-only", false, "Open the database read-only and reject every request that would change it")
+
+		maxSQLLength     = flag.Int("max-sql-length", 1<<20, "Maximum length in bytes of a SQL console query (0 = unlimited)")
+		requestTimeout   = flag.Duration("request-timeout", 0, "Cancel requests that take longer than this, responding 503 if nothing was sent yet (0 = no timeout)")
+		waitForDB        = flag.Duration("wait-for-db", 0, "Wait up to this long for the database file to appear before opening it (0 = don't wait)")
+		maxResponseBytes = flag.Int("max-response-bytes", 64<<20, "Stop adding rows to table data and query results once they reach this many bytes of JSON (0 = unlimited)")
+		maxRows          = flag.Int("max-rows", 10000, "Stop reading SQL console query results after this many rows (0 = unlimited)")
+		maxBlobSize      = flag.Int64("max-blob-size", 16<<20, "Maximum size in bytes of a BLOB cell upload (0 = SQLite's 1 GB limit)")
+		queryTimeout     = flag.Duration("query-timeout", 30*time.Second, "Interrupt SQL console queries that run longer than this (0 = no timeout)")
+		authUser         = flag.String("auth-user", "", "Require HTTP basic auth with this user name for the API (needs --auth-pass)")
+		authPass         = flag.String("auth-pass", "", "Password for --auth-user")
+		authToken        = flag.String("auth-token", "", "Require this bearer token for the API")
+		journalMode      = flag.String("journal-mode", "wal", "Journal mode to switch the database to: wal, delete, truncate, persist, memory or off (empty = leave unchanged; ignored with --read-only)")
+		busyTimeout      = flag.Duration("busy-timeout", 5*time.Second, "How long a connection retries when the database is locked before failing (0 = fail immediately)")
+		queryHistory     = flag.Int("query-history", 100, "Number of SQL console statements to keep in the query history (0 = don't record)")
+		persistHistory   = flag.Bool("persist-query-history", false, "Store the query history in the database so it survives restarts (ignored with --read-only)")
+		rowKeyFormat     = flag.String("row-key-format", "", "Add each row's key to table data: 'object' (a _key object), 'embedded' (key columns in the row) or 'token' (an opaque _key token)")
 	)
+	var dbPaths stringsFlag
+	flag.Var(&dbPaths, "db", "Path to a SQLite database file, or a directory of them (repeatable; the first is the default)")
+	scopes := scopeFlags{}
+	flag.Var(scopes, "scope", "Always filter a table to matching rows, as table:column=value (repeatable)")
+	var initPragmas stringsFlag
+	flag.Var(&initPragmas, "init-pragma", "PRAGMA run on every new database connection, e.g. foreign_keys=ON (repeatable)")
 	flag.Parse()
 
-	if *dbPath == "" {
+	if len(dbPaths) == 0 {
 		log.Fatal("Database path is required. Use --db flag to specify the SQLite database file.")
 	}
 
-	database, err := db.NewSQLiteDB(*dbPath)
+	if (*authUser == "") != (*authPass == "") {
+		log.Fatal("--auth-user and --auth-pass must be set together.")
+	}
+
+	if !models.IsValidRowKeyFormat(*rowKeyFormat) {
+		log.Fatal("Invalid --row-key-format, must be 'object', 'embedded' or 'token'.")
+	}
+
+	if *journalMode != "" && !db.IsValidJournalMode(*journalMode) {
+		log.Fatal("Invalid --journal-mode, must be 'wal', 'delete', 'truncate', 'persist', 'memory' or 'off'.")
+	}
+
+	if *waitForDB > 0 {
+		for _, path := range dbPaths {
+			if err := waitForFile(path, *waitForDB, waitForDBInterval); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	paths, err := databaseFiles(dbPaths)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatal(err)
+	}
+
+	openDB := db.NewSQLiteDB
+	if *readOnly {
+		openDB = db.NewReadOnlySQLiteDB
+	}
+	connectionJournalMode := ""
+	if !*readOnly && db.IsConnectionJournalMode(*journalMode) {
+		connectionJournalMode = *journalMode
+	}
+	pragmas := connectionPragmas(*busyTimeout, connectionJournalMode, initPragmas)
+	databases := make([]*db.SQLiteDB, len(paths))
+	for i, path := range paths {
+		if databases[i], err = openDB(path, pragmas...); err != nil {
+			log.Fatalf("Failed to connect to database %s: %v", path, err)
+		}
+		defer databases[i].Close()
+
+		if *journalMode != "" && !*readOnly && connectionJournalMode == "" {
+			mode, err := databases[i].SetJournalMode(*journalMode)
+			if err != nil {
+				log.Fatalf("Failed to set journal mode of %s: %v", path, err)
+			}
+			if !strings.EqualFold(mode, *journalMode) {
+				log.Printf("Database %s stays in %s journal mode, %s isn't supported for it", path, mode, *journalMode)
+			}
+		}
+
+		if *queryHistory > 0 {
+			if err := databases[i].EnableQueryHistory(*queryHistory, *persistHistory); err != nil {
+				log.Fatalf("Failed to enable query history of %s: %v", path, err)
+			}
+		}
 	}
-	defer database.Close()
 
 	// Create sub-filesystem for the dist directory
 	distFS, err := fs.Sub(staticFiles, "web/dist")
@@ -36,11 +125,142 @@ func main() {
 		log.Fatalf("Failed to create sub-filesystem for static files: %v", err)
 	}
 
-	handler := api.NewHandler(database, distFS)
+	handler := api.NewHandler(databases[0], distFS, api.Config{
+		DefaultSort:      *defaultSort,
+		MaxColumns:       *maxColumns,
+		TrackUsage:       *trackUsage,
+		MaxSQLLength:     *maxSQLLength,
+		Scopes:           scopes,
+		RowKeyFormat:     *rowKeyFormat,
+		MaxResponseBytes: *maxResponseBytes,
+		MaxRows:          *maxRows,
+		QueryTimeout:     *queryTimeout,
+		MaxBlobSize:      *maxBlobSize,
+		RequestTimeout:   *requestTimeout,
+		JournalMode:      *journalMode,
+		BusyTimeout:      *busyTimeout,
+		AuthUser:         *authUser,
+		AuthPass:         *authPass,
+		AuthToken:        *authToken,
+	})
+	for _, database := range databases[1:] {
+		if err := handler.AddDatabase(database); err != nil {
+			log.Fatal(err)
+		}
+	}
 	router := handler.SetupRoutes()
 
-	fmt.Printf("Starting SQLiter on port %s with database %s\n", *port, *dbPath)
-	if err := router.Run(":" + *port); err != nil {
+	addr := listenAddress(*host, *port)
+	fmt.Printf("Starting SQLiter on %s with database %s\n", addr, strings.Join(paths, ", "))
+	if err := http.ListenAndServe(addr, api.WithRequestTimeout(router, *requestTimeout)); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+// listenAddress combines the host and port flags into a listen address.
+func listenAddress(host, port string) string {
+	return net.JoinHostPort(host, port)
+}
+
+// waitForFile polls until the file exists and can be opened for reading, or
+// the timeout elapses. Containers may mount the database after the process
+// starts, and opening a missing path would create an empty database instead.
+func waitForFile(path string, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.Open(path)
+		if err == nil {
+			f.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("database file %s not available after %s: %w", path, timeout, err)
+		}
+		log.Printf("Waiting for database file %s: %v", path, err)
+		time.Sleep(interval)
+	}
+}
+
+// databaseExtensions are the file extensions picked up from a --db directory.
+var databaseExtensions = map[string]bool{".db": true, ".sqlite": true, ".sqlite3": true, ".db3": true}
+
+// connectionPragmas returns the PRAGMAs run on every new connection: the
+// busy timeout and the journal mode, when it's one that only applies to a
+// single connection, then the --init-pragma values, which can override them.
+func connectionPragmas(busyTimeout time.Duration, journalMode string, initPragmas []string) []string {
+	pragmas := []string{fmt.Sprintf("busy_timeout=%d", busyTimeout.Milliseconds())}
+	if journalMode != "" {
+		pragmas = append(pragmas, "journal_mode="+strings.ToLower(journalMode))
+	}
+	return append(pragmas, initPragmas...)
+}
+
+// databaseFiles expands the --db paths, replacing each directory with the
+// database files directly inside it in name order.
+func databaseFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			// A missing file is created as an empty database
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read database directory %s: %w", path, err)
+		}
+		found := false
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && databaseExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+				files = append(files, filepath.Join(path, entry.Name()))
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no database files found in directory %s", path)
+		}
+	}
+	return files, nil
+}
+
+// stringsFlag collects the values of a repeatable string flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// scopeFlags collects repeated --scope flags, keyed by table name.
+type scopeFlags map[string][]models.Scope
+
+func (f scopeFlags) String() string {
+	var specs []string
+	for table, scopes := range f {
+		for _, scope := range scopes {
+			specs = append(specs, table+":"+scope.Column+"="+scope.Value)
+		}
+	}
+	return strings.Join(specs, ",")
+}
+
+// Set parses a table:column=value scope. The value is bound as a query
+// parameter, so it is never interpreted as SQL.
+func (f scopeFlags) Set(spec string) error {
+	table, condition, ok := strings.Cut(spec, ":")
+	if !ok || table == "" {
+		return fmt.Errorf("invalid scope %q, must be table:column=value", spec)
+	}
+	column, value, ok := strings.Cut(condition, "=")
+	if !ok || column == "" {
+		return fmt.Errorf("invalid scope %q, must be table:column=value", spec)
+	}
+	f[table] = append(f[table], models.Scope{Column: column, Value: value})
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sqliter/internal/db"
+	"testing"
+	"time"
+)
+
+func TestListenAddress(t *testing.T) {
+	tests := []struct {
+		host, port, want string
+	}{
+		{"127.0.0.1", "2826", "127.0.0.1:2826"},
+		{"0.0.0.0", "8080", "0.0.0.0:8080"},
+		{"", "2826", ":2826"},
+		{"::1", "2826", "[::1]:2826"},
+	}
+
+	for _, tt := range tests {
+		if got := listenAddress(tt.host, tt.port); got != tt.want {
+			t.Errorf("listenAddress(%q, %q) = %q, want %q", tt.host, tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestScopeFlags(t *testing.T) {
+	scopes := scopeFlags{}
+	for _, spec := range []string{"orders:tenant_id=42", "orders:region=eu:west", "users:tenant_id=42"} {
+		if err := scopes.Set(spec); err != nil {
+			t.Fatalf("Set(%q) failed: %v", spec, err)
+		}
+	}
+
+	want := scopeFlags{
+		"orders": {{Column: "tenant_id", Value: "42"}, {Column: "region", Value: "eu:west"}},
+		"users":  {{Column: "tenant_id", Value: "42"}},
+	}
+	if !reflect.DeepEqual(scopes, want) {
+		t.Errorf("Expected %v, got %v", want, scopes)
+	}
+
+	for _, spec := range []string{"orders", "orders:tenant_id", ":tenant_id=1", "orders:=1"} {
+		if err := scopes.Set(spec); err == nil {
+			t.Errorf("Expected Set(%q) to fail", spec)
+		}
+	}
+}
+
+func TestWaitForFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "late.db")
+
+	// The file is mounted shortly after startup
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		os.WriteFile(path, nil, 0o644)
+	}()
+
+	if err := waitForFile(path, 5*time.Second, 20*time.Millisecond); err != nil {
+		t.Fatalf("Expected the file to be found once it appears, got %v", err)
+	}
+	database, err := db.NewSQLiteDB(path)
+	if err != nil {
+		t.Fatalf("Expected the database to open once it appears, got %v", err)
+	}
+	database.Close()
+
+	if err := waitForFile(filepath.Join(t.TempDir(), "never.db"), 50*time.Millisecond, 20*time.Millisecond); err == nil {
+		t.Error("Expected an error when the file never appears")
+	}
+}
+
+func TestDatabaseFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.sqlite", "a.db", "notes.txt", "C.DB3"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0o644)
+	}
+	os.Mkdir(filepath.Join(dir, "nested.db"), 0o755)
+
+	got, err := databaseFiles([]string{"main.db", dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"main.db", filepath.Join(dir, "C.DB3"), filepath.Join(dir, "a.db"), filepath.Join(dir, "b.sqlite")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if _, err := databaseFiles([]string{t.TempDir()}); err == nil {
+		t.Error("Expected an error for a directory without databases")
+	}
+}
+
+func TestConnectionPragmas(t *testing.T) {
+	got := connectionPragmas(2500*time.Millisecond, "", []string{"foreign_keys=ON"})
+	want := []string{"busy_timeout=2500", "foreign_keys=ON"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	got = connectionPragmas(time.Second, "TRUNCATE", nil)
+	want = []string{"busy_timeout=1000", "journal_mode=truncate"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// The pragmas open a working database, which then switches to WAL
+	path := filepath.Join(t.TempDir(), "test.db")
+	database, err := db.NewSQLiteDB(path, connectionPragmas(time.Second, "", nil)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	mode, err := database.SetJournalMode("WAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode != "wal" {
+		t.Errorf("Expected wal journal mode, got %s", mode)
+	}
+	info, err := database.GetDatabaseInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.JournalMode != "wal" {
+		t.Errorf("Expected the info to report wal, got %s", info.JournalMode)
+	}
+
+	if _, err := database.SetJournalMode("fast"); err == nil {
+		t.Error("Expected an invalid journal mode to fail")
+	}
+	// Rollback modes other than delete only apply to the connection that sets them
+	if _, err := database.SetJournalMode("truncate"); err == nil {
+		t.Error("Expected a per-connection journal mode to be rejected")
+	}
+}
@@ -6,12 +6,13 @@ import { SqlEditor } from './components/SqlEditor';
 import { ThemeToggle } from './components/ThemeToggle';
 import { ThemeProvider } from './contexts/ThemeContext';
 import { api } from './api';
-import { Table, DatabaseInfo } from './types';
+import { Table, View, DatabaseInfo } from './types';
 
 // Layout component that wraps all pages
-function Layout({ children, tables, databaseInfo, pendingChangesByTable }: {
+function Layout({ children, tables, views, databaseInfo, pendingChangesByTable }: {
   children: React.ReactNode;
   tables: Table[];
+  views: View[];
   databaseInfo: DatabaseInfo | null;
   pendingChangesByTable: Record<string, number>;
 }) {
@@ -26,7 +27,9 @@ function Layout({ children, tables, databaseInfo, pendingChangesByTable }: {
                 SQLiter{databaseInfo && ` - ${databaseInfo.filename}`}
               </h1>
             </Link>
-            <p className="text-blue-100 dark:text-gray-400 text-sm">SQLite Database Editor</p>
+            <p className="text-blue-100 dark:text-gray-400 text-sm">
+              SQLite Database Editor{databaseInfo?.read_only && ' (read-only)'}
+            </p>
           </div>
           <ThemeToggle />
         </div>
@@ -35,6 +38,7 @@ function Layout({ children, tables, databaseInfo, pendingChangesByTable }: {
       <div className="flex-1 flex">
         <TableList
           tables={tables}
+          views={views}
           pendingChangesByTable={pendingChangesByTable}
         />
         <div className="flex-1 overflow-hidden bg-gray-50 dark:bg-gray-900">
@@ -97,6 +101,7 @@ function SqlEditorPage() {
 
 function App() {
   const [tables, setTables] = useState<Table[]>([]);
+  const [views, setViews] = useState<View[]>([]);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
   const [databaseInfo, setDatabaseInfo] = useState<DatabaseInfo | null>(null);
@@ -105,11 +110,13 @@ function App() {
   const loadTables = async () => {
     try {
       setLoading(true);
-      const [fetchedTables, dbInfo] = await Promise.all([
+      const [fetchedTables, fetchedViews, dbInfo] = await Promise.all([
         api.getTables(),
+        api.getViews(),
         api.getDatabaseInfo()
       ]);
       setTables(fetchedTables);
+      setViews(fetchedViews);
       setDatabaseInfo(dbInfo);
       setError(null);
     } catch (err) {
@@ -159,17 +166,17 @@ function App() {
     <ThemeProvider>
       <Routes>
       <Route path="/" element={
-        <Layout tables={tables} databaseInfo={databaseInfo} pendingChangesByTable={pendingChangesByTable}>
+        <Layout tables={tables} views={views} databaseInfo={databaseInfo} pendingChangesByTable={pendingChangesByTable}>
           <HomePage />
         </Layout>
       } />
       <Route path="/table/:tableName" element={
-        <Layout tables={tables} databaseInfo={databaseInfo} pendingChangesByTable={pendingChangesByTable}>
+        <Layout tables={tables} views={views} databaseInfo={databaseInfo} pendingChangesByTable={pendingChangesByTable}>
           <TablePage />
         </Layout>
       } />
       <Route path="/sql" element={
-        <Layout tables={tables} databaseInfo={databaseInfo} pendingChangesByTable={pendingChangesByTable}>
+        <Layout tables={tables} views={views} databaseInfo={databaseInfo} pendingChangesByTable={pendingChangesByTable}>
           <SqlEditorPage />
         </Layout>
       } />
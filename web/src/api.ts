@@ -1,8 +1,17 @@
 import axios from 'axios';
-import { Table, Column, TableData, InsertRequest, UpdateRequest, DeleteRequest, DatabaseInfo } from './types';
+import { Table, View, Column, TableData, InsertRequest, UpdateRequest, UpdateResult, DeleteRequest, DeleteResult, DatabaseInfo } from './types';
 
 const API_BASE = '/api';
 
+// blobToHex turns a {"__blob__": "<base64>"} value from table data or query
+// results into the hex string the grid and hex editor work with.
+export const blobToHex = (value: any): any => {
+  if (value === null || typeof value !== 'object' || typeof value.__blob__ !== 'string') {
+    return value;
+  }
+  return Array.from(atob(value.__blob__), char => char.charCodeAt(0).toString(16).padStart(2, '0')).join('');
+};
+
 export const api = {
   async getDatabaseInfo(): Promise<DatabaseInfo> {
     const response = await axios.get(`${API_BASE}/info`);
@@ -14,6 +23,11 @@ export const api = {
     return response.data.tables;
   },
 
+  async getViews(): Promise<View[]> {
+    const response = await axios.get(`${API_BASE}/views`);
+    return response.data.views;
+  },
+
   async getTableSchema(tableName: string): Promise<Column[]> {
     const response = await axios.get(`${API_BASE}/tables/${tableName}/schema`);
     return response.data.columns;
@@ -27,11 +41,20 @@ export const api = {
     }
     if (whereClause) {
       params.where_clause = whereClause;
+      params.allow_raw = true;
     }
     const response = await axios.get(`${API_BASE}/tables/${tableName}/data`, {
       params
     });
-    return response.data;
+    const data: TableData = response.data;
+    data.rows = data.rows.map(row => {
+      const converted: Record<string, any> = {};
+      Object.keys(row).forEach(key => {
+        converted[key] = blobToHex(row[key]);
+      });
+      return converted;
+    });
+    return data;
   },
 
   async insertRow(tableName: string, data: Record<string, any>): Promise<void> {
@@ -39,14 +62,16 @@ export const api = {
     await axios.post(`${API_BASE}/tables/${tableName}/rows`, request);
   },
 
-  async updateRow(tableName: string, data: Record<string, any>, where: Record<string, any>): Promise<void> {
+  async updateRow(tableName: string, data: Record<string, any>, where: Record<string, any>): Promise<UpdateResult> {
     const request: UpdateRequest = { data, where };
-    await axios.put(`${API_BASE}/tables/${tableName}/rows`, request);
+    const response = await axios.put(`${API_BASE}/tables/${tableName}/rows`, request);
+    return response.data;
   },
 
-  async deleteRow(tableName: string, where: Record<string, any>): Promise<void> {
+  async deleteRow(tableName: string, where: Record<string, any>): Promise<DeleteResult> {
     const request: DeleteRequest = { where };
-    await axios.delete(`${API_BASE}/tables/${tableName}/rows`, { data: request });
+    const response = await axios.delete(`${API_BASE}/tables/${tableName}/rows`, { data: request });
+    return response.data;
   },
 
   async exportTableCSV(tableName: string, sortColumn?: string, sortDirection?: 'asc' | 'desc', whereClause?: string): Promise<Blob> {
@@ -57,6 +82,7 @@ export const api = {
     }
     if (whereClause) {
       params.where_clause = whereClause;
+      params.allow_raw = true;
     }
     const response = await axios.get(`${API_BASE}/tables/${tableName}/export/csv`, {
       params,
@@ -76,9 +76,10 @@ export const EditModal: React.FC<EditModalProps> = ({ isOpen, onClose, onSave, c
     if (initialData) {
       setFormData(initialData);
     } else {
+      // Untouched fields stay null rather than '', so nullable columns get NULL
       const emptyData: Record<string, any> = {};
       columns.forEach(col => {
-        emptyData[col.name] = '';
+        emptyData[col.name] = null;
       });
       setFormData(emptyData);
     }
@@ -86,7 +87,18 @@ export const EditModal: React.FC<EditModalProps> = ({ isOpen, onClose, onSave, c
 
   const handleSubmit = (e: React.FormEvent) => {
     e.preventDefault();
-    onSave(formData);
+    if (initialData) {
+      onSave(formData);
+      return;
+    }
+    // Leave unset fields out of inserts so column defaults apply
+    const data: Record<string, any> = {};
+    Object.keys(formData).forEach(key => {
+      if (formData[key] !== null) {
+        data[key] = formData[key];
+      }
+    });
+    onSave(data);
   };
 
   if (!isOpen) return null;
@@ -7,6 +7,7 @@ import 'ace-builds/src-noconflict/theme-cloud_editor_dark';
 import 'ace-builds/src-noconflict/ext-language_tools';
 
 import { useTheme } from '../contexts/ThemeContext';
+import { blobToHex } from '../api';
 
 interface SqlEditorProps {
   onRefresh?: () => void;
@@ -17,6 +18,7 @@ interface QueryResult {
   rows: any[][];
   rowCount: number;
   executionTime: number;
+  truncated: boolean;
 }
 
 export const SqlEditor: React.FC<SqlEditorProps> = ({ onRefresh }) => {
@@ -24,6 +26,7 @@ export const SqlEditor: React.FC<SqlEditorProps> = ({ onRefresh }) => {
   const [sql, setSql] = useState('-- Enter your SQL query here\nSELECT name FROM sqlite_master WHERE type=\'table\';');
   const [results, setResults] = useState<QueryResult | null>(null);
   const [error, setError] = useState<string | null>(null);
+  const [preview, setPreview] = useState<string | null>(null);
   const [isExecuting, setIsExecuting] = useState(false);
   const [currentPage, setCurrentPage] = useState(1);
   const [pageSize, setPageSize] = useState(50);
@@ -48,6 +51,7 @@ export const SqlEditor: React.FC<SqlEditorProps> = ({ onRefresh }) => {
 
     setIsExecuting(true);
     setError(null);
+    setPreview(null);
     setResults(null);
 
     try {
@@ -64,14 +68,16 @@ export const SqlEditor: React.FC<SqlEditorProps> = ({ onRefresh }) => {
       const data = await response.json();
 
       if (!response.ok) {
-        throw new Error(data.error || 'Query execution failed');
+        throw new Error(data.error?.message || 'Query execution failed');
       }
 
       setResults({
         columns: data.columns || [],
-        rows: data.rows || [],
+        rows: (data.rows || []).map((row: any[]) => row.map(blobToHex)),
         rowCount: data.rowCount || 0,
-        executionTime: endTime - startTime,
+        // Prefer the server's measurement, which leaves out the network
+        executionTime: data.elapsed_ms ?? endTime - startTime,
+        truncated: Boolean(data.truncated || data.truncated_by_size),
       });
 
       // Reset pagination to first page
@@ -88,6 +94,35 @@ export const SqlEditor: React.FC<SqlEditorProps> = ({ onRefresh }) => {
     }
   };
 
+  // Runs a DELETE or UPDATE in a rolled back transaction to show how many
+  // rows it would affect
+  const previewQuery = async () => {
+    if (!sql.trim()) return;
+
+    setError(null);
+    setPreview(null);
+
+    try {
+      const response = await fetch('/api/sql/preview', {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ sql: sql.trim() }),
+      });
+      const data = await response.json();
+
+      if (!response.ok) {
+        throw new Error(data.error?.message || 'Query preview failed');
+      }
+
+      const affected = data.rowsAffected || 0;
+      setPreview(`This statement would affect ${affected.toLocaleString()} ${affected === 1 ? 'row' : 'rows'}. Nothing was changed.`);
+    } catch (err: any) {
+      setError(err.message);
+    }
+  };
+
   const downloadCsv = () => {
     if (!results || results.rows.length === 0) return;
 
@@ -160,6 +195,17 @@ export const SqlEditor: React.FC<SqlEditorProps> = ({ onRefresh }) => {
               <i className={wrapTextMode ? 'ti ti-code-off' : 'ti ti-text-wrap'}></i>
               {wrapTextMode ? 'Code Editor' : 'Wrap Text'}
             </button>
+            {/\b(DELETE|UPDATE)\b/i.test(sql) && (
+              <button
+                onClick={previewQuery}
+                disabled={isExecuting}
+                className="flex items-center gap-2 px-3 py-2 rounded-md text-sm font-medium transition-colors bg-gray-100 dark:bg-gray-700 text-gray-700 dark:text-gray-300 border border-gray-300 dark:border-gray-600 hover:bg-gray-200 dark:hover:bg-gray-600"
+                title="Show how many rows the statement would affect without changing anything"
+              >
+                <i className="ti ti-eye"></i>
+                Preview
+              </button>
+            )}
             <button
               onClick={executeQuery}
               disabled={isExecuting || !sql.trim()}
@@ -255,6 +301,12 @@ SELECT name FROM sqlite_master WHERE type='table';"
           </div>
         )}
 
+        {preview && (
+          <div className="p-4 border-l-4 border-blue-400 dark:border-blue-600 bg-blue-50 dark:bg-blue-900/20">
+            <div className="text-sm text-blue-700 dark:text-blue-400">{preview}</div>
+          </div>
+        )}
+
         {isExecuting && (
           <div className="p-8 text-center text-gray-600 dark:text-gray-400">
             <div className="flex items-center justify-center gap-2">
@@ -271,8 +323,13 @@ SELECT name FROM sqlite_master WHERE type='table';"
               <div className="flex items-center justify-between mb-3">
                 <div className="flex items-center gap-4">
                   <span className="text-sm text-gray-600 dark:text-gray-400">
-                    {results.rowCount} rows returned in {results.executionTime.toFixed(1)}ms
+                    {results.rowCount.toLocaleString()} rows returned in {results.executionTime.toFixed(1)} ms
                   </span>
+                  {results.truncated && (
+                    <span className="text-sm text-yellow-600 dark:text-yellow-400">
+                      Results were truncated; add a LIMIT or narrow the query to see the rest
+                    </span>
+                  )}
                   {results.rows.length > 0 && (
                     <span className="text-sm text-gray-600 dark:text-gray-400">
                       Showing {paginatedData.startIndex + 1}-{paginatedData.endIndex} of {results.rows.length}
@@ -1,13 +1,14 @@
 import React from 'react';
 import { Link, useLocation } from 'react-router-dom';
-import { Table } from '../types';
+import { Table, View } from '../types';
 
 interface TableListProps {
   tables: Table[];
+  views: View[];
   pendingChangesByTable: Record<string, number>;
 }
 
-export const TableList: React.FC<TableListProps> = ({ tables, pendingChangesByTable }) => {
+export const TableList: React.FC<TableListProps> = ({ tables, views, pendingChangesByTable }) => {
   const location = useLocation();
   const currentPath = location.pathname;
 
@@ -60,6 +61,33 @@ export const TableList: React.FC<TableListProps> = ({ tables, pendingChangesByTa
           <p className="text-gray-500 dark:text-gray-400 text-sm p-3">No tables found</p>
         )}
       </div>
+
+      {(views || []).length > 0 && (
+        <>
+          <div className="p-4 border-y border-gray-300 dark:border-gray-600 bg-gray-50 dark:bg-gray-700">
+            <h2 className="text-lg font-semibold text-gray-800 dark:text-gray-200">Views</h2>
+          </div>
+          <div className="p-2">
+            {views.map((view) => (
+              <Link
+                key={view.name}
+                to={`/table/${view.name}`}
+                title={view.sql}
+                className={`w-full text-left p-3 rounded-md mb-1 transition-colors block ${
+                  currentPath === `/table/${view.name}`
+                    ? 'bg-blue-100 dark:bg-blue-900 text-blue-800 dark:text-blue-200 border border-blue-200 dark:border-blue-700'
+                    : 'hover:bg-gray-200 dark:hover:bg-gray-700 text-gray-700 dark:text-gray-300'
+                }`}
+              >
+                <div className="flex items-center">
+                  <i className="ti ti-eye mr-2"></i>
+                  <span className="font-mono text-sm">{view.name}</span>
+                </div>
+              </Link>
+            ))}
+          </div>
+        </>
+      )}
     </div>
   );
 };
\ No newline at end of file
@@ -636,7 +636,7 @@ export const TableView: React.FC<TableViewProps> = ({ tableName, onRefresh, onPe
       onRefresh?.();
     } catch (err: any) {
       console.error('Error inserting row:', err);
-      const errorMessage = err.response?.data?.error || err.message || 'Unknown error occurred';
+      const errorMessage = err.response?.data?.error?.message || err.message || 'Unknown error occurred';
       showError('Failed to Insert Row', errorMessage);
     }
   };
@@ -659,13 +659,16 @@ export const TableView: React.FC<TableViewProps> = ({ tableName, onRefresh, onPe
         }
       });
 
-      await api.updateRow(tableName, updateData, whereClause);
+      const result = await api.updateRow(tableName, updateData, whereClause);
       setEditModal({ isOpen: false, mode: 'insert' });
       loadTableData();
       onRefresh?.();
+      if (!result.noop && result.rows_affected === 0) {
+        showError('Row Not Updated', 'No row matched, it may have been changed or deleted by someone else.');
+      }
     } catch (err: any) {
       console.error('Error updating row:', err);
-      const errorMessage = err.response?.data?.error || err.message || 'Unknown error occurred';
+      const errorMessage = err.response?.data?.error?.message || err.message || 'Unknown error occurred';
       showError('Failed to Update Row', errorMessage);
     }
   };
@@ -687,12 +690,15 @@ export const TableView: React.FC<TableViewProps> = ({ tableName, onRefresh, onPe
         whereClause[col.name] = row[col.name];
       });
 
-      await api.deleteRow(tableName, whereClause);
+      const result = await api.deleteRow(tableName, whereClause);
       loadTableData();
       onRefresh?.();
+      if (result.rows_affected === 0) {
+        showError('Row Not Deleted', 'No row matched, it may have already been deleted.');
+      }
     } catch (err: any) {
       console.error('Error deleting row:', err);
-      const errorMessage = err.response?.data?.error || err.message || 'Unknown error occurred';
+      const errorMessage = err.response?.data?.error?.message || err.message || 'Unknown error occurred';
       showError('Failed to Delete Row', errorMessage);
     }
   };
@@ -824,7 +830,7 @@ export const TableView: React.FC<TableViewProps> = ({ tableName, onRefresh, onPe
       onRefresh?.();
     } catch (err: any) {
       console.error('Error saving changes:', err);
-      const errorMessage = err.response?.data?.error || err.message || 'Unknown error occurred';
+      const errorMessage = err.response?.data?.error?.message || err.message || 'Unknown error occurred';
       showError('Failed to Save Changes', errorMessage);
     }
   };
@@ -869,7 +875,7 @@ export const TableView: React.FC<TableViewProps> = ({ tableName, onRefresh, onPe
       onRefresh?.();
     } catch (err: any) {
       console.error('Error saving all changes:', err);
-      const errorMessage = err.response?.data?.error || err.message || 'Unknown error occurred';
+      const errorMessage = err.response?.data?.error?.message || err.message || 'Unknown error occurred';
       showError('Failed to Save All Changes', errorMessage);
     }
   };
@@ -884,7 +890,7 @@ export const TableView: React.FC<TableViewProps> = ({ tableName, onRefresh, onPe
       onRefresh?.();
     } catch (err: any) {
       console.error('Error adding new row:', err);
-      const errorMessage = err.response?.data?.error || err.message || 'Unknown error occurred';
+      const errorMessage = err.response?.data?.error?.message || err.message || 'Unknown error occurred';
       showError('Failed to Add New Row', errorMessage);
     }
   };
@@ -959,7 +965,7 @@ export const TableView: React.FC<TableViewProps> = ({ tableName, onRefresh, onPe
       onRefresh?.();
     } catch (err: any) {
       console.error('Error deleting rows:', err);
-      const errorMessage = err.response?.data?.error || err.message || 'Unknown error occurred';
+      const errorMessage = err.response?.data?.error?.message || err.message || 'Unknown error occurred';
       showError('Failed to Delete Rows', errorMessage);
     }
   };
@@ -1169,7 +1175,7 @@ export const TableView: React.FC<TableViewProps> = ({ tableName, onRefresh, onPe
       window.URL.revokeObjectURL(url);
     } catch (err: any) {
       console.error('Error exporting CSV:', err);
-      const errorMessage = err.response?.data?.error || err.message || 'Unknown error occurred';
+      const errorMessage = err.response?.data?.error?.message || err.message || 'Unknown error occurred';
       showError('Failed to Export CSV', errorMessage);
     }
   };
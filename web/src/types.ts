@@ -3,6 +3,11 @@ export interface Table {
   type: string;
 }
 
+export interface View {
+  name: string;
+  sql: string;
+}
+
 export interface Column {
   cid: number;
   name: string;
@@ -36,8 +41,20 @@ export interface DeleteRequest {
   where: Record<string, any>;
 }
 
+export interface UpdateResult {
+  rows_affected: number;
+  noop: boolean;
+}
+
+export interface DeleteResult {
+  rows_affected: number;
+}
+
 export interface DatabaseInfo {
   filename: string;
+  read_only: boolean;
+  journal_mode: string;
+  databases?: string[];
 }
 
 export interface ColumnFilter {